@@ -1,12 +1,25 @@
 package api
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"net/http"
+	"time"
 
+	"github.com/gfmanica/splitz-backend/config"
+	"github.com/gfmanica/splitz-backend/service/balance"
 	"github.com/gfmanica/splitz-backend/service/bill"
+	"github.com/gfmanica/splitz-backend/service/calendar"
+	"github.com/gfmanica/splitz-backend/service/currency"
+	"github.com/gfmanica/splitz-backend/service/export"
+	"github.com/gfmanica/splitz-backend/service/group"
+	"github.com/gfmanica/splitz-backend/service/mail"
+	"github.com/gfmanica/splitz-backend/service/payment"
+	"github.com/gfmanica/splitz-backend/service/recurring"
 	"github.com/gfmanica/splitz-backend/service/ride"
+	"github.com/gfmanica/splitz-backend/service/security"
+	"github.com/gfmanica/splitz-backend/service/statement"
 	"github.com/gfmanica/splitz-backend/service/user"
 	"github.com/gorilla/mux"
 )
@@ -27,18 +40,62 @@ func (s *APIServer) Run() error {
 	router := mux.NewRouter()
 	subrouter := router.PathPrefix("/api/v1").Subrouter()
 
+	mailer, err := mail.New(config.Envs)
+	if err != nil {
+		return err
+	}
+
 	userStore := user.NewStore(s.db)
-	userHandler := user.NewHandler(userStore)
+	securityStore := security.NewStore(s.db)
+	userHandler := user.NewHandler(userStore, mailer, securityStore)
 	userHandler.RegisterRoutes(subrouter)
 
+	securityHandler := security.NewHandler(securityStore, userStore)
+	securityHandler.RegisterRoutes(subrouter)
+
+	groupStore := group.NewStore(s.db)
+	groupHandler := group.NewHandler(groupStore, userStore)
+	groupHandler.RegisterRoutes(subrouter)
+
 	billStore := bill.NewStore(s.db)
-	billHandler := bill.NewHandler(billStore, userStore)
+	billHandler := bill.NewHandler(billStore, userStore, groupStore)
 	billHandler.RegisterRoutes(subrouter)
 
+	recurringStore := recurring.NewStore(s.db)
+	recurringHandler := recurring.NewHandler(recurringStore, userStore, groupStore)
+	recurringHandler.RegisterRoutes(subrouter)
+
+	// Gera os bills recorrentes em segundo plano
+	interval := time.Second * time.Duration(config.Envs.RecurringBillIntervalInSeconds)
+	scheduler := recurring.NewScheduler(recurringStore, interval)
+	go scheduler.Start(context.Background())
+
+	calendarStore := calendar.NewStore(s.db)
+	calendarHandler := calendar.NewHandler(calendarStore, userStore)
+	calendarHandler.RegisterRoutes(subrouter)
+
 	rideStore := ride.NewStore(s.db)
-	rideHandler := *ride.NewHandler(rideStore, userStore)
+	rideHandler := *ride.NewHandler(rideStore, userStore, groupStore)
 	rideHandler.RegisterRoutes(subrouter)
 
+	currencyStore := currency.NewStore(s.db)
+	currencyHandler := currency.NewHandler(currencyStore, userStore)
+	currencyHandler.RegisterRoutes(subrouter)
+
+	paymentStore := payment.NewStore(s.db)
+	paymentHandler := payment.NewHandler(paymentStore, currencyStore, userStore)
+	paymentHandler.RegisterRoutes(subrouter)
+
+	balanceStore := balance.NewStore(s.db)
+	balanceHandler := balance.NewHandler(balanceStore, currencyStore, userStore)
+	balanceHandler.RegisterRoutes(subrouter)
+
+	exportHandler := export.NewHandler(billStore, rideStore, currencyStore, userStore)
+	exportHandler.RegisterRoutes(subrouter)
+
+	statementHandler := statement.NewHandler(billStore, rideStore, currencyStore, userStore)
+	statementHandler.RegisterRoutes(subrouter)
+
 	log.Println("Starting server on", s.addr)
 
 	return http.ListenAndServe(s.addr, router)
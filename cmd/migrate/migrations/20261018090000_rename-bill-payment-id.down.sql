@@ -0,0 +1,9 @@
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_schema = 'public' AND table_name = 'bill_payment' AND column_name = 'id_bill_payment'
+    ) THEN
+        ALTER TABLE "bill_payment" RENAME COLUMN "id_bill_payment" TO "id_payment";
+    END IF;
+END $$;
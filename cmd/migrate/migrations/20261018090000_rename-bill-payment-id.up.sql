@@ -0,0 +1,12 @@
+-- A migration original criou a chave como "id_payment", mas o código e as
+-- chaves estrangeiras seguintes usam "id_bill_payment". Bancos que já foram
+-- corrigidos à mão ficam como estão.
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_schema = 'public' AND table_name = 'bill_payment' AND column_name = 'id_payment'
+    ) THEN
+        ALTER TABLE "bill_payment" RENAME COLUMN "id_payment" TO "id_bill_payment";
+    END IF;
+END $$;
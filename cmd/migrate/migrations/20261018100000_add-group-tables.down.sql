@@ -0,0 +1,4 @@
+ALTER TABLE "ride_payment" DROP COLUMN IF EXISTS "id_user";
+ALTER TABLE "bill_payment" DROP COLUMN IF EXISTS "id_user";
+DROP TABLE IF EXISTS "public"."group_member";
+DROP TABLE IF EXISTS "public"."groups";
@@ -0,0 +1,24 @@
+CREATE TABLE "groups"(
+    "id_group" SERIAL PRIMARY KEY,
+    "ds_group" VARCHAR(255) NOT NULL,
+    "id_user" INTEGER NOT NULL,
+    "created_at" TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT "groups_id_user_foreign" FOREIGN KEY("id_user") REFERENCES "users"("id")
+);
+
+CREATE TABLE "group_member"(
+    "id_group_member" SERIAL PRIMARY KEY,
+    "id_group" INTEGER NOT NULL,
+    "id_user" INTEGER NOT NULL,
+    "fg_accepted" BOOLEAN NOT NULL DEFAULT FALSE,
+    "created_at" TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT "group_member_id_group_foreign" FOREIGN KEY("id_group") REFERENCES "groups"("id_group") ON DELETE CASCADE,
+    CONSTRAINT "group_member_id_user_foreign" FOREIGN KEY("id_user") REFERENCES "users"("id"),
+    CONSTRAINT "group_member_id_group_id_user_unique" UNIQUE("id_group", "id_user")
+);
+
+ALTER TABLE "bill_payment" ADD COLUMN "id_user" INTEGER;
+ALTER TABLE "bill_payment" ADD CONSTRAINT "bill_payment_id_user_foreign" FOREIGN KEY("id_user") REFERENCES "users"("id");
+
+ALTER TABLE "ride_payment" ADD COLUMN "id_user" INTEGER;
+ALTER TABLE "ride_payment" ADD CONSTRAINT "ride_payment_id_user_foreign" FOREIGN KEY("id_user") REFERENCES "users"("id");
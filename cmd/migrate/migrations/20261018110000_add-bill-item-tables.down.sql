@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS "public"."bill_item_participant";
+DROP TABLE IF EXISTS "public"."bill_item";
+ALTER TABLE "bill" DROP COLUMN IF EXISTS "pc_tip";
+ALTER TABLE "bill" DROP COLUMN IF EXISTS "pc_service_charge";
+ALTER TABLE "bill" DROP COLUMN IF EXISTS "pc_tax";
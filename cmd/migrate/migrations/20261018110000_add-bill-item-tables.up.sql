@@ -0,0 +1,20 @@
+ALTER TABLE "bill" ADD COLUMN "pc_tax" DECIMAL(5, 2) NOT NULL DEFAULT 0;
+ALTER TABLE "bill" ADD COLUMN "pc_service_charge" DECIMAL(5, 2) NOT NULL DEFAULT 0;
+ALTER TABLE "bill" ADD COLUMN "pc_tip" DECIMAL(5, 2) NOT NULL DEFAULT 0;
+
+CREATE TABLE "bill_item"(
+    "id_bill_item" SERIAL PRIMARY KEY,
+    "id_bill" INTEGER NOT NULL,
+    "ds_item" VARCHAR(255) NOT NULL,
+    "vl_item" DECIMAL(10, 2) NOT NULL,
+    "qt_item" INTEGER NOT NULL DEFAULT 1,
+    CONSTRAINT "bill_item_id_bill_foreign" FOREIGN KEY("id_bill") REFERENCES "bill"("id_bill") ON DELETE CASCADE
+);
+
+CREATE TABLE "bill_item_participant"(
+    "id_bill_item" INTEGER NOT NULL,
+    "id_bill_payment" INTEGER NOT NULL,
+    PRIMARY KEY("id_bill_item", "id_bill_payment"),
+    CONSTRAINT "bill_item_participant_id_bill_item_foreign" FOREIGN KEY("id_bill_item") REFERENCES "bill_item"("id_bill_item") ON DELETE CASCADE,
+    CONSTRAINT "bill_item_participant_id_bill_payment_foreign" FOREIGN KEY("id_bill_payment") REFERENCES "bill_payment"("id_bill_payment") ON DELETE CASCADE
+);
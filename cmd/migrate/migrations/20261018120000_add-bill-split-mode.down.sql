@@ -0,0 +1,3 @@
+ALTER TABLE "bill_payment" DROP COLUMN IF EXISTS "pc_payment";
+ALTER TABLE "bill_payment" DROP COLUMN IF EXISTS "qt_share";
+ALTER TABLE "bill" DROP COLUMN IF EXISTS "tp_split";
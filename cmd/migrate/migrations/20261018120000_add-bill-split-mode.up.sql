@@ -0,0 +1,4 @@
+ALTER TABLE "bill" ADD COLUMN "tp_split" VARCHAR(20) NOT NULL DEFAULT 'equal';
+
+ALTER TABLE "bill_payment" ADD COLUMN "qt_share" INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE "bill_payment" ADD COLUMN "pc_payment" DECIMAL(5, 2) NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "public"."recurring_bill_period";
+DROP TABLE IF EXISTS "public"."recurring_bill_payment";
+DROP TABLE IF EXISTS "public"."recurring_bill";
@@ -0,0 +1,36 @@
+CREATE TABLE "recurring_bill"(
+    "id_recurring_bill" SERIAL PRIMARY KEY,
+    "ds_bill" VARCHAR(255) NOT NULL,
+    "vl_bill" DECIMAL(10, 2) NOT NULL,
+    "qt_person" INTEGER NOT NULL,
+    "tp_split" VARCHAR(20) NOT NULL DEFAULT 'equal',
+    "tp_recurrence" VARCHAR(20) NOT NULL,
+    "nr_day" INTEGER NOT NULL DEFAULT 1,
+    "nr_month" INTEGER NOT NULL DEFAULT 1,
+    "dt_start" DATE NOT NULL,
+    "dt_finish" DATE,
+    "fg_active" BOOLEAN NOT NULL DEFAULT TRUE,
+    "id_user" INTEGER NOT NULL,
+    CONSTRAINT "recurring_bill_id_user_foreign" FOREIGN KEY("id_user") REFERENCES "users"("id")
+);
+
+CREATE TABLE "recurring_bill_payment"(
+    "id_recurring_bill_payment" SERIAL PRIMARY KEY,
+    "id_recurring_bill" INTEGER NOT NULL,
+    "ds_person" VARCHAR(255) NOT NULL,
+    "id_user" INTEGER,
+    "vl_payment" DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    "qt_share" INTEGER NOT NULL DEFAULT 1,
+    "pc_payment" DECIMAL(5, 2) NOT NULL DEFAULT 0,
+    CONSTRAINT "recurring_bill_payment_id_recurring_bill_foreign" FOREIGN KEY("id_recurring_bill") REFERENCES "recurring_bill"("id_recurring_bill") ON DELETE CASCADE,
+    CONSTRAINT "recurring_bill_payment_id_user_foreign" FOREIGN KEY("id_user") REFERENCES "users"("id")
+);
+
+CREATE TABLE "recurring_bill_period"(
+    "id_recurring_bill" INTEGER NOT NULL,
+    "dt_period" DATE NOT NULL,
+    "id_bill" INTEGER,
+    PRIMARY KEY("id_recurring_bill", "dt_period"),
+    CONSTRAINT "recurring_bill_period_id_recurring_bill_foreign" FOREIGN KEY("id_recurring_bill") REFERENCES "recurring_bill"("id_recurring_bill") ON DELETE CASCADE,
+    CONSTRAINT "recurring_bill_period_id_bill_foreign" FOREIGN KEY("id_bill") REFERENCES "bill"("id_bill") ON DELETE SET NULL
+);
@@ -0,0 +1,25 @@
+ALTER TABLE "bill_payment" ADD COLUMN "fg_payed" BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE "bill_payment" ADD COLUMN "dt_payment" DATE;
+ALTER TABLE "ride_payment" ADD COLUMN "fg_payed" BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE "bill_payment" bp SET
+    "fg_payed" = t."vl_paid" >= bp."vl_payment",
+    "dt_payment" = t."dt_payment"
+FROM (
+    SELECT "id_bill_payment", SUM("vl_payment") AS "vl_paid", MAX("dt_payment") AS "dt_payment"
+    FROM "payment_transaction"
+    WHERE "id_bill_payment" IS NOT NULL
+    GROUP BY "id_bill_payment"
+) t
+WHERE t."id_bill_payment" = bp."id_bill_payment";
+
+UPDATE "ride_payment" rp SET "fg_payed" = t."vl_paid" >= rp."vl_payment"
+FROM (
+    SELECT "id_ride_payment", SUM("vl_payment") AS "vl_paid"
+    FROM "payment_transaction"
+    WHERE "id_ride_payment" IS NOT NULL
+    GROUP BY "id_ride_payment"
+) t
+WHERE t."id_ride_payment" = rp."id_ride_payment";
+
+DROP TABLE IF EXISTS "public"."payment_transaction";
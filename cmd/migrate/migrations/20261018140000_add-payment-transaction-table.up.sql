@@ -0,0 +1,37 @@
+CREATE TABLE "payment_transaction"(
+    "id_payment_transaction" SERIAL PRIMARY KEY,
+    "id_bill_payment" INTEGER,
+    "id_ride_payment" INTEGER,
+    "vl_payment" DECIMAL(10, 2) NOT NULL,
+    "dt_payment" DATE NOT NULL DEFAULT CURRENT_DATE,
+    "tp_method" VARCHAR(20) NOT NULL,
+    "ds_note" VARCHAR(255) NOT NULL DEFAULT '',
+    "id_reversed_transaction" INTEGER UNIQUE,
+    "id_user" INTEGER NOT NULL,
+    "created_at" TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT "payment_transaction_id_bill_payment_foreign" FOREIGN KEY("id_bill_payment") REFERENCES "bill_payment"("id_bill_payment") ON DELETE CASCADE,
+    CONSTRAINT "payment_transaction_id_ride_payment_foreign" FOREIGN KEY("id_ride_payment") REFERENCES "ride_payment"("id_ride_payment") ON DELETE CASCADE,
+    CONSTRAINT "payment_transaction_id_reversed_transaction_foreign" FOREIGN KEY("id_reversed_transaction") REFERENCES "payment_transaction"("id_payment_transaction") ON DELETE CASCADE,
+    CONSTRAINT "payment_transaction_id_user_foreign" FOREIGN KEY("id_user") REFERENCES "users"("id"),
+    CONSTRAINT "payment_transaction_payment_check" CHECK (("id_bill_payment" IS NULL) <> ("id_ride_payment" IS NULL))
+);
+
+CREATE INDEX "payment_transaction_id_bill_payment_index" ON "payment_transaction"("id_bill_payment");
+CREATE INDEX "payment_transaction_id_ride_payment_index" ON "payment_transaction"("id_ride_payment");
+
+-- Os pagamentos já quitados viram uma transação com o valor total
+INSERT INTO "payment_transaction" ("id_bill_payment", "vl_payment", "dt_payment", "tp_method", "id_user")
+SELECT bp."id_bill_payment", bp."vl_payment", COALESCE(bp."dt_payment", CURRENT_DATE), 'other', b."id_user"
+FROM "bill_payment" bp
+INNER JOIN "bill" b ON b."id_bill" = bp."id_bill"
+WHERE bp."fg_payed" = TRUE AND bp."vl_payment" > 0;
+
+INSERT INTO "payment_transaction" ("id_ride_payment", "vl_payment", "tp_method", "id_user")
+SELECT rp."id_ride_payment", rp."vl_payment", 'other', r."id_user"
+FROM "ride_payment" rp
+INNER JOIN "ride" r ON r."id_ride" = rp."id_ride"
+WHERE rp."fg_payed" = TRUE AND rp."vl_payment" > 0;
+
+ALTER TABLE "bill_payment" DROP COLUMN "fg_payed";
+ALTER TABLE "bill_payment" DROP COLUMN "dt_payment";
+ALTER TABLE "ride_payment" DROP COLUMN "fg_payed";
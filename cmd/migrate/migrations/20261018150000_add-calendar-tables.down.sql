@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS "public"."ride_excluded_date";
+DROP TABLE IF EXISTS "public"."ride_calendar";
+DROP TABLE IF EXISTS "public"."calendar_date";
+DROP TABLE IF EXISTS "public"."calendar";
@@ -0,0 +1,31 @@
+CREATE TABLE "calendar"(
+    "id_calendar" SERIAL PRIMARY KEY,
+    "ds_calendar" VARCHAR(255) NOT NULL,
+    "id_user" INTEGER NOT NULL,
+    "created_at" TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT "calendar_id_user_foreign" FOREIGN KEY("id_user") REFERENCES "users"("id")
+);
+
+CREATE TABLE "calendar_date"(
+    "id_calendar_date" SERIAL PRIMARY KEY,
+    "id_calendar" INTEGER NOT NULL,
+    "dt_date" DATE NOT NULL,
+    "ds_date" VARCHAR(255) NOT NULL DEFAULT '',
+    CONSTRAINT "calendar_date_id_calendar_foreign" FOREIGN KEY("id_calendar") REFERENCES "calendar"("id_calendar") ON DELETE CASCADE,
+    CONSTRAINT "calendar_date_id_calendar_dt_date_unique" UNIQUE("id_calendar", "dt_date")
+);
+
+CREATE TABLE "ride_calendar"(
+    "id_ride" INTEGER NOT NULL,
+    "id_calendar" INTEGER NOT NULL,
+    PRIMARY KEY("id_ride", "id_calendar"),
+    CONSTRAINT "ride_calendar_id_ride_foreign" FOREIGN KEY("id_ride") REFERENCES "ride"("id_ride") ON DELETE CASCADE,
+    CONSTRAINT "ride_calendar_id_calendar_foreign" FOREIGN KEY("id_calendar") REFERENCES "calendar"("id_calendar") ON DELETE CASCADE
+);
+
+CREATE TABLE "ride_excluded_date"(
+    "id_ride" INTEGER NOT NULL,
+    "dt_excluded" DATE NOT NULL,
+    PRIMARY KEY("id_ride", "dt_excluded"),
+    CONSTRAINT "ride_excluded_date_id_ride_foreign" FOREIGN KEY("id_ride") REFERENCES "ride"("id_ride") ON DELETE CASCADE
+);
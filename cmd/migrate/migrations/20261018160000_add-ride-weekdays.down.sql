@@ -0,0 +1,2 @@
+ALTER TABLE "ride_payment" DROP COLUMN "nr_weekdays";
+ALTER TABLE "ride" DROP COLUMN "nr_weekdays";
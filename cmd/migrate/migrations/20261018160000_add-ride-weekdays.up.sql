@@ -0,0 +1,4 @@
+-- Dias da semana como máscara de bits (bit 0 é domingo). Nulo mantém o
+-- comportamento anterior, guiado por fg_count_weekend.
+ALTER TABLE "ride" ADD COLUMN "nr_weekdays" INTEGER;
+ALTER TABLE "ride_payment" ADD COLUMN "nr_weekdays" INTEGER;
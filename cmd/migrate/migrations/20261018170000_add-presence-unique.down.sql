@@ -0,0 +1 @@
+ALTER TABLE "presence" DROP CONSTRAINT "presence_id_ride_payment_dt_ride_unique";
@@ -0,0 +1,8 @@
+-- Remove presenças duplicadas antes de garantir uma por pagamento e dia
+DELETE FROM "presence" p
+USING "presence" d
+WHERE p."id_ride_payment" = d."id_ride_payment"
+AND p."dt_ride" = d."dt_ride"
+AND p."id_presence" > d."id_presence";
+
+ALTER TABLE "presence" ADD CONSTRAINT "presence_id_ride_payment_dt_ride_unique" UNIQUE("id_ride_payment", "dt_ride");
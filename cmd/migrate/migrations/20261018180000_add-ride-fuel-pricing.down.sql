@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS "public"."ride_fuel_price";
+
+ALTER TABLE "ride" DROP COLUMN "vl_parking";
+ALTER TABLE "ride" DROP COLUMN "vl_toll";
+ALTER TABLE "ride" DROP COLUMN "nr_consumption";
+ALTER TABLE "ride" DROP COLUMN "nr_distance";
+ALTER TABLE "ride" DROP COLUMN "tp_pricing";
@@ -0,0 +1,16 @@
+-- flat mantém o cálculo por vl_ride * qt_ride; fuel deriva o custo diário
+-- da distância, do consumo e do preço do combustível vigente no dia.
+ALTER TABLE "ride" ADD COLUMN "tp_pricing" VARCHAR(20) NOT NULL DEFAULT 'flat';
+ALTER TABLE "ride" ADD COLUMN "nr_distance" DECIMAL(10, 2) NOT NULL DEFAULT 0;
+ALTER TABLE "ride" ADD COLUMN "nr_consumption" DECIMAL(10, 2) NOT NULL DEFAULT 0;
+ALTER TABLE "ride" ADD COLUMN "vl_toll" DECIMAL(10, 2) NOT NULL DEFAULT 0;
+ALTER TABLE "ride" ADD COLUMN "vl_parking" DECIMAL(10, 2) NOT NULL DEFAULT 0;
+
+CREATE TABLE "ride_fuel_price"(
+    "id_fuel_price" SERIAL PRIMARY KEY,
+    "id_ride" INTEGER NOT NULL,
+    "vl_fuel_price" DECIMAL(10, 2) NOT NULL,
+    "dt_effective" DATE NOT NULL,
+    CONSTRAINT "ride_fuel_price_id_ride_foreign" FOREIGN KEY("id_ride") REFERENCES "ride"("id_ride") ON DELETE CASCADE,
+    CONSTRAINT "ride_fuel_price_id_ride_dt_effective_unique" UNIQUE("id_ride", "dt_effective")
+);
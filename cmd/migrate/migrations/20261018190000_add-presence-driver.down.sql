@@ -0,0 +1 @@
+ALTER TABLE "presence" DROP COLUMN "fg_driver";
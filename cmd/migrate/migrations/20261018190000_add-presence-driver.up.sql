@@ -0,0 +1,2 @@
+-- Marca quem dirigiu no dia. O motorista não divide o custo do dia.
+ALTER TABLE "presence" ADD COLUMN "fg_driver" BOOLEAN NOT NULL DEFAULT false;
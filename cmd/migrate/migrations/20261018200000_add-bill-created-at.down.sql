@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS "ride_dt_init_index";
+DROP INDEX IF EXISTS "bill_created_at_index";
+
+ALTER TABLE "bill" DROP COLUMN "created_at";
@@ -0,0 +1,5 @@
+-- Bills já existentes ficam com a data da migração
+ALTER TABLE "bill" ADD COLUMN "created_at" TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+CREATE INDEX "bill_created_at_index" ON "bill"("created_at");
+CREATE INDEX "ride_dt_init_index" ON "ride"("dt_init");
@@ -0,0 +1,13 @@
+-- Lançamentos de extrato já importados, para não criar o mesmo bill duas
+-- vezes. Excluir o bill mantém o registro, então o lançamento não volta a
+-- aparecer como pendente.
+CREATE TABLE "bill_import"(
+    "id_bill_import" SERIAL PRIMARY KEY,
+    "id_user" INTEGER NOT NULL,
+    "ds_external_id" VARCHAR(255) NOT NULL,
+    "id_bill" INTEGER NULL,
+    "created_at" TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT "bill_import_id_user_foreign" FOREIGN KEY("id_user") REFERENCES "users"("id") ON DELETE CASCADE,
+    CONSTRAINT "bill_import_id_bill_foreign" FOREIGN KEY("id_bill") REFERENCES "bill"("id_bill") ON DELETE SET NULL,
+    CONSTRAINT "bill_import_id_user_ds_external_id_unique" UNIQUE("id_user", "ds_external_id")
+);
@@ -0,0 +1,2 @@
+ALTER TABLE "users" DROP COLUMN "ds_pix_city";
+ALTER TABLE "users" DROP COLUMN "ds_pix_key";
@@ -0,0 +1,3 @@
+-- Chave Pix e cidade do recebedor, exigidas no BR Code das cobranças
+ALTER TABLE "users" ADD COLUMN "ds_pix_key" VARCHAR(77) NULL;
+ALTER TABLE "users" ADD COLUMN "ds_pix_city" VARCHAR(60) NULL;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS "public"."exchange_rate";
+
+ALTER TABLE "users" DROP COLUMN "cd_currency";
+ALTER TABLE "bill" DROP COLUMN "cd_currency";
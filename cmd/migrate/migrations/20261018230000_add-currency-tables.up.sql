@@ -0,0 +1,15 @@
+-- Valores originais ficam na moeda do bill; a conversão para a moeda do
+-- usuário é feita na leitura, com as taxas cadastradas por ele.
+ALTER TABLE "bill" ADD COLUMN "cd_currency" CHAR(3) NOT NULL DEFAULT 'BRL';
+ALTER TABLE "users" ADD COLUMN "cd_currency" CHAR(3) NOT NULL DEFAULT 'BRL';
+
+CREATE TABLE "exchange_rate"(
+    "id_exchange_rate" SERIAL PRIMARY KEY,
+    "id_user" INTEGER NOT NULL,
+    "cd_from" CHAR(3) NOT NULL,
+    "cd_to" CHAR(3) NOT NULL,
+    "dt_rate" DATE NOT NULL,
+    "vl_rate" DECIMAL(18, 6) NOT NULL,
+    CONSTRAINT "exchange_rate_id_user_foreign" FOREIGN KEY("id_user") REFERENCES "users"("id") ON DELETE CASCADE,
+    CONSTRAINT "exchange_rate_id_user_cd_from_cd_to_dt_rate_unique" UNIQUE("id_user", "cd_from", "cd_to", "dt_rate")
+);
@@ -0,0 +1,17 @@
+-- Cada login abre uma família de refresh tokens (ds_family). A cada refresh o
+-- token usado é marcado e outro da mesma família é emitido; reutilizar um
+-- token já trocado revoga a família inteira. Só o hash do token é gravado.
+CREATE TABLE "refresh_token"(
+    "id_refresh_token" SERIAL PRIMARY KEY,
+    "id_user" INTEGER NOT NULL,
+    "ds_family" VARCHAR(64) NOT NULL,
+    "ds_token_hash" CHAR(64) NOT NULL,
+    "dt_expires" TIMESTAMP NOT NULL,
+    "dt_used" TIMESTAMP NULL,
+    "dt_revoked" TIMESTAMP NULL,
+    "created_at" TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT "refresh_token_id_user_foreign" FOREIGN KEY("id_user") REFERENCES "users"("id") ON DELETE CASCADE,
+    CONSTRAINT "refresh_token_ds_token_hash_unique" UNIQUE("ds_token_hash")
+);
+
+CREATE INDEX "refresh_token_ds_family_index" ON "refresh_token"("ds_family");
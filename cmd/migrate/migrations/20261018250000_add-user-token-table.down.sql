@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "public"."user_token";
+ALTER TABLE "users" DROP COLUMN "fg_email_verified";
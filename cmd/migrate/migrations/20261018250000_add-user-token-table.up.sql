@@ -0,0 +1,17 @@
+-- Contas criadas antes da verificação de email continuam podendo entrar
+ALTER TABLE "users" ADD COLUMN "fg_email_verified" BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE "users" SET "fg_email_verified" = TRUE;
+
+-- Tokens de uso único enviados por email: verificação do endereço
+-- (verify_email) e troca de senha (reset_password). Só o hash é gravado.
+CREATE TABLE "user_token"(
+    "id_user_token" SERIAL PRIMARY KEY,
+    "id_user" INTEGER NOT NULL,
+    "tp_token" VARCHAR(20) NOT NULL,
+    "ds_token_hash" CHAR(64) NOT NULL,
+    "dt_expires" TIMESTAMP NOT NULL,
+    "dt_used" TIMESTAMP NULL,
+    "created_at" TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT "user_token_id_user_foreign" FOREIGN KEY("id_user") REFERENCES "users"("id") ON DELETE CASCADE,
+    CONSTRAINT "user_token_ds_token_hash_unique" UNIQUE("ds_token_hash")
+);
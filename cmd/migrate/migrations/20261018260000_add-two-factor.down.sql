@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS "public"."recovery_code";
+ALTER TABLE "users" DROP COLUMN "nr_totp_counter";
+ALTER TABLE "users" DROP COLUMN "fg_totp_enabled";
+ALTER TABLE "users" DROP COLUMN "ds_totp_secret";
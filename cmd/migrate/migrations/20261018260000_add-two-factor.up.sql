@@ -0,0 +1,16 @@
+-- O segredo TOTP é gravado no setup e só passa a ser exigido depois que o
+-- usuário confirma um código (fg_totp_enabled). nr_totp_counter é o período do
+-- último código aceito, para o mesmo código não servir duas vezes.
+ALTER TABLE "users" ADD COLUMN "ds_totp_secret" VARCHAR(64) NULL;
+ALTER TABLE "users" ADD COLUMN "fg_totp_enabled" BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE "users" ADD COLUMN "nr_totp_counter" BIGINT NULL;
+
+-- Códigos de recuperação de uso único; só o hash é gravado.
+CREATE TABLE "recovery_code"(
+    "id_recovery_code" SERIAL PRIMARY KEY,
+    "id_user" INTEGER NOT NULL,
+    "ds_code_hash" CHAR(64) NOT NULL,
+    "dt_used" TIMESTAMP NULL,
+    CONSTRAINT "recovery_code_id_user_foreign" FOREIGN KEY("id_user") REFERENCES "users"("id") ON DELETE CASCADE,
+    CONSTRAINT "recovery_code_id_user_ds_code_hash_unique" UNIQUE("id_user", "ds_code_hash")
+);
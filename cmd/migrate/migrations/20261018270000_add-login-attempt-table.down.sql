@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "public"."security_event";
+DROP TABLE IF EXISTS "public"."login_attempt";
@@ -0,0 +1,25 @@
+-- Falhas de login por email (tp_key = 'account') e por IP (tp_key = 'ip').
+-- O email é contado mesmo quando não tem conta, para a resposta não revelar
+-- quais emails estão cadastrados. Falhas mais antigas que a janela zeram.
+CREATE TABLE "login_attempt"(
+    "id_login_attempt" SERIAL PRIMARY KEY,
+    "tp_key" VARCHAR(10) NOT NULL,
+    "ds_key" VARCHAR(255) NOT NULL,
+    "qt_failures" INTEGER NOT NULL DEFAULT 0,
+    "dt_last_failure" TIMESTAMP NOT NULL,
+    "dt_locked_until" TIMESTAMP NULL,
+    CONSTRAINT "login_attempt_tp_key_ds_key_unique" UNIQUE("tp_key", "ds_key")
+);
+
+CREATE TABLE "security_event"(
+    "id_security_event" SERIAL PRIMARY KEY,
+    "id_user" INTEGER NOT NULL,
+    "tp_event" VARCHAR(30) NOT NULL,
+    "ds_ip" VARCHAR(45) NOT NULL,
+    "ds_user_agent" VARCHAR(255) NOT NULL,
+    "dt_locked_until" TIMESTAMP NULL,
+    "created_at" TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT "security_event_id_user_foreign" FOREIGN KEY("id_user") REFERENCES "users"("id") ON DELETE CASCADE
+);
+
+CREATE INDEX "security_event_id_user_index" ON "security_event"("id_user");
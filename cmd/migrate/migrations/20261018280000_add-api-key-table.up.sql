@@ -0,0 +1,18 @@
+-- Chaves de API pessoais. Só o hash da chave é gravado; ds_prefix é o começo
+-- dela, para o usuário reconhecer qual chave é qual na listagem.
+CREATE TABLE "api_key"(
+    "id_api_key" SERIAL PRIMARY KEY,
+    "id_user" INTEGER NOT NULL,
+    "ds_name" VARCHAR(100) NOT NULL,
+    "ds_prefix" VARCHAR(20) NOT NULL,
+    "ds_key_hash" CHAR(64) NOT NULL,
+    "tp_scope" VARCHAR(10) NOT NULL,
+    "dt_expires" TIMESTAMP NULL,
+    "dt_last_used" TIMESTAMP NULL,
+    "dt_revoked" TIMESTAMP NULL,
+    "created_at" TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT "api_key_id_user_foreign" FOREIGN KEY("id_user") REFERENCES "users"("id") ON DELETE CASCADE,
+    CONSTRAINT "api_key_ds_key_hash_unique" UNIQUE("ds_key_hash")
+);
+
+CREATE INDEX "api_key_id_user_index" ON "api_key"("id_user");
@@ -8,9 +8,28 @@ import (
 )
 
 type Config struct {
-	DatabaseURL            string
-	JWTSecret              string
-	JWTExpirationInSeconds int64
+	DatabaseURL                      string
+	JWTSecret                        string
+	JWTExpirationInSeconds           int64
+	RefreshTokenExpirationInSeconds  int64
+	RecurringBillIntervalInSeconds   int64
+	VerifyEmailExpirationInSeconds   int64
+	ResetPasswordExpirationInSeconds int64
+	ChallengeExpirationInSeconds     int64
+	AppURL                           string
+	MailDriver                       string
+	MailFrom                         string
+	MailDir                          string
+	SMTPHost                         string
+	SMTPPort                         int64
+	SMTPUser                         string
+	SMTPPassword                     string
+	LoginMaxAttempts                 int64
+	LoginIPMaxAttempts               int64
+	LoginLockoutBaseInSeconds        int64
+	LoginLockoutMaxInSeconds         int64
+	LoginAttemptWindowInSeconds      int64
+	TrustProxy                       bool
 }
 
 var Envs = initConfig()
@@ -21,7 +40,26 @@ func initConfig() Config {
 	return Config{
 		getEnv("DATABASE_URL", ""),
 		getEnv("JWT_SECRET", "segredo"),
-		getEnvAsInt("JWT_EXP", 3600*24*7),
+		getEnvAsInt("JWT_EXP", 60*15),
+		getEnvAsInt("REFRESH_TOKEN_EXP", 3600*24*30),
+		getEnvAsInt("RECURRING_BILL_INTERVAL", 3600),
+		getEnvAsInt("VERIFY_EMAIL_EXP", 3600*48),
+		getEnvAsInt("RESET_PASSWORD_EXP", 3600),
+		getEnvAsInt("CHALLENGE_EXP", 300),
+		getEnv("APP_URL", "http://localhost:3000"),
+		getEnv("MAIL_DRIVER", "log"),
+		getEnv("MAIL_FROM", "Splitz <no-reply@splitz.local>"),
+		getEnv("MAIL_DIR", "tmp/mail"),
+		getEnv("SMTP_HOST", ""),
+		getEnvAsInt("SMTP_PORT", 587),
+		getEnv("SMTP_USER", ""),
+		getEnv("SMTP_PASSWORD", ""),
+		getEnvAsInt("LOGIN_MAX_ATTEMPTS", 5),
+		getEnvAsInt("LOGIN_IP_MAX_ATTEMPTS", 20),
+		getEnvAsInt("LOGIN_LOCKOUT_BASE", 30),
+		getEnvAsInt("LOGIN_LOCKOUT_MAX", 3600),
+		getEnvAsInt("LOGIN_ATTEMPT_WINDOW", 3600),
+		getEnv("TRUST_PROXY", "false") == "true",
 	}
 }
 
@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+)
+
+// As chaves de API começam com um prefixo fixo, o que permite ao middleware
+// distinguir uma chave de um JWT no mesmo cabeçalho Authorization.
+const ApiKeyPrefix = "splitz_"
+
+// Tamanho do início da chave guardado em claro para o usuário reconhecê-la
+const apiKeyDisplaySize = len(ApiKeyPrefix) + 6
+
+// NewApiKey gera a chave, o início dela para exibição e o hash que vai para o banco.
+func NewApiKey() (string, string, string, error) {
+	token, _, err := NewToken()
+	if err != nil {
+		return "", "", "", err
+	}
+
+	key := ApiKeyPrefix + token
+
+	return key, key[:apiKeyDisplaySize], HashToken(key), nil
+}
+
+func IsApiKey(token string) bool {
+	return strings.HasPrefix(token, ApiKeyPrefix)
+}
+
+// Chaves só de leitura não podem alterar nada.
+func isReadOnlyMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/config"
+	"github.com/golang-jwt/jwt"
+)
+
+var ErrInvalidChallenge = fmt.Errorf("invalid or expired challenge token")
+
+// ChallengeClaims é o token devolvido pelo login quando o usuário tem 2FA.
+// Ele só comprova a senha e serve apenas para trocar pelo par de tokens junto
+// com o código TOTP.
+type ChallengeClaims struct {
+	jwt.StandardClaims
+}
+
+func (c *ChallengeClaims) Valid() error {
+	if c.ExpiresAt == 0 || c.Subject == "" {
+		return fmt.Errorf("token is missing required claims")
+	}
+
+	if !c.VerifyAudience(AudienceChallenge, true) {
+		return fmt.Errorf("token is not a challenge token")
+	}
+
+	return c.StandardClaims.Valid()
+}
+
+func CreateChallengeJWT(secret []byte, userID int) (string, error) {
+	expiration := time.Second * time.Duration(config.Envs.ChallengeExpirationInSeconds)
+	now := time.Now()
+
+	jti, err := RandomID()
+
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &ChallengeClaims{jwt.StandardClaims{
+		Id:        jti,
+		Subject:   strconv.Itoa(userID),
+		Audience:  AudienceChallenge,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(expiration).Unix(),
+	}})
+
+	return token.SignedString(secret)
+}
+
+// ValidateChallenge devolve o usuário do token do segundo passo.
+func ValidateChallenge(tokenString string) (int, error) {
+	claims := &ChallengeClaims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+
+		return []byte(config.Envs.JWTSecret), nil
+	})
+
+	if err != nil || !token.Valid {
+		return 0, ErrInvalidChallenge
+	}
+
+	userID, err := strconv.Atoi(claims.Subject)
+
+	if err != nil {
+		return 0, ErrInvalidChallenge
+	}
+
+	return userID, nil
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gfmanica/splitz-backend/config"
@@ -16,16 +17,65 @@ import (
 
 type contextKey string
 
-const UserKey contextKey = "id"
+const (
+	UserKey    contextKey = "id"
+	SessionKey contextKey = "sid"
+	ApiKeyKey  contextKey = "apiKey"
+)
+
+// Claims do access token. Além dos dados do usuário, sid é a sessão (família
+// de refresh tokens) que emitiu o token, para que o logout o invalide.
+type Claims struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	SessionID string `json:"sid"`
+	jwt.StandardClaims
+}
+
+// O aud separa o access token do token do segundo passo do login, que é
+// assinado com o mesmo segredo.
+const (
+	AudienceAccess    = "access"
+	AudienceChallenge = "challenge"
+)
+
+// Valid exige exp, iat e jti, que o jwt-go só confere quando estão presentes.
+func (c *Claims) Valid() error {
+	if c.ExpiresAt == 0 || c.IssuedAt == 0 || c.Id == "" || c.SessionID == "" {
+		return fmt.Errorf("token is missing required claims")
+	}
 
-func CreateJWT(secret []byte, user *types.User) (string, error) {
+	if !c.VerifyAudience(AudienceAccess, true) {
+		return fmt.Errorf("token is not an access token")
+	}
+
+	return c.StandardClaims.Valid()
+}
+
+// CreateJWT emite um access token de curta duração (JWT_EXP) para a sessão.
+func CreateJWT(secret []byte, user *types.User, sessionID string) (string, error) {
 	expiration := time.Second * time.Duration(config.Envs.JWTExpirationInSeconds)
+	now := time.Now()
+
+	jti, err := RandomID()
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"id":        strconv.Itoa(user.ID),
-		"name":      user.Name,
-		"email":     user.Email,
-		"expiredAt": time.Now().Add(expiration).Unix(),
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{
+		ID:        strconv.Itoa(user.ID),
+		Name:      user.Name,
+		Email:     user.Email,
+		SessionID: sessionID,
+		StandardClaims: jwt.StandardClaims{
+			Id:        jti,
+			Subject:   strconv.Itoa(user.ID),
+			Audience:  AudienceAccess,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(expiration).Unix(),
+		},
 	})
 
 	tokenString, err := token.SignedString(secret)
@@ -37,10 +87,37 @@ func CreateJWT(secret []byte, user *types.User) (string, error) {
 	return tokenString, nil
 }
 
+// WithJWTAuth aceita um access token ou uma chave de API. Os dois colocam o
+// mesmo usuário no contexto; chaves só de leitura ficam restritas a GET.
 func WithJWTAuth(handlerFunc http.HandlerFunc, store types.UserStore) http.HandlerFunc {
+	return withAuth(handlerFunc, store, true)
+}
+
+// WithSessionAuth aceita só o access token de um login. É usado nas rotas da
+// própria conta (chaves de API, 2FA, logout), que uma chave vazada não deve
+// poder alterar.
+func WithSessionAuth(handlerFunc http.HandlerFunc, store types.UserStore) http.HandlerFunc {
+	return withAuth(handlerFunc, store, false)
+}
+
+func withAuth(handlerFunc http.HandlerFunc, store types.UserStore, allowApiKey bool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		tokenString := getTokenFromRequest(r)
 
+		if IsApiKey(tokenString) {
+			if !allowApiKey {
+				log.Printf("api key used on a session-only route")
+
+				permissionDenied(w)
+
+				return
+			}
+
+			withApiKey(handlerFunc, store, tokenString, w, r)
+
+			return
+		}
+
 		token, err := validateToken(tokenString)
 
 		if err != nil {
@@ -59,10 +136,20 @@ func WithJWTAuth(handlerFunc http.HandlerFunc, store types.UserStore) http.Handl
 			return
 		}
 
-		claims := token.Claims.(jwt.MapClaims)
-		str := claims["id"].(string)
+		claims := token.Claims.(*Claims)
+
+		userID, _ := strconv.Atoi(claims.ID)
+
+		// Tokens de sessões encerradas no logout deixam de valer antes do exp
+		active, err := store.IsSessionActive(claims.SessionID, userID)
+
+		if err != nil || !active {
+			log.Printf("session %s is not active: %v", claims.SessionID, err)
+
+			permissionDenied(w)
 
-		userID, _ := strconv.Atoi(str)
+			return
+		}
 
 		u, err := store.GetUserByID(userID)
 
@@ -75,25 +162,53 @@ func WithJWTAuth(handlerFunc http.HandlerFunc, store types.UserStore) http.Handl
 		}
 
 		ctx := r.Context()
-		ctx = context.WithValue(ctx, "userId", u.ID)
+		ctx = context.WithValue(ctx, UserKey, u.ID)
+		ctx = context.WithValue(ctx, SessionKey, claims.SessionID)
 		r = r.WithContext(ctx)
 
 		handlerFunc(w, r)
 	}
 }
 
+func withApiKey(handlerFunc http.HandlerFunc, store types.UserStore, key string, w http.ResponseWriter, r *http.Request) {
+	apiKey, err := store.AuthenticateApiKey(HashToken(key))
+
+	if err != nil {
+		log.Printf("Error authenticating api key: %v", err)
+
+		permissionDenied(w)
+
+		return
+	}
+
+	if apiKey.TpScope != types.ScopeWrite && !isReadOnlyMethod(r.Method) {
+		utils.WriterError(w, http.StatusForbidden, fmt.Errorf("api key is read-only"))
+
+		return
+	}
+
+	ctx := r.Context()
+	ctx = context.WithValue(ctx, UserKey, apiKey.IdUser)
+	ctx = context.WithValue(ctx, ApiKeyKey, apiKey.IdApiKey)
+	r = r.WithContext(ctx)
+
+	handlerFunc(w, r)
+}
+
+// getTokenFromRequest lê o Authorization (com ou sem Bearer) e, para chaves
+// de API, também o X-API-Key.
 func getTokenFromRequest(r *http.Request) string {
 	token := r.Header.Get("Authorization")
 
 	if token != "" {
-		return token
+		return strings.TrimPrefix(token, "Bearer ")
 	}
 
-	return ""
+	return r.Header.Get("X-API-Key")
 }
 
 func validateToken(token string) (*jwt.Token, error) {
-	return jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+	return jwt.ParseWithClaims(token, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
@@ -106,8 +221,14 @@ func permissionDenied(w http.ResponseWriter) {
 	utils.WriterError(w, http.StatusUnauthorized, fmt.Errorf("permission denied"))
 }
 
+func GetSessionIDFromContext(ctx context.Context) string {
+	sessionID, _ := ctx.Value(SessionKey).(string)
+
+	return sessionID
+}
+
 func GetUserIDFromContext(ctx context.Context) int {
-	userID, ok := ctx.Value("userId").(int)
+	userID, ok := ctx.Value(UserKey).(int)
 	fmt.Println("userId", userID)
 
 	if !ok {
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/config"
+	"github.com/gfmanica/splitz-backend/types"
+	"github.com/golang-jwt/jwt"
+)
+
+func signClaims(t *testing.T, claims *Claims) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(config.Envs.JWTSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+func TestValidateToken(t *testing.T) {
+	t.Run("should accept a token issued by CreateJWT", func(t *testing.T) {
+		token, err := CreateJWT([]byte(config.Envs.JWTSecret), &types.User{ID: 1}, "session")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		parsed, err := validateToken(token)
+		if err != nil || !parsed.Valid {
+			t.Fatalf("expected a valid token, got %v", err)
+		}
+
+		if claims := parsed.Claims.(*Claims); claims.SessionID != "session" || claims.Id == "" {
+			t.Errorf("expected sid and jti claims, got %+v", claims)
+		}
+	})
+
+	t.Run("should reject an expired token", func(t *testing.T) {
+		now := time.Now()
+
+		token := signClaims(t, &Claims{ID: "1", SessionID: "session", StandardClaims: jwt.StandardClaims{
+			Id:        "jti",
+			IssuedAt:  now.Add(-time.Hour).Unix(),
+			ExpiresAt: now.Add(-time.Minute).Unix(),
+		}})
+
+		if _, err := validateToken(token); err == nil {
+			t.Errorf("expected expired token to be rejected")
+		}
+	})
+
+	t.Run("should reject a token without exp", func(t *testing.T) {
+		token := signClaims(t, &Claims{ID: "1", SessionID: "session", StandardClaims: jwt.StandardClaims{
+			Id:       "jti",
+			IssuedAt: time.Now().Unix(),
+		}})
+
+		if _, err := validateToken(token); err == nil {
+			t.Errorf("expected token without exp to be rejected")
+		}
+	})
+}
+
+func TestValidateChallenge(t *testing.T) {
+	t.Run("should not accept a challenge token as access token", func(t *testing.T) {
+		token, err := CreateChallengeJWT([]byte(config.Envs.JWTSecret), 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := validateToken(token); err == nil {
+			t.Errorf("expected challenge token to be rejected")
+		}
+
+		if userID, err := ValidateChallenge(token); err != nil || userID != 1 {
+			t.Errorf("expected user 1, got %d (%v)", userID, err)
+		}
+	})
+}
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// RandomID gera um identificador aleatório de 128 bits em hexadecimal, usado
+// no jti e no id das sessões.
+func RandomID() (string, error) {
+	b := make([]byte, 16)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// NewToken gera um token opaco (refresh token, link de verificação de email
+// ou de troca de senha) e o hash que vai para o banco.
+func NewToken() (string, string, error) {
+	b := make([]byte, 32)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	return token, HashToken(token), nil
+}
+
+// HashToken é o sha256 do token. Como o token é aleatório, não precisa de
+// salt nem de um hash lento como o das senhas.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,216 @@
+package balance
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+	"strings"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+// personKey identifica uma pessoa entre bills e rides diferentes: pelo id
+// quando o pagamento está vinculado a uma conta, senão pelo nome informado.
+func personKey(idUser *int, dsPerson string) string {
+	if idUser != nil {
+		return fmt.Sprintf("user:%d", *idUser)
+	}
+
+	return "person:" + strings.ToLower(strings.TrimSpace(dsPerson))
+}
+
+// isOwnShare indica a parte do próprio dono do registro, que não gera
+// dívida. Só compara ids: um pagamento sem conta vinculada nunca é tomado
+// pelo dono só por ter o mesmo nome.
+func isOwnShare(debt types.Debt) bool {
+	if debt.FgOwnerShare {
+		return true
+	}
+
+	return debt.IdUserDebtor != nil && debt.IdUserCreditor != nil && *debt.IdUserDebtor == *debt.IdUserCreditor
+}
+
+// ComputeBalances soma as dívidas por pessoa. Saldo positivo é o que a pessoa
+// tem a receber e negativo o que ela tem a pagar.
+func ComputeBalances(debts []types.Debt) []types.Balance {
+	balances := make(map[string]*types.Balance)
+	keys := make([]string, 0)
+
+	add := func(idUser *int, dsPerson string, amount types.Money) {
+		key := personKey(idUser, dsPerson)
+
+		if _, ok := balances[key]; !ok {
+			balances[key] = &types.Balance{IdUser: idUser, DsPerson: dsPerson}
+			keys = append(keys, key)
+		}
+
+		balances[key].VlBalance += amount
+	}
+
+	for _, debt := range debts {
+		if debt.VlDebt == 0 || isOwnShare(debt) {
+			continue
+		}
+
+		add(debt.IdUserDebtor, debt.DsPersonDebtor, -debt.VlDebt)
+		add(debt.IdUserCreditor, debt.DsPersonCreditor, debt.VlDebt)
+	}
+
+	result := make([]types.Balance, 0)
+
+	for _, key := range keys {
+		if balances[key].VlBalance != 0 {
+			result = append(result, *balances[key])
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].VlBalance > result[j].VlBalance
+	})
+
+	return result
+}
+
+// maxExactSettle limita a busca exata, que percorre os 2^n subconjuntos de
+// pessoas. Acima disso os saldos são liquidados direto pelo guloso.
+const maxExactSettle = 16
+
+// Settle calcula o menor número de transferências para zerar os saldos.
+// Separa as pessoas no maior número possível de grupos que somam zero e
+// liquida cada grupo de k pessoas com k-1 transferências, casando sempre o
+// maior devedor com o maior credor.
+//
+// O mínimo só é garantido com até maxExactSettle pessoas com saldo. Acima
+// disso todos ficam num único grupo e o resultado é o do guloso, que ainda
+// zera os saldos com no máximo n-1 transferências, mas pode não ser o menor.
+func Settle(balances []types.Balance) []types.Settlement {
+	people := make([]types.Balance, 0)
+
+	for _, b := range balances {
+		if b.VlBalance != 0 {
+			people = append(people, b)
+		}
+	}
+
+	settlements := make([]types.Settlement, 0)
+
+	for _, group := range zeroSumGroups(people) {
+		settlements = append(settlements, settleGroup(group)...)
+	}
+
+	return settlements
+}
+
+// zeroSumGroups particiona os saldos no maior número de grupos que somam
+// zero. best[mask] guarda quantos grupos cabem no subconjunto mask, e o
+// caminho de volta a partir do conjunto completo fecha um grupo a cada
+// subconjunto restante com soma zero.
+func zeroSumGroups(people []types.Balance) [][]types.Balance {
+	n := len(people)
+
+	if n == 0 {
+		return nil
+	}
+
+	if n > maxExactSettle {
+		return [][]types.Balance{people}
+	}
+
+	size := 1 << n
+	sums := make([]types.Money, size)
+	best := make([]int, size)
+
+	for mask := 1; mask < size; mask++ {
+		low := bits.TrailingZeros(uint(mask))
+		sums[mask] = sums[mask&(mask-1)] + people[low].VlBalance
+
+		for i := 0; i < n; i++ {
+			if bit := 1 << i; mask&bit != 0 && best[mask^bit] > best[mask] {
+				best[mask] = best[mask^bit]
+			}
+		}
+
+		if sums[mask] == 0 {
+			best[mask]++
+		}
+	}
+
+	groups := make([][]types.Balance, 0)
+	group := make([]types.Balance, 0)
+
+	for mask := size - 1; mask != 0; {
+		gain := 0
+		if sums[mask] == 0 {
+			gain = 1
+		}
+
+		for i := 0; i < n; i++ {
+			if bit := 1 << i; mask&bit != 0 && best[mask^bit]+gain == best[mask] {
+				group = append(group, people[i])
+				mask ^= bit
+				break
+			}
+		}
+
+		if sums[mask] == 0 {
+			groups = append(groups, group)
+			group = make([]types.Balance, 0)
+		}
+	}
+
+	// Saldos que não fecham em zero ficam juntos no último grupo
+	if len(group) > 0 {
+		groups = append(groups, group)
+	}
+
+	return groups
+}
+
+// settleGroup liquida sempre o maior devedor com o maior credor. Cada
+// transferência zera ao menos uma pessoa, então um grupo de k pessoas gera
+// no máximo k-1 transferências.
+func settleGroup(group []types.Balance) []types.Settlement {
+	creditors := make([]types.Balance, 0)
+	debtors := make([]types.Balance, 0)
+
+	for _, b := range group {
+		if b.VlBalance > 0 {
+			creditors = append(creditors, b)
+		} else if b.VlBalance < 0 {
+			b.VlBalance = -b.VlBalance
+			debtors = append(debtors, b)
+		}
+	}
+
+	settlements := make([]types.Settlement, 0)
+
+	for {
+		sortByAmount(debtors)
+		sortByAmount(creditors)
+
+		if len(debtors) == 0 || len(creditors) == 0 || debtors[0].VlBalance == 0 || creditors[0].VlBalance == 0 {
+			break
+		}
+
+		amount := min(debtors[0].VlBalance, creditors[0].VlBalance)
+
+		settlements = append(settlements, types.Settlement{
+			IdUserFrom:   debtors[0].IdUser,
+			DsPersonFrom: debtors[0].DsPerson,
+			IdUserTo:     creditors[0].IdUser,
+			DsPersonTo:   creditors[0].DsPerson,
+			VlSettlement: amount,
+		})
+
+		debtors[0].VlBalance -= amount
+		creditors[0].VlBalance -= amount
+	}
+
+	return settlements
+}
+
+func sortByAmount(balances []types.Balance) {
+	sort.SliceStable(balances, func(i, j int) bool {
+		return balances[i].VlBalance > balances[j].VlBalance
+	})
+}
@@ -0,0 +1,227 @@
+package balance
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestBalances(t *testing.T) {
+	ana := intPtr(1)
+	bruno := intPtr(2)
+
+	debts := []types.Debt{
+		// Bill da Ana dividido com ela mesma, Bruno e Carla
+		{IdUserDebtor: ana, DsPersonDebtor: "Ana", IdUserCreditor: ana, DsPersonCreditor: "Ana", VlDebt: 3000},
+		{IdUserDebtor: bruno, DsPersonDebtor: "Bruno", IdUserCreditor: ana, DsPersonCreditor: "Ana", VlDebt: 3000},
+		{DsPersonDebtor: "Carla", IdUserCreditor: ana, DsPersonCreditor: "Ana", VlDebt: 3000},
+		// Ride do Bruno em que a Carla deve a ele
+		{DsPersonDebtor: "carla ", IdUserCreditor: bruno, DsPersonCreditor: "Bruno", VlDebt: 1000},
+	}
+
+	t.Run("should total unpaid shares per person across bills and rides", func(t *testing.T) {
+		balances := ComputeBalances(debts)
+
+		expected := map[string]types.Money{"Ana": 6000, "Bruno": -2000, "Carla": -4000}
+
+		if len(balances) != len(expected) {
+			t.Fatalf("expected %d balances, got %d", len(expected), len(balances))
+		}
+
+		for _, b := range balances {
+			if expected[b.DsPerson] != b.VlBalance {
+				t.Errorf("expected %s to have %s, got %s", b.DsPerson, expected[b.DsPerson], b.VlBalance)
+			}
+		}
+	})
+
+	t.Run("should settle with the minimum number of transfers", func(t *testing.T) {
+		settlements := Settle(ComputeBalances(debts))
+
+		if len(settlements) != 2 {
+			t.Fatalf("expected 2 settlements, got %d", len(settlements))
+		}
+
+		var total types.Money
+		for _, s := range settlements {
+			if s.DsPersonTo != "Ana" {
+				t.Errorf("expected every transfer to go to Ana, got %s", s.DsPersonTo)
+			}
+			total += s.VlSettlement
+		}
+
+		if total != 6000 {
+			t.Errorf("expected transfers to add up to 60.00, got %s", total)
+		}
+	})
+
+	t.Run("should match equal debts and credits directly", func(t *testing.T) {
+		settlements := Settle([]types.Balance{
+			{DsPerson: "A", VlBalance: 500},
+			{DsPerson: "B", VlBalance: 300},
+			{DsPerson: "C", VlBalance: -300},
+			{DsPerson: "D", VlBalance: -500},
+		})
+
+		if len(settlements) != 2 {
+			t.Fatalf("expected 2 settlements, got %d", len(settlements))
+		}
+
+		for _, s := range settlements {
+			if (s.DsPersonFrom == "C" && s.DsPersonTo != "B") || (s.DsPersonFrom == "D" && s.DsPersonTo != "A") {
+				t.Errorf("unexpected transfer from %s to %s", s.DsPersonFrom, s.DsPersonTo)
+			}
+		}
+	})
+
+	t.Run("should split into zero-sum groups when the greedy match is not minimal", func(t *testing.T) {
+		// {A, B, F} e {C, D, E} fecham em zero: 4 transferências, contra 5
+		// casando sempre o maior devedor com o maior credor
+		settlements := Settle([]types.Balance{
+			{DsPerson: "A", VlBalance: 900},
+			{DsPerson: "B", VlBalance: 500},
+			{DsPerson: "C", VlBalance: 700},
+			{DsPerson: "D", VlBalance: -300},
+			{DsPerson: "E", VlBalance: -400},
+			{DsPerson: "F", VlBalance: -1400},
+		})
+
+		if len(settlements) != 4 {
+			t.Fatalf("expected 4 settlements, got %d: %+v", len(settlements), settlements)
+		}
+
+		received := map[string]types.Money{}
+		for _, s := range settlements {
+			received[s.DsPersonTo] += s.VlSettlement
+			received[s.DsPersonFrom] -= s.VlSettlement
+		}
+
+		expected := map[string]types.Money{"A": 900, "B": 500, "C": 700, "D": -300, "E": -400, "F": -1400}
+		for person, amount := range expected {
+			if received[person] != amount {
+				t.Errorf("expected %s to settle %s, got %s", person, amount, received[person])
+			}
+		}
+	})
+}
+
+// boundaryBalances monta os seis saldos em que o guloso não é mínimo, mais
+// pares de valores opostos e, se pedido, um trio que fecha em zero.
+func boundaryBalances(pairs int, triple bool) []types.Balance {
+	balances := []types.Balance{
+		{DsPerson: "A", VlBalance: 900},
+		{DsPerson: "B", VlBalance: 500},
+		{DsPerson: "C", VlBalance: 700},
+		{DsPerson: "D", VlBalance: -300},
+		{DsPerson: "E", VlBalance: -400},
+		{DsPerson: "F", VlBalance: -1400},
+	}
+
+	for i := 0; i < pairs; i++ {
+		amount := types.Money(10000 + 1000*i)
+		balances = append(balances,
+			types.Balance{DsPerson: fmt.Sprintf("P%d", i), VlBalance: amount},
+			types.Balance{DsPerson: fmt.Sprintf("N%d", i), VlBalance: -amount})
+	}
+
+	if triple {
+		balances = append(balances,
+			types.Balance{DsPerson: "X", VlBalance: 100000},
+			types.Balance{DsPerson: "Y", VlBalance: 200000},
+			types.Balance{DsPerson: "Z", VlBalance: -300000})
+	}
+
+	return balances
+}
+
+func checkSettled(t *testing.T, balances []types.Balance, settlements []types.Settlement) {
+	t.Helper()
+
+	left := map[string]types.Money{}
+	for _, b := range balances {
+		left[b.DsPerson] = b.VlBalance
+	}
+
+	for _, s := range settlements {
+		left[s.DsPersonFrom] += s.VlSettlement
+		left[s.DsPersonTo] -= s.VlSettlement
+	}
+
+	for person, amount := range left {
+		if amount != 0 {
+			t.Errorf("expected %s to be settled, %s left", person, amount)
+		}
+	}
+}
+
+func TestSettleLimit(t *testing.T) {
+	t.Run("should find the minimum with up to the exact search limit", func(t *testing.T) {
+		balances := boundaryBalances(5, false)
+
+		if len(balances) != maxExactSettle {
+			t.Fatalf("expected %d balances, got %d", maxExactSettle, len(balances))
+		}
+
+		settlements := Settle(balances)
+		checkSettled(t, balances, settlements)
+
+		// 4 no grupo de seis e 1 por par
+		if len(settlements) != 9 {
+			t.Errorf("expected 9 settlements, got %d", len(settlements))
+		}
+	})
+
+	t.Run("should still settle everyone above the limit", func(t *testing.T) {
+		balances := boundaryBalances(4, true)
+
+		if len(balances) != maxExactSettle+1 {
+			t.Fatalf("expected %d balances, got %d", maxExactSettle+1, len(balances))
+		}
+
+		settlements := Settle(balances)
+		checkSettled(t, balances, settlements)
+
+		// Sem a busca exata não há garantia de mínimo (10), só de n-1
+		if len(settlements) < 10 || len(settlements) > len(balances)-1 {
+			t.Errorf("expected between 10 and %d settlements, got %d", len(balances)-1, len(settlements))
+		}
+	})
+}
+
+func TestOwnerShare(t *testing.T) {
+	ana := intPtr(1)
+
+	t.Run("should not book the owner's unlinked share as a debt", func(t *testing.T) {
+		balances := ComputeBalances([]types.Debt{
+			{DsPersonDebtor: "Pessoa 1", IdUserCreditor: ana, DsPersonCreditor: "Ana", VlDebt: 3000, FgOwnerShare: true},
+			{DsPersonDebtor: "Pessoa 2", IdUserCreditor: ana, DsPersonCreditor: "Ana", VlDebt: 3000},
+		})
+
+		expected := map[string]types.Money{"Ana": 3000, "Pessoa 2": -3000}
+
+		if len(balances) != len(expected) {
+			t.Fatalf("expected %d balances, got %+v", len(expected), balances)
+		}
+
+		for _, b := range balances {
+			if expected[b.DsPerson] != b.VlBalance {
+				t.Errorf("expected %s to have %s, got %s", b.DsPerson, expected[b.DsPerson], b.VlBalance)
+			}
+		}
+	})
+
+	t.Run("should not match an unlinked person to the owner by name", func(t *testing.T) {
+		balances := ComputeBalances([]types.Debt{
+			{DsPersonDebtor: "Ana", IdUserCreditor: ana, DsPersonCreditor: "Ana", VlDebt: 3000},
+		})
+
+		if len(balances) != 2 {
+			t.Errorf("expected the namesake to owe the owner, got %+v", balances)
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package balance
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gfmanica/splitz-backend/service/auth"
+	"github.com/gfmanica/splitz-backend/service/currency"
+	"github.com/gfmanica/splitz-backend/types"
+	"github.com/gfmanica/splitz-backend/utils"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store     types.BalanceStore
+	rateStore types.ExchangeRateStore
+	userStore types.UserStore
+}
+
+func NewHandler(store types.BalanceStore, rateStore types.ExchangeRateStore, userStore types.UserStore) *Handler {
+	return &Handler{
+		store:     store,
+		rateStore: rateStore,
+		userStore: userStore,
+	}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/balances", auth.WithJWTAuth(h.handleGetBalances, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/settlements", auth.WithJWTAuth(h.handleGetSettlements, h.userStore)).Methods(http.MethodGet)
+}
+
+// getBalances calcula os saldos na moeda do usuário, convertendo cada dívida
+// pela taxa da data do bill.
+func (h *Handler) getBalances(w http.ResponseWriter, r *http.Request) ([]types.Balance, string, bool) {
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	debts, err := h.store.GetDebts(userId)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return nil, "", false
+	}
+
+	converter, err := currency.Load(h.rateStore, h.userStore, userId)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return nil, "", false
+	}
+
+	debts, err = converter.Debts(debts)
+
+	if errors.Is(err, currency.ErrMissingRate) {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return nil, "", false
+	}
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return nil, "", false
+	}
+
+	balances := ComputeBalances(debts)
+	for i := range balances {
+		balances[i].CdCurrency = converter.Home()
+	}
+
+	return balances, converter.Home(), true
+}
+
+func (h *Handler) handleGetBalances(w http.ResponseWriter, r *http.Request) {
+	balances, _, ok := h.getBalances(w, r)
+	if !ok {
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, balances)
+}
+
+// handleGetSettlements responde as transferências que zeram os saldos. Com
+// até 16 pessoas com saldo é o menor número possível; acima disso é uma
+// aproximação com no máximo uma transferência a menos que o número de
+// pessoas (ver Settle).
+func (h *Handler) handleGetSettlements(w http.ResponseWriter, r *http.Request) {
+	balances, cdCurrency, ok := h.getBalances(w, r)
+	if !ok {
+		return
+	}
+
+	settlements := Settle(balances)
+	for i := range settlements {
+		settlements[i].CdCurrency = cdCurrency
+	}
+
+	utils.WriteJSON(w, http.StatusOK, settlements)
+}
@@ -0,0 +1,71 @@
+package balance
+
+import (
+	"database/sql"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// GetDebts retorna o valor em aberto dos pagamentos dos bills e rides
+// visíveis ao usuário, descontadas as transações já registradas. Quem deve
+// é a pessoa do pagamento e quem recebe é o dono do registro. Os valores
+// ficam na moeda original, com a data do bill ou o início do ride.
+//
+// A parte do dono é o pagamento vinculado à conta dele. Num bill sem esse
+// vínculo, é o primeiro pagamento sem conta, que nasce como "Pessoa 1".
+func (s *Store) GetDebts(userId int) ([]types.Debt, error) {
+	rows, err := s.db.Query(`
+		SELECT bp.id_user, bp.ds_person, b.id_user, u.name, bp.vl_payment - t.vl_paid, b.cd_currency, b.created_at,
+			COALESCE(bp.id_user = b.id_user, FALSE) OR (
+				bp.id_user IS NULL
+				AND bp.id_bill_payment = (SELECT MIN(f.id_bill_payment) FROM bill_payment f WHERE f.id_bill = b.id_bill)
+				AND NOT EXISTS (SELECT 1 FROM bill_payment o WHERE o.id_bill = b.id_bill AND o.id_user = b.id_user)
+			)
+		FROM bill_payment bp
+		INNER JOIN bill b ON b.id_bill = bp.id_bill
+		INNER JOIN users u ON u.id = b.id_user
+		CROSS JOIN LATERAL (SELECT COALESCE(SUM(pt.vl_payment), 0) AS vl_paid FROM payment_transaction pt WHERE pt.id_bill_payment = bp.id_bill_payment) t
+		WHERE bp.vl_payment > t.vl_paid
+		AND (b.id_user = $1 OR EXISTS (SELECT 1 FROM bill_payment x WHERE x.id_bill = b.id_bill AND x.id_user = $1))
+		UNION ALL
+		SELECT rp.id_user, rp.ds_person, r.id_user, u.name, rp.vl_payment - t.vl_paid, CAST($2 AS CHAR(3)), r.dt_init,
+			COALESCE(rp.id_user = r.id_user, FALSE)
+		FROM ride_payment rp
+		INNER JOIN ride r ON r.id_ride = rp.id_ride
+		INNER JOIN users u ON u.id = r.id_user
+		CROSS JOIN LATERAL (SELECT COALESCE(SUM(pt.vl_payment), 0) AS vl_paid FROM payment_transaction pt WHERE pt.id_ride_payment = rp.id_ride_payment) t
+		WHERE rp.vl_payment > t.vl_paid
+		AND (r.id_user = $1 OR EXISTS (SELECT 1 FROM ride_payment x WHERE x.id_ride = r.id_ride AND x.id_user = $1))`, userId, types.DefaultCurrency)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	debts := make([]types.Debt, 0)
+
+	for rows.Next() {
+		var creditorId int
+		debt := types.Debt{}
+
+		err := rows.Scan(&debt.IdUserDebtor, &debt.DsPersonDebtor, &creditorId, &debt.DsPersonCreditor, &debt.VlDebt, &debt.CdCurrency, &debt.DtDebt, &debt.FgOwnerShare)
+
+		if err != nil {
+			return nil, err
+		}
+
+		debt.IdUserCreditor = &creditorId
+		debts = append(debts, debt)
+	}
+
+	return debts, nil
+}
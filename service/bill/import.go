@@ -0,0 +1,207 @@
+package bill
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gfmanica/splitz-backend/service/auth"
+	"github.com/gfmanica/splitz-backend/service/importer"
+	"github.com/gfmanica/splitz-backend/types"
+	"github.com/gfmanica/splitz-backend/utils"
+	"github.com/go-playground/validator/v10"
+)
+
+// GetImportedIds indica quais dos ids externos o usuário já importou.
+func (s *Store) GetImportedIds(externalIds []string, userId int) (map[string]bool, error) {
+	imported := make(map[string]bool)
+
+	if len(externalIds) == 0 {
+		return imported, nil
+	}
+
+	rows, err := s.db.Query("SELECT ds_external_id FROM bill_import WHERE id_user = $1 AND ds_external_id = ANY($2)", userId, externalIds)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var externalId string
+		if err := rows.Scan(&externalId); err != nil {
+			return nil, err
+		}
+		imported[externalId] = true
+	}
+
+	return imported, rows.Err()
+}
+
+// ImportBills cria um bill por lançamento, com a descrição, o valor e a data
+// do lançamento e os participantes do template, tudo em uma transação. Os
+// lançamentos já importados, inclusive repetidos no mesmo envio, são pulados.
+func (s *Store) ImportBills(transactions []types.ImportedTransaction, template types.Bill, userId int) (*types.ImportResult, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(transactions))
+	skipped := make([]string, 0)
+
+	for _, transaction := range transactions {
+		var idBillImport int
+
+		err := tx.QueryRow(`
+			INSERT INTO bill_import (id_user, ds_external_id) VALUES ($1, $2)
+			ON CONFLICT (id_user, ds_external_id) DO NOTHING
+			RETURNING id_bill_import`, userId, transaction.DsExternalId).Scan(&idBillImport)
+		if errors.Is(err, sql.ErrNoRows) {
+			skipped = append(skipped, transaction.DsExternalId)
+			continue
+		}
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		bill := template
+		bill.DsBill = transaction.DsDescription
+		bill.VlBill = transaction.VlAmount
+
+		id, err := createBill(tx, bill, userId)
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("%s: %w", transaction.DsExternalId, err)
+		}
+
+		if _, err := tx.Exec("UPDATE bill SET created_at = $1 WHERE id_bill = $2", transaction.DtTransaction, id); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		if _, err := tx.Exec("UPDATE bill_import SET id_bill = $1 WHERE id_bill_import = $2", id, idBillImport); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	result := &types.ImportResult{Bills: make([]types.Bill, 0, len(ids)), Skipped: skipped}
+
+	for _, id := range ids {
+		bill, err := s.GetBillById(id, userId)
+		if err != nil {
+			return nil, err
+		}
+		result.Bills = append(result.Bills, *bill)
+	}
+
+	return result, nil
+}
+
+// handlePreviewImport lê o extrato enviado e devolve os lançamentos, marcando
+// os que já foram importados, sem gravar nada.
+func (h *Handler) handlePreviewImport(w http.ResponseWriter, r *http.Request) {
+	var payload types.PreviewImportPayload
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		error := err.(validator.ValidationErrors)
+		utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %s", error))
+
+		return
+	}
+
+	var transactions []types.ImportedTransaction
+	var err error
+
+	switch payload.TpFormat {
+	case types.ImportCSV:
+		transactions, err = importer.ParseCSV(strings.NewReader(payload.DsContent), *payload.Mapping)
+	case types.ImportOFX:
+		transactions, err = importer.ParseOFX(payload.DsContent)
+	}
+
+	if err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	externalIds := make([]string, len(transactions))
+	for i, transaction := range transactions {
+		externalIds[i] = transaction.DsExternalId
+	}
+
+	imported, err := h.store.GetImportedIds(externalIds, userId)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	for i := range transactions {
+		transactions[i].FgImported = imported[transactions[i].DsExternalId]
+	}
+
+	utils.WriteJSON(w, http.StatusOK, transactions)
+}
+
+// handleImportBills cria os bills dos lançamentos selecionados na prévia.
+func (h *Handler) handleImportBills(w http.ResponseWriter, r *http.Request) {
+	var payload types.ImportBillsPayload
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		error := err.(validator.ValidationErrors)
+		utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %s", error))
+
+		return
+	}
+
+	userId := auth.GetUserIDFromContext(r.Context())
+	payments := convertToBillPayments(payload.Payments)
+
+	if err := h.resolveParticipants(payments, userId); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := h.store.ImportBills(payload.Transactions, types.Bill{
+		QtPerson:   payload.QtPerson,
+		TpSplit:    payload.TpSplit,
+		CdCurrency: payload.CdCurrency,
+		Payments:   payments,
+	}, userId)
+
+	// Só a divisão inválida é erro do cliente; o restante é falha do banco
+	if errors.Is(err, ErrInvalidSplit) {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, result)
+}
@@ -1,11 +1,15 @@
 package bill
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/gfmanica/splitz-backend/service/auth"
+	"github.com/gfmanica/splitz-backend/service/group"
+	"github.com/gfmanica/splitz-backend/service/listing"
+	"github.com/gfmanica/splitz-backend/service/payment"
 	"github.com/gfmanica/splitz-backend/types"
 	"github.com/gfmanica/splitz-backend/utils"
 	"github.com/go-playground/validator/v10"
@@ -13,8 +17,9 @@ import (
 )
 
 type Handler struct {
-	store     types.BillStore
-	userStore types.UserStore
+	store      types.BillStore
+	userStore  types.UserStore
+	groupStore types.GroupStore
 }
 
 func convertToBillPayments(createPayments []types.BillPayment) []types.BillPayment {
@@ -24,34 +29,71 @@ func convertToBillPayments(createPayments []types.BillPayment) []types.BillPayme
 		billPayments[i] = types.BillPayment{
 			DsPerson:        createPayment.DsPerson,
 			VlPayment:       createPayment.VlPayment,
-			FgPayed:         createPayment.FgPayed,
 			FgCustomPayment: createPayment.FgCustomPayment,
 			IdBillPayment:   createPayment.IdBillPayment,
 			IdBill:          createPayment.IdBill,
+			IdUser:          createPayment.IdUser,
+			QtShare:         createPayment.QtShare,
+			PcPayment:       createPayment.PcPayment,
 		}
 	}
 	return billPayments
 }
 
-func NewHandler(store types.BillStore, userStore types.UserStore) *Handler {
+func NewHandler(store types.BillStore, userStore types.UserStore, groupStore types.GroupStore) *Handler {
 	return &Handler{
-		store:     store,
-		userStore: userStore,
+		store:      store,
+		userStore:  userStore,
+		groupStore: groupStore,
 	}
 }
 
+// resolveParticipants valida os pagamentos vinculados a usuários e preenche
+// o nome da pessoa quando ele não foi informado.
+func (h *Handler) resolveParticipants(payments []types.BillPayment, userId int) error {
+	for i, payment := range payments {
+		if payment.IdUser == nil {
+			continue
+		}
+
+		participant, err := group.CheckParticipant(h.groupStore, h.userStore, userId, *payment.IdUser)
+
+		if err != nil {
+			return err
+		}
+
+		if payment.DsPerson == "" {
+			payments[i].DsPerson = participant.Name
+		}
+	}
+
+	return nil
+}
+
 func (h *Handler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/bill", auth.WithJWTAuth(h.handleGetBills, h.userStore)).Methods(http.MethodGet)
 	router.HandleFunc("/bill", auth.WithJWTAuth(h.handleCreateBill, h.userStore)).Methods(http.MethodPost)
 	router.HandleFunc("/bill", auth.WithJWTAuth(h.handleUpdateBill, h.userStore)).Methods(http.MethodPut)
+	router.HandleFunc("/bill/import/preview", auth.WithJWTAuth(h.handlePreviewImport, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/bill/import", auth.WithJWTAuth(h.handleImportBills, h.userStore)).Methods(http.MethodPost)
 	router.HandleFunc("/bill/{id}", auth.WithJWTAuth(h.handleGetBill, h.userStore)).Methods(http.MethodGet)
 	router.HandleFunc("/bill/{id}", auth.WithJWTAuth(h.handleDeleteBill, h.userStore)).Methods(http.MethodDelete)
 }
 
+// handleGetBills lista os bills paginados por cursor. Aceita search, status,
+// person, dtFrom e dtTo (data de criação), sort, order, limit, cursor e
+// include=payments,summary.
 func (h *Handler) handleGetBills(w http.ResponseWriter, r *http.Request) {
+	query, err := listing.ParseQuery(r.URL.Query(), Sorts)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
 	userId := auth.GetUserIDFromContext(r.Context())
 
-	bills, err := h.store.GetBills(userId)
+	bills, err := h.store.GetBills(userId, query)
 
 	if err != nil {
 		utils.WriterError(w, http.StatusInternalServerError, err)
@@ -64,8 +106,14 @@ func (h *Handler) handleGetBills(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) handleGetBill(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, _ := strconv.Atoi(vars["id"])
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	bill, err := h.store.GetBillById(id, userId)
 
-	bill, err := h.store.GetBillById(id)
+	if errors.Is(err, ErrBillNotFound) {
+		utils.WriterError(w, http.StatusNotFound, err)
+		return
+	}
 
 	if err != nil {
 		utils.WriterError(w, http.StatusInternalServerError, err)
@@ -90,19 +138,54 @@ func (h *Handler) handleUpdateBill(w http.ResponseWriter, r *http.Request) {
 	}
 
 	bill := types.Bill{
-		IdBill:   payload.IdBill,
-		DsBill:   payload.DsBill,
-		VlBill:   payload.VlBill,
-		QtPerson: payload.QtPerson,
-		Payments: convertToBillPayments(payload.Payments),
+		IdBill:          payload.IdBill,
+		DsBill:          payload.DsBill,
+		VlBill:          payload.VlBill,
+		QtPerson:        payload.QtPerson,
+		TpSplit:         payload.TpSplit,
+		PcTax:           payload.PcTax,
+		PcServiceCharge: payload.PcServiceCharge,
+		PcTip:           payload.PcTip,
+		CdCurrency:      payload.CdCurrency,
+		Items:           payload.Items,
+		Payments:        convertToBillPayments(payload.Payments),
+	}
+
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	if err := h.resolveParticipants(bill.Payments, userId); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
 	}
 
-	if err := h.store.UpdateBill(bill); err != nil {
+	if err := h.store.UpdateBill(bill, userId); err != nil {
+		if errors.Is(err, ErrBillNotFound) {
+			utils.WriterError(w, http.StatusNotFound, err)
+			return
+		}
+
+		if errors.Is(err, payment.ErrPaymentHasHistory) {
+			utils.WriterError(w, http.StatusConflict, err)
+			return
+		}
+
+		if errors.Is(err, ErrInvalidSplit) {
+			utils.WriterError(w, http.StatusBadRequest, err)
+			return
+		}
+
 		utils.WriterError(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusOK, bill)
+	updatedBill, err := h.store.GetBillById(bill.IdBill, userId)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, updatedBill)
 }
 
 func (h *Handler) handleCreateBill(w http.ResponseWriter, r *http.Request) {
@@ -121,11 +204,24 @@ func (h *Handler) handleCreateBill(w http.ResponseWriter, r *http.Request) {
 	}
 
 	userId := auth.GetUserIDFromContext(r.Context())
+	payments := convertToBillPayments(payload.Payments)
+
+	if err := h.resolveParticipants(payments, userId); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
 	bill, err := h.store.CreateBill(types.Bill{
-		DsBill:   payload.DsBill,
-		VlBill:   payload.VlBill,
-		QtPerson: payload.QtPerson,
-		Payments: convertToBillPayments(payload.Payments),
+		DsBill:          payload.DsBill,
+		VlBill:          payload.VlBill,
+		QtPerson:        payload.QtPerson,
+		TpSplit:         payload.TpSplit,
+		PcTax:           payload.PcTax,
+		PcServiceCharge: payload.PcServiceCharge,
+		PcTip:           payload.PcTip,
+		CdCurrency:      payload.CdCurrency,
+		Items:           payload.Items,
+		Payments:        payments,
 	}, userId)
 
 	if err != nil {
@@ -140,8 +236,14 @@ func (h *Handler) handleCreateBill(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) handleDeleteBill(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, _ := strconv.Atoi(vars["id"])
+	userId := auth.GetUserIDFromContext(r.Context())
 
-	err := h.store.DeleteBill(id)
+	err := h.store.DeleteBill(id, userId)
+
+	if errors.Is(err, ErrBillNotFound) {
+		utils.WriterError(w, http.StatusNotFound, err)
+		return
+	}
 
 	if err != nil {
 		utils.WriterError(w, http.StatusInternalServerError, err)
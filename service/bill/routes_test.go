@@ -0,0 +1,439 @@
+package bill
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/service/auth"
+	"github.com/gfmanica/splitz-backend/service/payment"
+	"github.com/gfmanica/splitz-backend/types"
+	"github.com/gorilla/mux"
+)
+
+type mockBillStore struct {
+	bills     map[int]types.Bill
+	owners    map[int]int
+	imported  map[string]bool
+	importErr error
+	// Pagamentos com transações registradas
+	history map[int]bool
+}
+
+func newMockBillStore() *mockBillStore {
+	return &mockBillStore{
+		bills:    map[int]types.Bill{1: {IdBill: 1, DsBill: "Mercado", VlBill: 10000, QtPerson: 2}},
+		owners:   map[int]int{1: 1},
+		imported: map[string]bool{},
+		history:  map[int]bool{},
+	}
+}
+
+// visible segue a consulta do banco: o dono e os participantes vinculados
+// a algum pagamento enxergam o bill.
+func (m *mockBillStore) visible(id int, userId int) bool {
+	if m.owners[id] == userId {
+		return true
+	}
+
+	for _, payment := range m.bills[id].Payments {
+		if payment.IdUser != nil && *payment.IdUser == userId {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *mockBillStore) GetBills(userId int, query types.ListQuery) (*types.Page[types.Bill], error) {
+	bills := make([]types.Bill, 0)
+
+	for id, bill := range m.bills {
+		if m.visible(id, userId) {
+			bills = append(bills, bill)
+		}
+	}
+
+	return &types.Page[types.Bill]{Items: bills, Limit: query.Limit}, nil
+}
+
+func (m *mockBillStore) GetBillById(id int, userId int) (*types.Bill, error) {
+	bill, ok := m.bills[id]
+
+	if !ok || !m.visible(id, userId) {
+		return nil, ErrBillNotFound
+	}
+
+	return &bill, nil
+}
+
+func (m *mockBillStore) CreateBill(b types.Bill, userId int) (*types.Bill, error) {
+	b.IdBill = len(m.bills) + 1
+	m.bills[b.IdBill] = b
+	m.owners[b.IdBill] = userId
+
+	return &b, nil
+}
+
+func (m *mockBillStore) UpdateBill(b types.Bill, userId int) error {
+	if _, ok := m.bills[b.IdBill]; !ok || m.owners[b.IdBill] != userId {
+		return ErrBillNotFound
+	}
+
+	kept := make(map[int]bool)
+	for _, p := range b.Payments {
+		kept[p.IdBillPayment] = true
+	}
+
+	for _, p := range m.bills[b.IdBill].Payments {
+		if m.history[p.IdBillPayment] && !kept[p.IdBillPayment] {
+			return payment.ErrPaymentHasHistory
+		}
+	}
+
+	m.bills[b.IdBill] = b
+
+	return nil
+}
+
+func (m *mockBillStore) DeleteBill(id int, userId int) error {
+	if _, ok := m.bills[id]; !ok || m.owners[id] != userId {
+		return ErrBillNotFound
+	}
+
+	delete(m.bills, id)
+
+	return nil
+}
+
+func (m *mockBillStore) GetImportedIds(externalIds []string, userId int) (map[string]bool, error) {
+	return m.imported, nil
+}
+
+func (m *mockBillStore) ImportBills(transactions []types.ImportedTransaction, template types.Bill, userId int) (*types.ImportResult, error) {
+	if m.importErr != nil {
+		return nil, m.importErr
+	}
+
+	result := &types.ImportResult{Bills: make([]types.Bill, 0), Skipped: make([]string, 0)}
+
+	for _, transaction := range transactions {
+		if m.imported[transaction.DsExternalId] {
+			result.Skipped = append(result.Skipped, transaction.DsExternalId)
+			continue
+		}
+		m.imported[transaction.DsExternalId] = true
+
+		bill := template
+		bill.DsBill = transaction.DsDescription
+		bill.VlBill = transaction.VlAmount
+
+		created, _ := m.CreateBill(bill, userId)
+		result.Bills = append(result.Bills, *created)
+	}
+
+	return result, nil
+}
+
+// mockGroupStore só responde quem é colega de grupo do usuário 1.
+type mockGroupStore struct {
+	types.GroupStore
+	mates map[int]bool
+}
+
+func (m *mockGroupStore) AreGroupMates(userId int, otherUserId int) (bool, error) {
+	return userId == otherUserId || (userId == 1 && m.mates[otherUserId]), nil
+}
+
+type mockUserStore struct {
+	types.UserStore
+}
+
+func (m *mockUserStore) GetUserByID(id int) (*types.User, error) {
+	return &types.User{ID: id, Name: fmt.Sprintf("Usuário %d", id)}, nil
+}
+
+func serveAs(userId int, method, path string, body []byte, route string, handler http.HandlerFunc) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBuffer(body))
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, userId))
+
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+
+	router.HandleFunc(route, handler)
+	router.ServeHTTP(rr, req)
+
+	return rr
+}
+
+func TestBillServiceHandlers(t *testing.T) {
+	t.Run("should return the bill to its owner", func(t *testing.T) {
+		handler := NewHandler(newMockBillStore(), nil, nil)
+
+		rr := serveAs(1, http.MethodGet, "/bill/1", nil, "/bill/{id}", handler.handleGetBill)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, rr.Code)
+		}
+	})
+
+	t.Run("should not return another user's bill", func(t *testing.T) {
+		handler := NewHandler(newMockBillStore(), nil, nil)
+
+		rr := serveAs(2, http.MethodGet, "/bill/1", nil, "/bill/{id}", handler.handleGetBill)
+
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("should not update another user's bill", func(t *testing.T) {
+		store := newMockBillStore()
+		handler := NewHandler(store, nil, nil)
+
+		marshalled, _ := json.Marshal(types.Bill{IdBill: 1, DsBill: "Alterado", VlBill: 100, QtPerson: 1})
+
+		rr := serveAs(2, http.MethodPut, "/bill", marshalled, "/bill", handler.handleUpdateBill)
+
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+		}
+
+		if store.bills[1].DsBill != "Mercado" {
+			t.Errorf("expected bill to be unchanged, got %s", store.bills[1].DsBill)
+		}
+	})
+
+	t.Run("should not drop a payment that has transactions", func(t *testing.T) {
+		store := newMockBillStore()
+		handler := NewHandler(store, nil, nil)
+
+		bill := store.bills[1]
+		bill.Payments = []types.BillPayment{{IdBillPayment: 1, DsPerson: "Ana"}, {IdBillPayment: 2, DsPerson: "Bruno"}}
+		store.bills[1] = bill
+		store.history[2] = true
+
+		marshalled, _ := json.Marshal(types.Bill{IdBill: 1, DsBill: "Mercado", VlBill: 10000, QtPerson: 1,
+			Payments: []types.BillPayment{{IdBillPayment: 1, DsPerson: "Ana"}}})
+
+		rr := serveAs(1, http.MethodPut, "/bill", marshalled, "/bill", handler.handleUpdateBill)
+
+		if rr.Code != http.StatusConflict {
+			t.Errorf("expected status %d, got %d", http.StatusConflict, rr.Code)
+		}
+
+		if len(store.bills[1].Payments) != 2 {
+			t.Errorf("expected both payments to be kept, got %+v", store.bills[1].Payments)
+		}
+	})
+
+	t.Run("should reject a negative number of people", func(t *testing.T) {
+		store := newMockBillStore()
+		handler := NewHandler(store, nil, nil)
+
+		marshalled, _ := json.Marshal(types.CreateBillPayload{DsBill: "Jantar", VlBill: 6000, QtPerson: -2})
+
+		rr := serveAs(1, http.MethodPost, "/bill", marshalled, "/bill", handler.handleCreateBill)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+
+		if len(store.bills) != 1 {
+			t.Errorf("expected no bill to be created, got %d bills", len(store.bills))
+		}
+	})
+
+	t.Run("should not delete another user's bill", func(t *testing.T) {
+		store := newMockBillStore()
+		handler := NewHandler(store, nil, nil)
+
+		rr := serveAs(2, http.MethodDelete, "/bill/1", nil, "/bill/{id}", handler.handleDeleteBill)
+
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+		}
+
+		if _, ok := store.bills[1]; !ok {
+			t.Errorf("expected bill to still exist")
+		}
+	})
+
+	t.Run("should delete the bill for its owner", func(t *testing.T) {
+		store := newMockBillStore()
+		handler := NewHandler(store, nil, nil)
+
+		rr := serveAs(1, http.MethodDelete, "/bill/1", nil, "/bill/{id}", handler.handleDeleteBill)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, rr.Code)
+		}
+	})
+}
+
+func TestBillImportHandlers(t *testing.T) {
+	ofx := "<OFX><ACCTID>1<STMTTRN><DTPOSTED>20250203<TRNAMT>-50.00<FITID>A<NAME>Mercado</STMTTRN>" +
+		"<STMTTRN><DTPOSTED>20250204<TRNAMT>-20.00<FITID>B<NAME>Padaria</STMTTRN></OFX>"
+
+	t.Run("should mark transactions that were already imported", func(t *testing.T) {
+		store := newMockBillStore()
+		store.imported["ofx:1:A"] = true
+		handler := NewHandler(store, nil, nil)
+
+		marshalled, _ := json.Marshal(types.PreviewImportPayload{TpFormat: types.ImportOFX, DsContent: ofx})
+
+		rr := serveAs(1, http.MethodPost, "/bill/import/preview", marshalled, "/bill/import/preview", handler.handlePreviewImport)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		var transactions []types.ImportedTransaction
+		json.NewDecoder(rr.Body).Decode(&transactions)
+
+		if len(transactions) != 2 || !transactions[0].FgImported || transactions[1].FgImported {
+			t.Errorf("expected only the first transaction to be imported, got %+v", transactions)
+		}
+	})
+
+	t.Run("should require a column mapping for csv files", func(t *testing.T) {
+		handler := NewHandler(newMockBillStore(), nil, nil)
+
+		marshalled, _ := json.Marshal(types.PreviewImportPayload{TpFormat: types.ImportCSV, DsContent: "Data;Valor\n"})
+
+		rr := serveAs(1, http.MethodPost, "/bill/import/preview", marshalled, "/bill/import/preview", handler.handlePreviewImport)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("should skip transactions that were already imported", func(t *testing.T) {
+		store := newMockBillStore()
+		handler := NewHandler(store, nil, nil)
+
+		transaction := types.ImportedTransaction{
+			DsExternalId:  "ofx:1:A",
+			DtTransaction: time.Date(2025, 2, 3, 0, 0, 0, 0, time.UTC),
+			DsDescription: "Mercado",
+			VlAmount:      5000,
+			TpEntry:       types.EntryDebit,
+		}
+
+		marshalled, _ := json.Marshal(types.ImportBillsPayload{
+			Transactions: []types.ImportedTransaction{transaction, transaction},
+			QtPerson:     2,
+		})
+
+		rr := serveAs(1, http.MethodPost, "/bill/import", marshalled, "/bill/import", handler.handleImportBills)
+
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d", http.StatusCreated, rr.Code)
+		}
+
+		var result types.ImportResult
+		json.NewDecoder(rr.Body).Decode(&result)
+
+		if len(result.Bills) != 1 || len(result.Skipped) != 1 || result.Bills[0].DsBill != "Mercado" {
+			t.Errorf("expected one bill and one skipped transaction, got %+v", result)
+		}
+	})
+
+	t.Run("should answer 400 only for an invalid split", func(t *testing.T) {
+		cases := map[error]int{
+			fmt.Errorf("ofx:1:A: %w: percentages add up to 90%% instead of 100%%", ErrInvalidSplit): http.StatusBadRequest,
+			errors.New("connection reset by peer"):                                                  http.StatusInternalServerError,
+		}
+
+		for importErr, expected := range cases {
+			store := newMockBillStore()
+			store.importErr = importErr
+			handler := NewHandler(store, nil, nil)
+
+			marshalled, _ := json.Marshal(types.ImportBillsPayload{
+				Transactions: []types.ImportedTransaction{{
+					DsExternalId:  "ofx:1:A",
+					DtTransaction: time.Date(2025, 2, 3, 0, 0, 0, 0, time.UTC),
+					DsDescription: "Mercado",
+					VlAmount:      5000,
+					TpEntry:       types.EntryDebit,
+				}},
+				QtPerson: 2,
+			})
+
+			rr := serveAs(1, http.MethodPost, "/bill/import", marshalled, "/bill/import", handler.handleImportBills)
+
+			if rr.Code != expected {
+				t.Errorf("expected status %d for %q, got %d", expected, importErr, rr.Code)
+			}
+		}
+	})
+}
+
+func TestBillParticipants(t *testing.T) {
+	mate := 2
+	stranger := 3
+
+	t.Run("should show the bill to a group mate linked to a payment", func(t *testing.T) {
+		store := newMockBillStore()
+		handler := NewHandler(store, &mockUserStore{}, &mockGroupStore{mates: map[int]bool{mate: true}})
+
+		marshalled, _ := json.Marshal(types.CreateBillPayload{
+			DsBill:   "Jantar",
+			VlBill:   6000,
+			QtPerson: 2,
+			Payments: []types.BillPayment{{DsPerson: "Ana"}, {IdUser: &mate}},
+		})
+
+		rr := serveAs(1, http.MethodPost, "/bill", marshalled, "/bill", handler.handleCreateBill)
+
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+		}
+
+		var bill types.Bill
+		json.NewDecoder(rr.Body).Decode(&bill)
+
+		if bill.Payments[1].DsPerson != "Usuário 2" {
+			t.Errorf("expected the participant name to be filled, got %q", bill.Payments[1].DsPerson)
+		}
+
+		path := fmt.Sprintf("/bill/%d", bill.IdBill)
+
+		if rr := serveAs(mate, http.MethodGet, path, nil, "/bill/{id}", handler.handleGetBill); rr.Code != http.StatusOK {
+			t.Errorf("expected status %d for the participant, got %d", http.StatusOK, rr.Code)
+		}
+
+		if rr := serveAs(stranger, http.MethodGet, path, nil, "/bill/{id}", handler.handleGetBill); rr.Code != http.StatusNotFound {
+			t.Errorf("expected status %d for someone else, got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("should reject a participant outside the user's groups", func(t *testing.T) {
+		store := newMockBillStore()
+		handler := NewHandler(store, &mockUserStore{}, &mockGroupStore{mates: map[int]bool{mate: true}})
+
+		marshalled, _ := json.Marshal(types.CreateBillPayload{
+			DsBill:   "Jantar",
+			VlBill:   6000,
+			QtPerson: 2,
+			Payments: []types.BillPayment{{DsPerson: "Ana"}, {IdUser: &stranger}},
+		})
+
+		rr := serveAs(1, http.MethodPost, "/bill", marshalled, "/bill", handler.handleCreateBill)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+
+		if len(store.bills) != 1 {
+			t.Errorf("expected no bill to be created, got %d bills", len(store.bills))
+		}
+	})
+}
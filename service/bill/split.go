@@ -0,0 +1,252 @@
+package bill
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+var ErrInvalidSplit = fmt.Errorf("invalid split")
+
+func splitMode(bill types.Bill) string {
+	if bill.TpSplit == "" {
+		return types.SplitEqual
+	}
+
+	return bill.TpSplit
+}
+
+func currencyCode(bill types.Bill) string {
+	if bill.CdCurrency == "" {
+		return types.DefaultCurrency
+	}
+
+	return bill.CdCurrency
+}
+
+func personName(dsPerson string) string {
+	return strings.ToLower(strings.TrimSpace(dsPerson))
+}
+
+// itemsTotal retorna a soma dos itens acrescida de taxa, serviço e gorjeta.
+func itemsTotal(bill types.Bill) types.Money {
+	subtotal := types.Money(0)
+
+	for _, item := range bill.Items {
+		subtotal += item.VlItem.Mul(itemQuantity(item))
+	}
+
+	return subtotal + subtotal.Percent(bill.PcTax+bill.PcServiceCharge+bill.PcTip)
+}
+
+func itemQuantity(item types.BillItem) int {
+	if item.QtItem <= 0 {
+		return 1
+	}
+
+	return item.QtItem
+}
+
+// itemWeights calcula quanto cada pagamento consumiu dos itens. Um item sem
+// participantes é dividido entre todos.
+func itemWeights(bill types.Bill) ([]int64, error) {
+	weights := make([]int64, len(bill.Payments))
+	indexByName := make(map[string]int)
+
+	for i, payment := range bill.Payments {
+		name := personName(payment.DsPerson)
+
+		if _, ok := indexByName[name]; ok {
+			return nil, fmt.Errorf("%w: person %q appears more than once", ErrInvalidSplit, payment.DsPerson)
+		}
+
+		indexByName[name] = i
+	}
+
+	for _, item := range bill.Items {
+		participants := make([]int, 0)
+
+		for _, dsPerson := range item.Participants {
+			i, ok := indexByName[personName(dsPerson)]
+
+			if !ok {
+				return nil, fmt.Errorf("%w: item %q references unknown person %q", ErrInvalidSplit, item.DsItem, dsPerson)
+			}
+
+			participants = append(participants, i)
+		}
+
+		if len(participants) == 0 {
+			for i := range bill.Payments {
+				participants = append(participants, i)
+			}
+		}
+
+		shares := item.VlItem.Mul(itemQuantity(item)).Allocate(len(participants))
+
+		for j, i := range participants {
+			weights[i] += shares[j].Cents()
+		}
+	}
+
+	return weights, nil
+}
+
+// checkQtPerson confere que a quantidade de pessoas é um número inteiro
+// positivo e que comporta todos os pagamentos enviados.
+func checkQtPerson(bill types.Bill) error {
+	if bill.QtPerson <= 0 || bill.QtPerson != math.Trunc(bill.QtPerson) {
+		return fmt.Errorf("%w: qtPerson must be a positive whole number, got %v", ErrInvalidSplit, bill.QtPerson)
+	}
+
+	if len(bill.Payments) > int(bill.QtPerson) {
+		return fmt.Errorf("%w: %d payments for %v people", ErrInvalidSplit, len(bill.Payments), bill.QtPerson)
+	}
+
+	return nil
+}
+
+// ValidateBill confere a quantidade de pessoas e a divisão do bill como a
+// criação faria, sem gravá-lo. Os modelos recorrentes usam para recusar um
+// modelo que nunca geraria um bill válido.
+func ValidateBill(billPayload types.Bill) error {
+	if err := checkQtPerson(billPayload); err != nil {
+		return err
+	}
+
+	billPayload.Payments = billPayments(billPayload)
+
+	_, _, err := computeShares(billPayload)
+
+	return err
+}
+
+// defaultShares assume uma cota para os pagamentos sem qtShare, que de
+// outra forma seriam gravados com zero e ficariam fora da divisão.
+func defaultShares(payments []types.BillPayment) {
+	for i := range payments {
+		if payments[i].QtShare == 0 {
+			payments[i].QtShare = 1
+		}
+	}
+}
+
+// computeShares calcula o valor de cada pagamento conforme o modo de divisão
+// do bill. Pagamentos customizados mantêm o próprio valor e o restante é
+// dividido entre os demais: igualmente, por cotas, por percentual ou, se o
+// bill tiver itens, na proporção do que cada um consumiu. No modo exato cada
+// pagamento já traz o seu valor. Retorna também o valor total do bill, que
+// nos bills com itens é derivado deles.
+func computeShares(bill types.Bill) ([]types.Money, types.Money, error) {
+	mode := splitMode(bill)
+
+	if len(bill.Items) > 0 && mode != types.SplitEqual {
+		return nil, 0, fmt.Errorf("%w: bills with items can only use the %s split mode", ErrInvalidSplit, types.SplitEqual)
+	}
+
+	if mode == types.SplitExact {
+		return exactShares(bill)
+	}
+
+	total := bill.VlBill
+	weights := make([]int64, len(bill.Payments))
+
+	for i, payment := range bill.Payments {
+		switch mode {
+		case types.SplitShares:
+			if payment.QtShare < 0 {
+				return nil, 0, fmt.Errorf("%w: %s has a negative share", ErrInvalidSplit, payment.DsPerson)
+			}
+			weights[i] = int64(payment.QtShare)
+		case types.SplitPercentage:
+			if payment.PcPayment < 0 {
+				return nil, 0, fmt.Errorf("%w: %s has a negative percentage", ErrInvalidSplit, payment.DsPerson)
+			}
+			weights[i] = int64(payment.PcPayment)
+		default:
+			weights[i] = 1
+		}
+	}
+
+	if len(bill.Items) > 0 {
+		var err error
+
+		total = itemsTotal(bill)
+		weights, err = itemWeights(bill)
+
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	shares := make([]types.Money, len(bill.Payments))
+	remaining := total
+	nonCustom := make([]int, 0)
+	nonCustomWeights := make([]int64, 0)
+	var totalWeight int64
+
+	for i, payment := range bill.Payments {
+		if payment.FgCustomPayment {
+			shares[i] = payment.VlPayment
+			remaining -= payment.VlPayment
+			continue
+		}
+
+		nonCustom = append(nonCustom, i)
+		nonCustomWeights = append(nonCustomWeights, weights[i])
+		totalWeight += weights[i]
+	}
+
+	if remaining < 0 {
+		return nil, 0, fmt.Errorf("%w: custom payments add up to more than the bill total of %s", ErrInvalidSplit, total)
+	}
+
+	if len(nonCustom) == 0 {
+		if remaining != 0 {
+			return nil, 0, fmt.Errorf("%w: payments add up to %s but the bill total is %s", ErrInvalidSplit, total-remaining, total)
+		}
+
+		return shares, total, nil
+	}
+
+	switch {
+	case mode == types.SplitPercentage && types.Percent(totalWeight) != types.OneHundredPercent:
+		return nil, 0, fmt.Errorf("%w: percentages add up to %s%% instead of 100%%", ErrInvalidSplit, types.Percent(totalWeight))
+	case mode == types.SplitShares && totalWeight == 0:
+		return nil, 0, fmt.Errorf("%w: at least one person must have a share", ErrInvalidSplit)
+	case totalWeight == 0:
+		// Sem consumo registrado entre os não customizados, divide igualmente
+		for i := range nonCustomWeights {
+			nonCustomWeights[i] = 1
+		}
+	}
+
+	for j, share := range remaining.AllocateByWeights(nonCustomWeights) {
+		shares[nonCustom[j]] = share
+	}
+
+	return shares, total, nil
+}
+
+// exactShares usa o valor informado em cada pagamento, que devem somar o total do bill.
+func exactShares(bill types.Bill) ([]types.Money, types.Money, error) {
+	shares := make([]types.Money, len(bill.Payments))
+	sum := types.Money(0)
+
+	for i, payment := range bill.Payments {
+		if payment.VlPayment < 0 {
+			return nil, 0, fmt.Errorf("%w: %s has a negative amount", ErrInvalidSplit, payment.DsPerson)
+		}
+
+		shares[i] = payment.VlPayment
+		sum += payment.VlPayment
+	}
+
+	if sum != bill.VlBill {
+		return nil, 0, fmt.Errorf("%w: exact amounts add up to %s but the bill total is %s", ErrInvalidSplit, sum, bill.VlBill)
+	}
+
+	return shares, bill.VlBill, nil
+}
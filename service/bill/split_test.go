@@ -0,0 +1,190 @@
+package bill
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+func TestComputeShares(t *testing.T) {
+	t.Run("should split the remainder equally and keep custom payments", func(t *testing.T) {
+		shares, total, err := computeShares(types.Bill{
+			VlBill: 10000,
+			Payments: []types.BillPayment{
+				{DsPerson: "Ana", VlPayment: 4000, FgCustomPayment: true},
+				{DsPerson: "Bruno"},
+				{DsPerson: "Carla"},
+				{DsPerson: "Davi"},
+			},
+		})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := []types.Money{4000, 2000, 2000, 2000}
+
+		for i, share := range shares {
+			if share != expected[i] {
+				t.Errorf("expected share %d to be %s, got %s", i, expected[i], share)
+			}
+		}
+
+		if total != 10000 {
+			t.Errorf("expected total 100.00, got %s", total)
+		}
+	})
+
+	t.Run("should derive shares from items with service charge", func(t *testing.T) {
+		shares, total, err := computeShares(types.Bill{
+			PcServiceCharge: 1000,
+			Items: []types.BillItem{
+				{DsItem: "Pizza", VlItem: 6000, QtItem: 1},
+				{DsItem: "Cerveja", VlItem: 1000, QtItem: 2, Participants: []string{"Ana"}},
+			},
+			Payments: []types.BillPayment{
+				{DsPerson: "Ana"},
+				{DsPerson: "Bruno"},
+				{DsPerson: "Carla"},
+			},
+		})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Subtotal de 80.00 com 10% de serviço
+		if total != 8800 {
+			t.Errorf("expected total 88.00, got %s", total)
+		}
+
+		expected := []types.Money{4400, 2200, 2200}
+
+		var sum types.Money
+		for i, share := range shares {
+			if share != expected[i] {
+				t.Errorf("expected share %d to be %s, got %s", i, expected[i], share)
+			}
+			sum += share
+		}
+
+		if sum != total {
+			t.Errorf("expected shares to add up to %s, got %s", total, sum)
+		}
+	})
+
+	t.Run("should reject items for unknown participants", func(t *testing.T) {
+		_, _, err := computeShares(types.Bill{
+			Items:    []types.BillItem{{DsItem: "Pizza", VlItem: 6000, Participants: []string{"Zé"}}},
+			Payments: []types.BillPayment{{DsPerson: "Ana"}},
+		})
+
+		if !errors.Is(err, ErrInvalidSplit) {
+			t.Errorf("expected ErrInvalidSplit, got %v", err)
+		}
+	})
+
+	t.Run("should split by shares", func(t *testing.T) {
+		shares, _, err := computeShares(types.Bill{
+			VlBill:  10000,
+			TpSplit: types.SplitShares,
+			Payments: []types.BillPayment{
+				{DsPerson: "Ana", QtShare: 2},
+				{DsPerson: "Bruno", QtShare: 1},
+				{DsPerson: "Carla", QtShare: 1},
+			},
+		})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := []types.Money{5000, 2500, 2500}
+
+		for i, share := range shares {
+			if share != expected[i] {
+				t.Errorf("expected share %d to be %s, got %s", i, expected[i], share)
+			}
+		}
+	})
+
+	t.Run("should count one share for payments without qtShare", func(t *testing.T) {
+		payments := []types.BillPayment{
+			{DsPerson: "Ana", QtShare: 2},
+			{DsPerson: "Bruno"},
+			{DsPerson: "Carla", QtShare: 0},
+		}
+
+		defaultShares(payments)
+
+		shares, _, err := computeShares(types.Bill{VlBill: 10000, TpSplit: types.SplitShares, Payments: payments})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := []types.Money{5000, 2500, 2500}
+
+		for i, share := range shares {
+			if share != expected[i] {
+				t.Errorf("expected share %d to be %s, got %s", i, expected[i], share)
+			}
+		}
+
+		if payments[1].QtShare != 1 || payments[2].QtShare != 1 {
+			t.Errorf("expected missing shares to be stored as 1, got %d and %d", payments[1].QtShare, payments[2].QtShare)
+		}
+	})
+
+	t.Run("should reject percentages that do not add up to 100", func(t *testing.T) {
+		_, _, err := computeShares(types.Bill{
+			VlBill:  10000,
+			TpSplit: types.SplitPercentage,
+			Payments: []types.BillPayment{
+				{DsPerson: "Ana", PcPayment: 6000},
+				{DsPerson: "Bruno", PcPayment: 3000},
+			},
+		})
+
+		if !errors.Is(err, ErrInvalidSplit) {
+			t.Errorf("expected ErrInvalidSplit, got %v", err)
+		}
+	})
+
+	t.Run("should reject exact amounts that do not add up to the bill total", func(t *testing.T) {
+		_, _, err := computeShares(types.Bill{
+			VlBill:  10000,
+			TpSplit: types.SplitExact,
+			Payments: []types.BillPayment{
+				{DsPerson: "Ana", VlPayment: 6000},
+				{DsPerson: "Bruno", VlPayment: 3000},
+			},
+		})
+
+		if !errors.Is(err, ErrInvalidSplit) {
+			t.Errorf("expected ErrInvalidSplit, got %v", err)
+		}
+	})
+}
+
+func TestCheckQtPerson(t *testing.T) {
+	t.Run("should reject invalid people counts", func(t *testing.T) {
+		for _, bill := range []types.Bill{
+			{QtPerson: -1},
+			{QtPerson: 0},
+			{QtPerson: 2.5},
+			{QtPerson: 1, Payments: []types.BillPayment{{DsPerson: "Ana"}, {DsPerson: "Bruno"}}},
+		} {
+			if err := checkQtPerson(bill); !errors.Is(err, ErrInvalidSplit) {
+				t.Errorf("expected ErrInvalidSplit for %v people and %d payments, got %v", bill.QtPerson, len(bill.Payments), err)
+			}
+		}
+	})
+
+	t.Run("should accept fewer payments than people", func(t *testing.T) {
+		if err := checkQtPerson(types.Bill{QtPerson: 3, Payments: []types.BillPayment{{DsPerson: "Ana"}}}); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+}
@@ -3,10 +3,15 @@ package bill
 import (
 	"database/sql"
 	"fmt"
+	"strconv"
 
+	"github.com/gfmanica/splitz-backend/service/listing"
+	"github.com/gfmanica/splitz-backend/service/payment"
 	"github.com/gfmanica/splitz-backend/types"
 )
 
+var ErrBillNotFound = fmt.Errorf("bill not found")
+
 type Store struct {
 	db *sql.DB
 }
@@ -15,8 +20,67 @@ func NewStore(db *sql.DB) *Store {
 	return &Store{db: db}
 }
 
-func (s *Store) GetBills(userId int) ([]types.Bill, error) {
-	rows, err := s.db.Query("SELECT id_bill, ds_bill, vl_bill, qt_person FROM bill WHERE id_user = $1 ORDER BY id_bill DESC", userId)
+// Sorts são as chaves de ordenação aceitas em GET /bill e na exportação.
+var Sorts = map[string]listing.Sort[types.Bill]{
+	"id": {Expr: "b.id_bill", Cast: "integer", Value: func(b types.Bill) string {
+		return strconv.Itoa(b.IdBill)
+	}},
+	"dsBill": {Expr: "b.ds_bill", Cast: "text", Value: func(b types.Bill) string {
+		return b.DsBill
+	}},
+	"vlBill": {Expr: "b.vl_bill", Cast: "numeric", Value: func(b types.Bill) string {
+		return b.VlBill.String()
+	}},
+	"createdAt": {Expr: "b.created_at", Cast: "timestamp", Value: func(b types.Bill) string {
+		return b.CreatedAt.Format("2006-01-02 15:04:05.999999")
+	}},
+}
+
+// unsettledBill indica um bill com algum pagamento ainda em aberto.
+const unsettledBill = `EXISTS (
+	SELECT 1 FROM bill_payment bp
+	WHERE bp.id_bill = b.id_bill
+	AND bp.vl_payment > (SELECT COALESCE(SUM(pt.vl_payment), 0) FROM payment_transaction pt WHERE pt.id_bill_payment = bp.id_bill_payment))`
+
+func (s *Store) GetBills(userId int, query types.ListQuery) (*types.Page[types.Bill], error) {
+	sort := Sorts[query.Sort]
+	builder := &listing.Builder{}
+
+	// Além dos bills do usuário, retorna aqueles em que ele é participante
+	user := builder.Arg(userId)
+	builder.Where(fmt.Sprintf("(b.id_user = %s OR EXISTS (SELECT 1 FROM bill_payment bp WHERE bp.id_bill = b.id_bill AND bp.id_user = %s))", user, user))
+
+	if query.Search != "" {
+		builder.Where("b.ds_bill ILIKE " + builder.Arg(listing.Contains(query.Search)))
+	}
+
+	if query.Person != "" {
+		builder.Where("EXISTS (SELECT 1 FROM bill_payment bp WHERE bp.id_bill = b.id_bill AND bp.ds_person ILIKE " + builder.Arg(listing.Contains(query.Person)) + ")")
+	}
+
+	switch query.Status {
+	case types.StatusSettled:
+		builder.Where("NOT " + unsettledBill)
+	case types.StatusUnsettled:
+		builder.Where(unsettledBill)
+	}
+
+	if query.DtFrom != nil {
+		builder.Where("b.created_at >= " + builder.Arg(*query.DtFrom))
+	}
+
+	if query.DtTo != nil {
+		builder.Where("b.created_at < " + builder.Arg(query.DtTo.AddDate(0, 0, 1)))
+	}
+
+	listing.After(builder, sort, "b.id_bill", query)
+
+	rows, err := s.db.Query(`
+		SELECT b.id_bill, b.ds_bill, b.vl_bill, b.qt_person, b.tp_split, b.pc_tax, b.pc_service_charge, b.pc_tip, b.cd_currency, b.id_user, b.created_at
+		FROM bill b
+		`+builder.WhereClause()+`
+		ORDER BY `+listing.OrderBy(sort, "b.id_bill", query.Order)+`
+		LIMIT `+builder.Arg(query.Limit+1), builder.Args()...)
 
 	if err != nil {
 		return nil, err
@@ -28,49 +92,200 @@ func (s *Store) GetBills(userId int) ([]types.Bill, error) {
 		bill, err := scanRowIntoBill(rows)
 
 		if err != nil {
+			rows.Close()
 			return nil, err
 		}
 
 		bills = append(bills, *bill)
 	}
+	rows.Close()
+
+	page := listing.NewPage(bills, query, sort, func(b types.Bill) int { return b.IdBill })
 
-	return bills, nil
+	if query.FgPayments || query.FgSummary {
+		ids := make([]int64, len(page.Items))
+		for i, bill := range page.Items {
+			ids[i] = int64(bill.IdBill)
+		}
+
+		payments, err := s.getPayments(ids)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, bill := range page.Items {
+			if query.FgPayments {
+				page.Items[i].Payments = payments[bill.IdBill]
+			}
+
+			if query.FgSummary {
+				page.Items[i].Summary = summarize(payments[bill.IdBill])
+			}
+		}
+	}
+
+	return page, nil
+}
+
+func summarize(payments []types.BillPayment) *types.PaymentSummary {
+	vlPayments := make([]types.Money, len(payments))
+	vlPaids := make([]types.Money, len(payments))
+
+	for i, p := range payments {
+		vlPayments[i] = p.VlPayment
+		vlPaids[i] = p.VlPaid
+	}
+
+	return payment.Summarize(vlPayments, vlPaids)
 }
 
-func (s *Store) GetBillById(id int) (*types.Bill, error) {
-	rows, err := s.db.Query("SELECT id_bill, ds_bill, vl_bill, qt_person FROM bill WHERE id_bill = $1", id)
+func (s *Store) GetBillById(id int, userId int) (*types.Bill, error) {
+	rows, err := s.db.Query(`
+		SELECT b.id_bill, b.ds_bill, b.vl_bill, b.qt_person, b.tp_split, b.pc_tax, b.pc_service_charge, b.pc_tip, b.cd_currency, b.id_user, b.created_at
+		FROM bill b
+		WHERE b.id_bill = $1
+		AND (b.id_user = $2 OR EXISTS (SELECT 1 FROM bill_payment bp WHERE bp.id_bill = b.id_bill AND bp.id_user = $2))`, id, userId)
 	if err != nil {
 		return nil, err
 	}
 
 	bill := &types.Bill{}
 	if rows.Next() {
-		err := rows.Scan(&bill.IdBill, &bill.DsBill, &bill.VlBill, &bill.QtPerson)
+		bill, err = scanRowIntoBill(rows)
 		if err != nil {
 			return nil, err
 		}
 	}
+	rows.Close()
+
+	if bill.IdBill == 0 {
+		return nil, ErrBillNotFound
+	}
 
-	paymentRows, err := s.db.Query("SELECT id_bill_payment, vl_payment,  ds_person, fg_payed, fg_custom_payment, id_bill FROM bill_payment WHERE id_bill = $1 ORDER BY id_bill_payment ASC ", id)
+	payments, err := s.getPayments([]int64{int64(id)})
 	if err != nil {
 		return nil, err
 	}
 
-	bill.Payments = make([]types.BillPayment, 0)
-	for paymentRows.Next() {
-		payment := types.BillPayment{}
-		err := paymentRows.Scan(&payment.IdBillPayment, &payment.VlPayment, &payment.DsPerson, &payment.FgPayed, &payment.FgCustomPayment, &payment.IdBill)
+	bill.Payments = payments[id]
+	if bill.Payments == nil {
+		bill.Payments = make([]types.BillPayment, 0)
+	}
+
+	bill.Items, err = s.getItems(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return bill, nil
+}
+
+// getPayments carrega os pagamentos dos bills informados, agrupados por bill.
+// O valor pago e a data do último pagamento vêm das transações.
+func (s *Store) getPayments(ids []int64) (map[int][]types.BillPayment, error) {
+	rows, err := s.db.Query(`
+		SELECT bp.id_bill_payment, bp.vl_payment, bp.ds_person, bp.fg_custom_payment, bp.id_bill, bp.id_user, bp.qt_share, bp.pc_payment, t.vl_paid, t.dt_payment
+		FROM bill_payment bp
+		CROSS JOIN LATERAL (
+			SELECT COALESCE(SUM(pt.vl_payment), 0) AS vl_paid,
+			MAX(pt.dt_payment) FILTER (WHERE pt.id_reversed_transaction IS NULL
+				AND NOT EXISTS (SELECT 1 FROM payment_transaction r WHERE r.id_reversed_transaction = pt.id_payment_transaction)) AS dt_payment
+			FROM payment_transaction pt
+			WHERE pt.id_bill_payment = bp.id_bill_payment
+		) t
+		WHERE bp.id_bill = ANY($1)
+		ORDER BY bp.id_bill_payment ASC`, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	payments := make(map[int][]types.BillPayment)
+	for rows.Next() {
+		p := types.BillPayment{}
+		err := rows.Scan(&p.IdBillPayment, &p.VlPayment, &p.DsPerson, &p.FgCustomPayment, &p.IdBill, &p.IdUser, &p.QtShare, &p.PcPayment, &p.VlPaid, &p.DtPayment)
 		if err != nil {
 			return nil, err
 		}
-		bill.Payments = append(bill.Payments, payment)
+		p.VlOutstanding, p.FgPayed = payment.Status(p.VlPayment, p.VlPaid)
+		payments[p.IdBill] = append(payments[p.IdBill], p)
 	}
 
-	if bill.IdBill == 0 {
-		return nil, nil
+	return payments, nil
+}
+
+func (s *Store) getItems(idBill int) ([]types.BillItem, error) {
+	rows, err := s.db.Query("SELECT id_bill_item, id_bill, ds_item, vl_item, qt_item FROM bill_item WHERE id_bill = $1 ORDER BY id_bill_item ASC", idBill)
+	if err != nil {
+		return nil, err
 	}
 
-	return bill, nil
+	items := make([]types.BillItem, 0)
+	indexById := make(map[int]int)
+	for rows.Next() {
+		item := types.BillItem{Participants: make([]string, 0)}
+		err := rows.Scan(&item.IdBillItem, &item.IdBill, &item.DsItem, &item.VlItem, &item.QtItem)
+		if err != nil {
+			return nil, err
+		}
+		indexById[item.IdBillItem] = len(items)
+		items = append(items, item)
+	}
+
+	participantRows, err := s.db.Query(`
+		SELECT bip.id_bill_item, bp.ds_person
+		FROM bill_item_participant bip
+		INNER JOIN bill_payment bp ON bp.id_bill_payment = bip.id_bill_payment
+		WHERE bp.id_bill = $1
+		ORDER BY bp.id_bill_payment ASC`, idBill)
+	if err != nil {
+		return nil, err
+	}
+
+	for participantRows.Next() {
+		var idBillItem int
+		var dsPerson string
+		if err := participantRows.Scan(&idBillItem, &dsPerson); err != nil {
+			return nil, err
+		}
+		i := indexById[idBillItem]
+		items[i].Participants = append(items[i].Participants, dsPerson)
+	}
+
+	return items, nil
+}
+
+// insertItems grava os itens do bill e vincula cada um aos pagamentos
+// dos participantes, encontrados pelo nome da pessoa.
+func insertItems(tx *sql.Tx, idBill int, items []types.BillItem, payments []types.BillPayment) error {
+	paymentByName := make(map[string]int)
+	for _, payment := range payments {
+		paymentByName[personName(payment.DsPerson)] = payment.IdBillPayment
+	}
+
+	for _, item := range items {
+		var idBillItem int
+		err := tx.QueryRow("INSERT INTO bill_item (id_bill, ds_item, vl_item, qt_item) VALUES ($1, $2, $3, $4) RETURNING id_bill_item",
+			idBill, item.DsItem, item.VlItem, itemQuantity(item)).Scan(&idBillItem)
+		if err != nil {
+			return err
+		}
+
+		for _, dsPerson := range item.Participants {
+			idBillPayment, ok := paymentByName[personName(dsPerson)]
+			if !ok {
+				return fmt.Errorf("%w: item %q references unknown person %q", ErrInvalidSplit, item.DsItem, dsPerson)
+			}
+
+			_, err := tx.Exec("INSERT INTO bill_item_participant (id_bill_item, id_bill_payment) VALUES ($1, $2) ON CONFLICT DO NOTHING", idBillItem, idBillPayment)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
 }
 
 func (s *Store) CreateBill(billPayload types.Bill, userId int) (*types.Bill, error) {
@@ -79,74 +294,138 @@ func (s *Store) CreateBill(billPayload types.Bill, userId int) (*types.Bill, err
 		return nil, err
 	}
 
-	var id int
-
-	err = tx.QueryRow("INSERT INTO bill (ds_bill, vl_bill, qt_person, id_user) VALUES ($1, $2, $3, $4) RETURNING id_bill", billPayload.DsBill, billPayload.VlBill, billPayload.QtPerson, userId).Scan(&id)
+	id, err := createBill(tx, billPayload, userId)
 	if err != nil {
 		tx.Rollback()
 		return nil, err
 	}
 
-	fmt.Print(id)
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
 
-	totalVlPayments := 0.0
+	return s.GetBillById(id, userId)
+}
 
-	for _, payment := range billPayload.Payments {
-		totalVlPayments += payment.VlPayment
-	}
+// CreateBillTx grava o bill dentro de uma transação aberta por outro
+// serviço, como a geração dos bills recorrentes.
+func CreateBillTx(tx *sql.Tx, billPayload types.Bill, userId int) (int, error) {
+	return createBill(tx, billPayload, userId)
+}
 
-	personVlBill := (billPayload.VlBill - totalVlPayments) / float64(billPayload.QtPerson)
+// billPayments monta os pagamentos do bill a partir dos enviados, completando
+// a lista até a quantidade de pessoas.
+func billPayments(billPayload types.Bill) []types.BillPayment {
+	payments := make([]types.BillPayment, 0, int(billPayload.QtPerson))
+
+	for i, p := range billPayload.Payments {
+		payment := types.BillPayment{
+			DsPerson:  p.DsPerson,
+			IdUser:    p.IdUser,
+			QtShare:   p.QtShare,
+			PcPayment: p.PcPayment,
+		}
 
-	for i := 0; i < int(billPayload.QtPerson); i++ {
-		dsPerson := fmt.Sprintf("Pessoa %d", i+1)
-		vlPayment := personVlBill
-		fgCustomPayment := false
+		if payment.DsPerson == "" {
+			payment.DsPerson = fmt.Sprintf("Pessoa %d", i+1)
+		}
 
-		if i < len(billPayload.Payments) {
-			if billPayload.Payments[i].DsPerson != "" {
-				dsPerson = billPayload.Payments[i].DsPerson
-			}
-			if billPayload.Payments[i].VlPayment != 0 {
-				vlPayment = billPayload.Payments[i].VlPayment
-				fgCustomPayment = true
-			}
+		if p.VlPayment != 0 {
+			payment.VlPayment = p.VlPayment
+			payment.FgCustomPayment = true
 		}
 
-		_, err := tx.Exec("INSERT INTO bill_payment (vl_payment, ds_person, fg_payed, fg_custom_payment, id_bill) VALUES ($1, $2, $3, $4, $5)",
-			vlPayment,
-			dsPerson,
-			false,
-			fgCustomPayment,
+		payments = append(payments, payment)
+	}
+
+	// Completa a lista de pagamentos até a quantidade de pessoas
+	for i := len(payments); i < int(billPayload.QtPerson); i++ {
+		payments = append(payments, types.BillPayment{DsPerson: fmt.Sprintf("Pessoa %d", i+1), QtShare: 1})
+	}
+
+	defaultShares(payments)
+
+	return payments
+}
+
+// createBill grava o bill com os pagamentos e itens dentro da transação
+// informada e retorna o id criado.
+func createBill(tx *sql.Tx, billPayload types.Bill, userId int) (int, error) {
+	if err := checkQtPerson(billPayload); err != nil {
+		return 0, err
+	}
+
+	payments := billPayments(billPayload)
+
+	billPayload.Payments = payments
+
+	shares, vlBill, err := computeShares(billPayload)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int
+
+	err = tx.QueryRow("INSERT INTO bill (ds_bill, vl_bill, qt_person, tp_split, pc_tax, pc_service_charge, pc_tip, cd_currency, id_user) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id_bill",
+		billPayload.DsBill, vlBill, billPayload.QtPerson, splitMode(billPayload), billPayload.PcTax, billPayload.PcServiceCharge, billPayload.PcTip, currencyCode(billPayload), userId).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, payment := range payments {
+		err := tx.QueryRow("INSERT INTO bill_payment (vl_payment, ds_person, fg_custom_payment, id_bill, id_user, qt_share, pc_payment) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id_bill_payment",
+			shares[i],
+			payment.DsPerson,
+			payment.FgCustomPayment,
 			id,
-		)
+			payment.IdUser,
+			payment.QtShare,
+			payment.PcPayment,
+		).Scan(&payments[i].IdBillPayment)
 		if err != nil {
-			tx.Rollback()
-			return nil, err
+			return 0, err
 		}
 	}
 
-	err = tx.Commit()
-	if err != nil {
-		return nil, err
+	if err := insertItems(tx, id, billPayload.Items, payments); err != nil {
+		return 0, err
 	}
 
-	return s.GetBillById(id)
+	return id, nil
 }
 
-func (s *Store) UpdateBill(billPayload types.Bill) error {
+func (s *Store) UpdateBill(billPayload types.Bill, userId int) error {
+	if err := checkQtPerson(billPayload); err != nil {
+		return err
+	}
+
+	defaultShares(billPayload.Payments)
+
 	tx, err := s.db.Begin()
 	if err != nil {
 		return err
 	}
 
 	// Atualizar a descrição, valor e quantidade de pessoas do bill
-	_, err = tx.Exec("UPDATE bill SET ds_bill = $1, vl_bill = $2, qt_person = $3 WHERE id_bill = $4",
-		billPayload.DsBill, billPayload.VlBill, billPayload.QtPerson, billPayload.IdBill)
+	result, err := tx.Exec("UPDATE bill SET ds_bill = $1, vl_bill = $2, qt_person = $3, tp_split = $4, pc_tax = $5, pc_service_charge = $6, pc_tip = $7, cd_currency = $8 WHERE id_bill = $9 AND id_user = $10",
+		billPayload.DsBill, billPayload.VlBill, billPayload.QtPerson, splitMode(billPayload), billPayload.PcTax, billPayload.PcServiceCharge, billPayload.PcTip, currencyCode(billPayload), billPayload.IdBill, userId)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
 
+	// Nenhuma linha atualizada significa que o bill não existe ou pertence a outro usuário
+	affected, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if affected == 0 {
+		tx.Rollback()
+		return ErrBillNotFound
+	}
+
 	// Obter pagamentos existentes do banco de dados
 	rows, err := tx.Query("SELECT id_bill_payment FROM bill_payment WHERE id_bill = $1", billPayload.IdBill)
 	if err != nil {
@@ -169,8 +448,8 @@ func (s *Store) UpdateBill(billPayload types.Bill) error {
 	for _, payment := range billPayload.Payments {
 		if payment.IdBillPayment != 0 {
 			// Atualizar pagamento existente
-			_, err := tx.Exec("UPDATE bill_payment SET vl_payment = $1, ds_person = $2, fg_payed = $3, fg_custom_payment = $4 WHERE id_bill_payment = $5",
-				payment.VlPayment, payment.DsPerson, payment.FgPayed, payment.FgCustomPayment, payment.IdBillPayment)
+			_, err := tx.Exec("UPDATE bill_payment SET vl_payment = $1, ds_person = $2, fg_custom_payment = $3, id_user = $4, qt_share = $5, pc_payment = $6 WHERE id_bill_payment = $7 AND id_bill = $8",
+				payment.VlPayment, payment.DsPerson, payment.FgCustomPayment, payment.IdUser, payment.QtShare, payment.PcPayment, payment.IdBillPayment, billPayload.IdBill)
 			if err != nil {
 				tx.Rollback()
 				return err
@@ -178,8 +457,8 @@ func (s *Store) UpdateBill(billPayload types.Bill) error {
 			delete(existingPayments, payment.IdBillPayment)
 		} else {
 			// Inserir novo pagamento
-			_, err := tx.Exec("INSERT INTO bill_payment (vl_payment, ds_person, fg_payed, fg_custom_payment, id_bill) VALUES ($1, $2, $3, $4, $5)",
-				payment.VlPayment, payment.DsPerson, payment.FgPayed, payment.FgCustomPayment, billPayload.IdBill)
+			_, err := tx.Exec("INSERT INTO bill_payment (vl_payment, ds_person, fg_custom_payment, id_bill, id_user, qt_share, pc_payment) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+				payment.VlPayment, payment.DsPerson, payment.FgCustomPayment, billPayload.IdBill, payment.IdUser, payment.QtShare, payment.PcPayment)
 			if err != nil {
 				tx.Rollback()
 				return err
@@ -187,8 +466,14 @@ func (s *Store) UpdateBill(billPayload types.Bill) error {
 		}
 	}
 
-	// Excluir pagamentos que não foram enviados no payload
+	// Excluir pagamentos que não foram enviados no payload, desde que não
+	// tenham transações registradas
 	for idBillPayment := range existingPayments {
+		if err := payment.CheckRemovable(tx, types.PaymentBill, idBillPayment); err != nil {
+			tx.Rollback()
+			return err
+		}
+
 		_, err := tx.Exec("DELETE FROM bill_payment WHERE id_bill_payment = $1", idBillPayment)
 		if err != nil {
 			tx.Rollback()
@@ -196,20 +481,13 @@ func (s *Store) UpdateBill(billPayload types.Bill) error {
 		}
 	}
 
-	// Inserir novos pagamentos até a quantidade total de pessoas (QtPerson)
-	totalVlPayments := 0.0
-	for _, payment := range billPayload.Payments {
-		totalVlPayments += payment.VlPayment
-	}
-
-	personVlBill := (billPayload.VlBill - totalVlPayments) / float64(billPayload.QtPerson)
+	// Inserir novos pagamentos até a quantidade total de pessoas (QtPerson).
+	// O valor é definido no recálculo abaixo.
 	for i := len(billPayload.Payments); i < int(billPayload.QtPerson); i++ {
 		dsPerson := fmt.Sprintf("Pessoa %d", i+1)
-		vlPayment := personVlBill
-		fgCustomPayment := false
 
-		_, err := tx.Exec("INSERT INTO bill_payment (vl_payment, ds_person, fg_payed, fg_custom_payment, id_bill) VALUES ($1, $2, $3, $4, $5)",
-			vlPayment, dsPerson, false, fgCustomPayment, billPayload.IdBill)
+		_, err := tx.Exec("INSERT INTO bill_payment (vl_payment, ds_person, fg_custom_payment, id_bill) VALUES ($1, $2, $3, $4)",
+			types.Money(0), dsPerson, false, billPayload.IdBill)
 		if err != nil {
 			tx.Rollback()
 			return err
@@ -217,7 +495,7 @@ func (s *Store) UpdateBill(billPayload types.Bill) error {
 	}
 
 	// Recalcular valores dos pagamentos não customizados
-	rows, err = tx.Query("SELECT id_bill_payment, vl_payment, ds_person, fg_payed, fg_custom_payment, id_bill FROM bill_payment WHERE id_bill = $1", billPayload.IdBill)
+	rows, err = tx.Query("SELECT id_bill_payment, vl_payment, ds_person, fg_custom_payment, id_bill, qt_share, pc_payment FROM bill_payment WHERE id_bill = $1 ORDER BY id_bill_payment ASC", billPayload.IdBill)
 	if err != nil {
 		tx.Rollback()
 		return err
@@ -226,7 +504,7 @@ func (s *Store) UpdateBill(billPayload types.Bill) error {
 	payments := make([]types.BillPayment, 0)
 	for rows.Next() {
 		payment := types.BillPayment{}
-		err := rows.Scan(&payment.IdBillPayment, &payment.VlPayment, &payment.DsPerson, &payment.FgPayed, &payment.FgCustomPayment, &payment.IdBill)
+		err := rows.Scan(&payment.IdBillPayment, &payment.VlPayment, &payment.DsPerson, &payment.FgCustomPayment, &payment.IdBill, &payment.QtShare, &payment.PcPayment)
 		if err != nil {
 			tx.Rollback()
 			return err
@@ -234,23 +512,18 @@ func (s *Store) UpdateBill(billPayload types.Bill) error {
 		payments = append(payments, payment)
 	}
 
-	totalCustomPayments := 0.0
-	nonCustomPaymentsCount := 0
-	for _, payment := range payments {
-		if payment.FgCustomPayment {
-			totalCustomPayments += payment.VlPayment
-		} else {
-			nonCustomPaymentsCount++
-		}
-	}
+	billPayload.Payments = payments
 
-	remainingAmount := billPayload.VlBill - totalCustomPayments
-	nonCustomPaymentValue := remainingAmount / float64(nonCustomPaymentsCount)
+	shares, vlBill, err := computeShares(billPayload)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
 
-	for _, payment := range payments {
+	for i, payment := range payments {
 		if !payment.FgCustomPayment {
 			_, err := tx.Exec("UPDATE bill_payment SET vl_payment = $1 WHERE id_bill_payment = $2",
-				nonCustomPaymentValue, payment.IdBillPayment)
+				shares[i], payment.IdBillPayment)
 			if err != nil {
 				tx.Rollback()
 				return err
@@ -258,6 +531,25 @@ func (s *Store) UpdateBill(billPayload types.Bill) error {
 		}
 	}
 
+	// Nos bills com itens o valor total é derivado deles
+	_, err = tx.Exec("UPDATE bill SET vl_bill = $1 WHERE id_bill = $2", vlBill, billPayload.IdBill)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// Os itens são regravados a cada atualização
+	_, err = tx.Exec("DELETE FROM bill_item WHERE id_bill = $1", billPayload.IdBill)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := insertItems(tx, billPayload.IdBill, billPayload.Items, payments); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		return err
@@ -266,12 +558,21 @@ func (s *Store) UpdateBill(billPayload types.Bill) error {
 	return nil
 }
 
-func (s *Store) DeleteBill(id int) error {
-	_, err := s.db.Exec("DELETE FROM bill WHERE id_bill = $1", id)
+func (s *Store) DeleteBill(id int, userId int) error {
+	result, err := s.db.Exec("DELETE FROM bill WHERE id_bill = $1 AND id_user = $2", id, userId)
 	if err != nil {
 		return err
 	}
 
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrBillNotFound
+	}
+
 	return nil
 }
 
@@ -283,6 +584,13 @@ func scanRowIntoBill(rows *sql.Rows) (*types.Bill, error) {
 		&u.DsBill,
 		&u.VlBill,
 		&u.QtPerson,
+		&u.TpSplit,
+		&u.PcTax,
+		&u.PcServiceCharge,
+		&u.PcTip,
+		&u.CdCurrency,
+		&u.IdUser,
+		&u.CreatedAt,
 	)
 
 	if err != nil {
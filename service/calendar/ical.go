@@ -0,0 +1,229 @@
+package calendar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+var ErrInvalidICal = fmt.Errorf("invalid iCalendar file")
+
+type icalEvent struct {
+	summary string
+	start   time.Time
+	end     time.Time
+	// Datas com horário têm fim inclusivo, eventos de dia inteiro não
+	endInclusive bool
+	yearly       bool
+	count        int
+	until        time.Time
+}
+
+// ParseICal lê os eventos (VEVENT) de um arquivo iCalendar e retorna um dia
+// para cada data coberta por eles. Eventos anuais (RRULE:FREQ=YEARLY), comuns
+// em calendários de feriados, são expandidos até a data limite informada.
+func ParseICal(r io.Reader, until time.Time) ([]types.CalendarDate, error) {
+	lines, err := unfoldLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(lines) == 0 || !strings.EqualFold(lines[0], "BEGIN:VCALENDAR") {
+		return nil, fmt.Errorf("%w: missing BEGIN:VCALENDAR", ErrInvalidICal)
+	}
+
+	dates := make(map[string]types.CalendarDate)
+	var event *icalEvent
+
+	for _, line := range lines {
+		name, params, value, ok := splitProperty(line)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			event = &icalEvent{}
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			if event == nil || event.start.IsZero() {
+				return nil, fmt.Errorf("%w: event without DTSTART", ErrInvalidICal)
+			}
+
+			for _, date := range event.dates(until) {
+				key := date.Format("2006-01-02")
+				if _, exists := dates[key]; !exists {
+					dates[key] = types.CalendarDate{DtDate: date, DsDate: event.summary}
+				}
+			}
+
+			event = nil
+		case event == nil:
+			continue
+		case name == "SUMMARY":
+			event.summary = unescapeText(value)
+		case name == "DTSTART":
+			event.start, _, err = parseDate(value, params)
+			if err != nil {
+				return nil, err
+			}
+		case name == "DTEND":
+			var hasTime bool
+			event.end, hasTime, err = parseDate(value, params)
+			if err != nil {
+				return nil, err
+			}
+			event.endInclusive = hasTime && !strings.HasSuffix(strings.TrimSuffix(value, "Z"), "T000000")
+		case name == "RRULE":
+			if err := event.parseRule(value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	result := make([]types.CalendarDate, 0, len(dates))
+	for _, date := range dates {
+		result = append(result, date)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].DtDate.Before(result[j].DtDate)
+	})
+
+	return result, nil
+}
+
+// unfoldLines junta as linhas dobradas (continuações começam com espaço ou tab).
+func unfoldLines(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	lines := make([]string, 0)
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+func splitProperty(line string) (name string, params string, value string, ok bool) {
+	head, value, ok := strings.Cut(line, ":")
+	if !ok {
+		return "", "", "", false
+	}
+
+	name, params, _ = strings.Cut(head, ";")
+
+	return strings.ToUpper(name), strings.ToUpper(params), value, true
+}
+
+// parseDate aceita datas (20260101) e datas com horário (20260101T090000Z),
+// guardando só o dia.
+func parseDate(value string, params string) (time.Time, bool, error) {
+	if len(value) < 8 {
+		return time.Time{}, false, fmt.Errorf("%w: invalid date %q", ErrInvalidICal, value)
+	}
+
+	date, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("%w: invalid date %q", ErrInvalidICal, value)
+	}
+
+	hasTime := len(value) > 8 && !strings.Contains(params, "VALUE=DATE")
+
+	return date, hasTime, nil
+}
+
+func (e *icalEvent) parseRule(value string) error {
+	for _, part := range strings.Split(value, ";") {
+		key, val, _ := strings.Cut(part, "=")
+
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			e.yearly = strings.EqualFold(val, "YEARLY")
+		case "COUNT":
+			count, err := strconv.Atoi(val)
+			if err != nil {
+				return fmt.Errorf("%w: invalid COUNT %q", ErrInvalidICal, val)
+			}
+			e.count = count
+		case "UNTIL":
+			until, _, err := parseDate(val, "")
+			if err != nil {
+				return err
+			}
+			e.until = until
+		}
+	}
+
+	return nil
+}
+
+// dates retorna os dias cobertos pelo evento e, nos anuais, por suas repetições.
+func (e *icalEvent) dates(limit time.Time) []time.Time {
+	days := 1
+
+	if !e.end.IsZero() {
+		days = int(e.end.Sub(e.start).Hours() / 24)
+		if e.endInclusive {
+			days++
+		}
+		days = max(days, 1)
+	}
+
+	occurrences := []time.Time{e.start}
+
+	// Outras frequências não são expandidas, só a primeira ocorrência é importada
+	if e.yearly {
+		for year := 1; ; year++ {
+			if e.count > 0 && year >= e.count {
+				break
+			}
+
+			next := e.start.AddDate(year, 0, 0)
+
+			if next.After(limit) || (!e.until.IsZero() && next.After(e.until)) {
+				break
+			}
+
+			occurrences = append(occurrences, next)
+		}
+	}
+
+	dates := make([]time.Time, 0, len(occurrences)*days)
+
+	for _, occurrence := range occurrences {
+		for i := 0; i < days; i++ {
+			dates = append(dates, occurrence.AddDate(0, 0, i))
+		}
+	}
+
+	return dates
+}
+
+func unescapeText(value string) string {
+	replacer := strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`)
+
+	return replacer.Replace(value)
+}
@@ -0,0 +1,62 @@
+package calendar
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const holidays = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20260101\r\n" +
+	"DTEND;VALUE=DATE:20260102\r\n" +
+	"SUMMARY:Confraterniza\r\n" +
+	" ção Universal\r\n" +
+	"RRULE:FREQ=YEARLY\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20261221\r\n" +
+	"DTEND;VALUE=DATE:20261224\r\n" +
+	"SUMMARY:Recesso\\, escritório fechado\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseICal(t *testing.T) {
+	t.Run("should expand multi-day and yearly events", func(t *testing.T) {
+		dates, err := ParseICal(strings.NewReader(holidays), time.Date(2027, 12, 31, 0, 0, 0, 0, time.UTC))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := []string{"2026-01-01", "2026-12-21", "2026-12-22", "2026-12-23", "2027-01-01"}
+
+		if len(dates) != len(expected) {
+			t.Fatalf("expected %d dates, got %v", len(expected), dates)
+		}
+
+		for i, date := range dates {
+			if date.DtDate.Format("2006-01-02") != expected[i] {
+				t.Errorf("expected date %d to be %s, got %s", i, expected[i], date.DtDate.Format("2006-01-02"))
+			}
+		}
+
+		if dates[0].DsDate != "Confraternização Universal" {
+			t.Errorf("expected unfolded summary, got %q", dates[0].DsDate)
+		}
+
+		if dates[1].DsDate != "Recesso, escritório fechado" {
+			t.Errorf("expected unescaped summary, got %q", dates[1].DsDate)
+		}
+	})
+
+	t.Run("should reject files that are not calendars", func(t *testing.T) {
+		_, err := ParseICal(strings.NewReader("dsRide;vlRide\n"), time.Now())
+
+		if !errors.Is(err, ErrInvalidICal) {
+			t.Errorf("expected ErrInvalidICal, got %v", err)
+		}
+	})
+}
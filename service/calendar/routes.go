@@ -0,0 +1,259 @@
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/service/auth"
+	"github.com/gfmanica/splitz-backend/types"
+	"github.com/gfmanica/splitz-backend/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+// Tamanho máximo aceito na importação de arquivos iCalendar
+const maxICalSize = 5 << 20
+
+// Até quantos anos à frente os feriados anuais são expandidos na importação
+const importYears = 5
+
+type Handler struct {
+	store     types.CalendarStore
+	userStore types.UserStore
+}
+
+func NewHandler(store types.CalendarStore, userStore types.UserStore) *Handler {
+	return &Handler{
+		store:     store,
+		userStore: userStore,
+	}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/calendar", auth.WithJWTAuth(h.handleGetCalendars, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/calendar", auth.WithJWTAuth(h.handleCreateCalendar, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/calendar/import", auth.WithJWTAuth(h.handleImportCalendar, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/calendar/{id}", auth.WithJWTAuth(h.handleGetCalendar, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/calendar/{id}", auth.WithJWTAuth(h.handleDeleteCalendar, h.userStore)).Methods(http.MethodDelete)
+	router.HandleFunc("/calendar/{id}/date", auth.WithJWTAuth(h.handleAddDates, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/calendar/{id}/date/{date}", auth.WithJWTAuth(h.handleRemoveDate, h.userStore)).Methods(http.MethodDelete)
+	router.HandleFunc("/calendar/{id}/import", auth.WithJWTAuth(h.handleImportDates, h.userStore)).Methods(http.MethodPost)
+}
+
+func (h *Handler) handleGetCalendars(w http.ResponseWriter, r *http.Request) {
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	calendars, err := h.store.GetCalendars(userId)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, calendars)
+}
+
+func (h *Handler) handleGetCalendar(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, _ := strconv.Atoi(vars["id"])
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	calendar, err := h.store.GetCalendarById(id, userId)
+
+	if errors.Is(err, ErrCalendarNotFound) {
+		utils.WriterError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, calendar)
+}
+
+func (h *Handler) handleCreateCalendar(w http.ResponseWriter, r *http.Request) {
+	var payload types.CreateCalendarPayload
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		error := err.(validator.ValidationErrors)
+		utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %s", error))
+		return
+	}
+
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	calendar, err := h.store.CreateCalendar(types.Calendar{
+		DsCalendar: payload.DsCalendar,
+		Dates:      payload.Dates,
+	}, userId)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, calendar)
+}
+
+func (h *Handler) handleDeleteCalendar(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, _ := strconv.Atoi(vars["id"])
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	err := h.store.DeleteCalendar(id, userId)
+
+	if errors.Is(err, ErrCalendarNotFound) {
+		utils.WriterError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+func (h *Handler) handleAddDates(w http.ResponseWriter, r *http.Request) {
+	var payload types.AddCalendarDatesPayload
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		error := err.(validator.ValidationErrors)
+		utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %s", error))
+		return
+	}
+
+	vars := mux.Vars(r)
+	id, _ := strconv.Atoi(vars["id"])
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	h.addDates(w, id, payload.Dates, userId)
+}
+
+func (h *Handler) handleRemoveDate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, _ := strconv.Atoi(vars["id"])
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	date, err := time.Parse("2006-01-02", vars["date"])
+
+	if err != nil {
+		utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", vars["date"]))
+		return
+	}
+
+	err = h.store.RemoveDate(id, date, userId)
+
+	if errors.Is(err, ErrDateNotFound) {
+		utils.WriterError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+// handleImportCalendar cria um calendário a partir de um arquivo iCalendar
+// enviado no corpo da requisição. O nome vem do parâmetro dsCalendar.
+func (h *Handler) handleImportCalendar(w http.ResponseWriter, r *http.Request) {
+	dsCalendar := r.URL.Query().Get("dsCalendar")
+
+	if dsCalendar == "" {
+		utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("missing dsCalendar query parameter"))
+		return
+	}
+
+	dates, ok := parseICalBody(w, r)
+	if !ok {
+		return
+	}
+
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	calendar, err := h.store.CreateCalendar(types.Calendar{
+		DsCalendar: dsCalendar,
+		Dates:      dates,
+	}, userId)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, calendar)
+}
+
+// handleImportDates inclui as datas de um arquivo iCalendar em um calendário existente.
+func (h *Handler) handleImportDates(w http.ResponseWriter, r *http.Request) {
+	dates, ok := parseICalBody(w, r)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	id, _ := strconv.Atoi(vars["id"])
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	h.addDates(w, id, dates, userId)
+}
+
+func (h *Handler) addDates(w http.ResponseWriter, id int, dates []types.CalendarDate, userId int) {
+	err := h.store.AddDates(id, dates, userId)
+
+	if errors.Is(err, ErrCalendarNotFound) {
+		utils.WriterError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	calendar, err := h.store.GetCalendarById(id, userId)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, calendar)
+}
+
+func parseICalBody(w http.ResponseWriter, r *http.Request) ([]types.CalendarDate, bool) {
+	if r.Body == nil {
+		utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("missing request body"))
+		return nil, false
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxICalSize)
+
+	dates, err := ParseICal(body, time.Now().AddDate(importYears, 0, 0))
+
+	if err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return nil, false
+	}
+
+	return dates, true
+}
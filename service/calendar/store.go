@@ -0,0 +1,200 @@
+package calendar
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+var (
+	ErrCalendarNotFound = fmt.Errorf("calendar not found")
+	ErrDateNotFound     = fmt.Errorf("date not found in calendar")
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) GetCalendars(userId int) ([]types.Calendar, error) {
+	rows, err := s.db.Query("SELECT id_calendar, ds_calendar, id_user FROM calendar WHERE id_user = $1 ORDER BY id_calendar ASC", userId)
+	if err != nil {
+		return nil, err
+	}
+
+	calendars := make([]types.Calendar, 0)
+
+	for rows.Next() {
+		c := types.Calendar{}
+		if err := rows.Scan(&c.IdCalendar, &c.DsCalendar, &c.IdUser); err != nil {
+			return nil, err
+		}
+		calendars = append(calendars, c)
+	}
+	rows.Close()
+
+	for i := range calendars {
+		calendars[i].Dates, err = s.getDates(calendars[i].IdCalendar)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return calendars, nil
+}
+
+func (s *Store) GetCalendarById(id int, userId int) (*types.Calendar, error) {
+	c := &types.Calendar{}
+
+	err := s.db.QueryRow("SELECT id_calendar, ds_calendar, id_user FROM calendar WHERE id_calendar = $1 AND id_user = $2", id, userId).
+		Scan(&c.IdCalendar, &c.DsCalendar, &c.IdUser)
+	if err == sql.ErrNoRows {
+		return nil, ErrCalendarNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c.Dates, err = s.getDates(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (s *Store) CreateCalendar(c types.Calendar, userId int) (*types.Calendar, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	var id int
+
+	err = tx.QueryRow("INSERT INTO calendar (ds_calendar, id_user) VALUES ($1, $2) RETURNING id_calendar", c.DsCalendar, userId).Scan(&id)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := insertDates(tx, id, c.Dates); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetCalendarById(id, userId)
+}
+
+func (s *Store) DeleteCalendar(id int, userId int) error {
+	result, err := s.db.Exec("DELETE FROM calendar WHERE id_calendar = $1 AND id_user = $2", id, userId)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrCalendarNotFound
+	}
+
+	return nil
+}
+
+// AddDates inclui datas no calendário. Datas já existentes têm só a
+// descrição atualizada.
+func (s *Store) AddDates(id int, dates []types.CalendarDate, userId int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	var exists bool
+
+	err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM calendar WHERE id_calendar = $1 AND id_user = $2)", id, userId).Scan(&exists)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if !exists {
+		tx.Rollback()
+		return ErrCalendarNotFound
+	}
+
+	if err := insertDates(tx, id, dates); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Store) RemoveDate(id int, date time.Time, userId int) error {
+	result, err := s.db.Exec(`
+		DELETE FROM calendar_date cd
+		USING calendar c
+		WHERE c.id_calendar = cd.id_calendar
+		AND cd.id_calendar = $1 AND cd.dt_date = $2 AND c.id_user = $3`, id, date, userId)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrDateNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) getDates(id int) ([]types.CalendarDate, error) {
+	rows, err := s.db.Query("SELECT id_calendar_date, id_calendar, dt_date, ds_date FROM calendar_date WHERE id_calendar = $1 ORDER BY dt_date ASC", id)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	dates := make([]types.CalendarDate, 0)
+
+	for rows.Next() {
+		d := types.CalendarDate{}
+		if err := rows.Scan(&d.IdCalendarDate, &d.IdCalendar, &d.DtDate, &d.DsDate); err != nil {
+			return nil, err
+		}
+		dates = append(dates, d)
+	}
+
+	return dates, nil
+}
+
+func insertDates(tx *sql.Tx, id int, dates []types.CalendarDate) error {
+	for _, d := range dates {
+		_, err := tx.Exec(`
+			INSERT INTO calendar_date (id_calendar, dt_date, ds_date) VALUES ($1, $2, $3)
+			ON CONFLICT (id_calendar, dt_date) DO UPDATE SET ds_date = EXCLUDED.ds_date`,
+			id, d.DtDate, d.DsDate)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,285 @@
+package currency
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+var ErrMissingRate = fmt.Errorf("missing exchange rate")
+
+// Converter converte valores para a moeda do usuário com as taxas que ele
+// cadastrou. Sem a taxa direta, usa o inverso da taxa no sentido contrário.
+type Converter struct {
+	home  string
+	rates map[string][]types.ExchangeRate
+}
+
+func NewConverter(home string, rates []types.ExchangeRate) *Converter {
+	if home == "" {
+		home = types.DefaultCurrency
+	}
+
+	byPair := make(map[string][]types.ExchangeRate)
+	for _, rate := range rates {
+		key := pair(rate.CdFrom, rate.CdTo)
+		byPair[key] = append(byPair[key], rate)
+	}
+
+	for _, pairRates := range byPair {
+		sort.Slice(pairRates, func(i, j int) bool {
+			return pairRates[i].DtRate.Before(pairRates[j].DtRate)
+		})
+	}
+
+	return &Converter{home: home, rates: byPair}
+}
+
+// Load monta o conversor com a moeda e as taxas do usuário.
+func Load(rateStore types.ExchangeRateStore, userStore types.UserStore, userId int) (*Converter, error) {
+	user, err := userStore.GetUserByID(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	rates, err := rateStore.GetRates(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewConverter(user.CdCurrency, rates), nil
+}
+
+func pair(from string, to string) string {
+	return from + "/" + to
+}
+
+func (c *Converter) Home() string {
+	return c.home
+}
+
+// Rate retorna a cotação de from na moeda do usuário no dia informado.
+func (c *Converter) Rate(from string, date time.Time) (types.Rate, error) {
+	if from == "" {
+		from = types.DefaultCurrency
+	}
+
+	if from == c.home {
+		return types.OneRate, nil
+	}
+
+	if rate, ok := rateAt(c.rates[pair(from, c.home)], date); ok {
+		return rate, nil
+	}
+
+	if rate, ok := rateAt(c.rates[pair(c.home, from)], date); ok {
+		return rate.Inverse(), nil
+	}
+
+	return 0, fmt.Errorf("%w: %s to %s on %s", ErrMissingRate, from, c.home, date.Format("2006-01-02"))
+}
+
+// rateAt usa a última taxa vigente no dia. Dias anteriores à primeira taxa
+// usam a mais antiga.
+func rateAt(rates []types.ExchangeRate, date time.Time) (types.Rate, bool) {
+	if len(rates) == 0 {
+		return 0, false
+	}
+
+	rate := rates[0].VlRate
+	for _, r := range rates {
+		if r.DtRate.After(date) {
+			break
+		}
+		rate = r.VlRate
+	}
+
+	return rate, true
+}
+
+func (c *Converter) Convert(amount types.Money, from string, date time.Time) (types.Money, error) {
+	rate, err := c.Rate(from, date)
+	if err != nil {
+		return 0, err
+	}
+
+	return amount.Convert(rate), nil
+}
+
+// convertTotal converte a soma dos valores de uma vez e a reparte na
+// proporção dos originais, para que as partes convertidas somem exatamente
+// o total convertido.
+func convertTotal(amounts []types.Money, rate types.Rate) []types.Money {
+	weights := make([]int64, len(amounts))
+	var total types.Money
+
+	for i, amount := range amounts {
+		weights[i] = amount.Cents()
+		total += amount
+	}
+
+	return total.Convert(rate).AllocateByWeights(weights)
+}
+
+// convertPaid converte o valor pago de um pagamento cujo valor devido já
+// foi convertido. O pago segue a proporção do original, então um pagamento
+// quitado continua quitado, e o valor em aberto é a diferença.
+func convertPaid(vlPayment types.Money, converted types.Money, vlPaid types.Money, rate types.Rate) (types.Money, types.Money) {
+	paid := converted
+
+	if vlPaid < vlPayment {
+		paid = converted.AllocateByWeights([]int64{vlPaid.Cents(), (vlPayment - vlPaid).Cents()})[0]
+	} else {
+		// O que passou do devido é convertido à parte
+		paid += (vlPaid - vlPayment).Convert(rate)
+	}
+
+	return paid, max(converted-paid, 0)
+}
+
+// convertSummary refaz o resumo a partir dos pagamentos já convertidos.
+// Sem os pagamentos carregados, converte o próprio resumo como se fosse um
+// único pagamento.
+func convertSummary(summary types.PaymentSummary, payments []types.Money, paids []types.Money, outstandings []types.Money, rate types.Rate) *types.PaymentSummary {
+	if len(payments) == 0 {
+		summary.VlPaid, summary.VlOutstanding = convertPaid(summary.VlTotal, summary.VlTotal.Convert(rate), summary.VlPaid, rate)
+		summary.VlTotal = summary.VlTotal.Convert(rate)
+
+		return &summary
+	}
+
+	summary.VlTotal, summary.VlPaid, summary.VlOutstanding = 0, 0, 0
+
+	for i := range payments {
+		summary.VlTotal += payments[i]
+		summary.VlPaid += paids[i]
+		summary.VlOutstanding += outstandings[i]
+	}
+
+	return &summary
+}
+
+// Bill retorna uma cópia do bill com o valor, os pagamentos e o resumo na
+// moeda do usuário, convertidos pela taxa da data do bill. Os pagamentos
+// são repartidos do total convertido, então continuam somando o bill.
+func (c *Converter) Bill(bill types.Bill) (types.Bill, error) {
+	rate, err := c.Rate(bill.CdCurrency, bill.CreatedAt)
+	if err != nil {
+		return bill, err
+	}
+
+	converted := bill
+	converted.CdCurrency = c.home
+	converted.VlBill = bill.VlBill.Convert(rate)
+
+	amounts := make([]types.Money, len(bill.Payments))
+	for i, p := range bill.Payments {
+		amounts[i] = p.VlPayment
+	}
+
+	payments := convertTotal(amounts, rate)
+	paids := make([]types.Money, len(payments))
+	outstandings := make([]types.Money, len(payments))
+
+	converted.Payments = make([]types.BillPayment, len(bill.Payments))
+	for i, p := range bill.Payments {
+		paids[i], outstandings[i] = convertPaid(p.VlPayment, payments[i], p.VlPaid, rate)
+
+		p.VlPayment = payments[i]
+		p.VlPaid = paids[i]
+		p.VlOutstanding = outstandings[i]
+		converted.Payments[i] = p
+	}
+
+	if bill.Summary != nil {
+		converted.Summary = convertSummary(*bill.Summary, payments, paids, outstandings, rate)
+	}
+
+	return converted, nil
+}
+
+func (c *Converter) Bills(bills []types.Bill) ([]types.Bill, error) {
+	converted := make([]types.Bill, len(bills))
+
+	for i, bill := range bills {
+		b, err := c.Bill(bill)
+		if err != nil {
+			return nil, err
+		}
+		converted[i] = b
+	}
+
+	return converted, nil
+}
+
+// Ride retorna uma cópia do ride com os pagamentos na moeda do usuário. Rides
+// são sempre em reais e usam a taxa do início do ride.
+func (c *Converter) Ride(ride types.Ride) (types.Ride, error) {
+	rate, err := c.Rate(types.DefaultCurrency, ride.DtInit)
+	if err != nil {
+		return ride, err
+	}
+
+	converted := ride
+	converted.VlRide = ride.VlRide.Convert(rate)
+
+	amounts := make([]types.Money, len(ride.Payments))
+	for i, p := range ride.Payments {
+		amounts[i] = p.VlPayment
+	}
+
+	payments := convertTotal(amounts, rate)
+	paids := make([]types.Money, len(payments))
+	outstandings := make([]types.Money, len(payments))
+
+	converted.Payments = make([]types.RidePayment, len(ride.Payments))
+	for i, p := range ride.Payments {
+		paids[i], outstandings[i] = convertPaid(p.VlPayment, payments[i], p.VlPaid, rate)
+
+		p.VlPayment = payments[i]
+		p.VlPaid = paids[i]
+		p.VlOutstanding = outstandings[i]
+		p.VlDriving = p.VlDriving.Convert(rate)
+		converted.Payments[i] = p
+	}
+
+	if ride.Summary != nil {
+		converted.Summary = convertSummary(*ride.Summary, payments, paids, outstandings, rate)
+	}
+
+	return converted, nil
+}
+
+func (c *Converter) Rides(rides []types.Ride) ([]types.Ride, error) {
+	converted := make([]types.Ride, len(rides))
+
+	for i, ride := range rides {
+		r, err := c.Ride(ride)
+		if err != nil {
+			return nil, err
+		}
+		converted[i] = r
+	}
+
+	return converted, nil
+}
+
+// Debts converte as dívidas para a moeda do usuário antes de calcular saldos.
+func (c *Converter) Debts(debts []types.Debt) ([]types.Debt, error) {
+	converted := make([]types.Debt, len(debts))
+
+	for i, debt := range debts {
+		vlDebt, err := c.Convert(debt.VlDebt, debt.CdCurrency, debt.DtDebt)
+		if err != nil {
+			return nil, err
+		}
+
+		debt.VlDebt = vlDebt
+		debt.CdCurrency = c.home
+		converted[i] = debt
+	}
+
+	return converted, nil
+}
@@ -0,0 +1,112 @@
+package currency
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+var ErrInvalidFile = fmt.Errorf("invalid exchange rate file")
+
+// Nomes aceitos no cabeçalho para cada coluna
+var rateColumns = map[string][]string{
+	"date": {"date", "data", "dtrate"},
+	"from": {"from", "de", "cdfrom"},
+	"to":   {"to", "para", "cdto"},
+	"rate": {"rate", "taxa", "cotacao", "vlrate"},
+}
+
+// ParseRates lê taxas de um CSV com as colunas date, from, to e rate. Com
+// ponto e vírgula como separador, a taxa usa vírgula decimal (5,4321).
+func ParseRates(content string) ([]types.ExchangeRate, error) {
+	header, _, _ := strings.Cut(content, "\n")
+	semicolon := strings.Count(header, ";") > strings.Count(header, ",")
+
+	reader := csv.NewReader(strings.NewReader(content))
+	reader.TrimLeadingSpace = true
+	if semicolon {
+		reader.Comma = ';'
+	}
+
+	names, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing header", ErrInvalidFile)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range names {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		for column, aliases := range rateColumns {
+			for _, alias := range aliases {
+				if name == alias {
+					columns[column] = i
+				}
+			}
+		}
+	}
+
+	for column := range rateColumns {
+		if _, ok := columns[column]; !ok {
+			return nil, fmt.Errorf("%w: missing column %s", ErrInvalidFile, column)
+		}
+	}
+
+	rates := make([]types.ExchangeRate, 0)
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+
+		value := func(column string) string {
+			if columns[column] >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[columns[column]])
+		}
+
+		if value("date") == "" && value("rate") == "" {
+			continue
+		}
+
+		date, err := parseDate(value("date"))
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d has invalid date %q", ErrInvalidFile, line, value("date"))
+		}
+
+		raw := value("rate")
+		if semicolon {
+			raw = strings.ReplaceAll(strings.ReplaceAll(raw, ".", ""), ",", ".")
+		}
+
+		rate, err := types.ParseRate(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidFile, line, err)
+		}
+
+		rates = append(rates, types.ExchangeRate{
+			CdFrom: strings.ToUpper(value("from")),
+			CdTo:   strings.ToUpper(value("to")),
+			DtRate: date,
+			VlRate: rate,
+		})
+	}
+
+	return rates, nil
+}
+
+func parseDate(value string) (time.Time, error) {
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return date, nil
+	}
+
+	return time.Parse("02/01/2006", value)
+}
@@ -0,0 +1,144 @@
+package currency
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+func day(d int) time.Time {
+	return time.Date(2025, 3, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestConverter(t *testing.T) {
+	converter := NewConverter("BRL", []types.ExchangeRate{
+		{CdFrom: "USD", CdTo: "BRL", DtRate: day(10), VlRate: 5200000},
+		{CdFrom: "USD", CdTo: "BRL", DtRate: day(1), VlRate: 5000000},
+		{CdFrom: "BRL", CdTo: "EUR", DtRate: day(1), VlRate: 160000},
+	})
+
+	t.Run("should use the latest rate in effect on the date", func(t *testing.T) {
+		for _, tc := range []struct {
+			date     time.Time
+			expected types.Money
+		}{
+			{day(5), 5000},
+			{day(10).Add(12 * time.Hour), 5200},
+			{time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), 5000},
+		} {
+			converted, err := converter.Convert(1000, "USD", tc.date)
+			if err != nil || converted != tc.expected {
+				t.Errorf("expected %s on %s, got %s (%v)", tc.expected, tc.date.Format("2006-01-02"), converted, err)
+			}
+		}
+	})
+
+	t.Run("should invert the rate of the opposite pair", func(t *testing.T) {
+		converted, err := converter.Convert(1000, "EUR", day(5))
+		if err != nil || converted != 6250 {
+			t.Errorf("expected 62.50, got %s (%v)", converted, err)
+		}
+	})
+
+	t.Run("should keep the home currency and fail without a rate", func(t *testing.T) {
+		if converted, _ := converter.Convert(1000, "", day(5)); converted != 1000 {
+			t.Errorf("expected bills without currency to stay in BRL, got %s", converted)
+		}
+
+		if _, err := converter.Convert(1000, "ARS", day(5)); !errors.Is(err, ErrMissingRate) {
+			t.Errorf("expected %v, got %v", ErrMissingRate, err)
+		}
+	})
+
+	t.Run("should convert a copy of the bill and keep the original", func(t *testing.T) {
+		bill := types.Bill{CdCurrency: "USD", VlBill: 2000, CreatedAt: day(5), Payments: []types.BillPayment{
+			{VlPayment: 1000, VlPaid: 400, VlOutstanding: 600},
+			{VlPayment: 1000},
+		}}
+
+		converted, err := converter.Bill(bill)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if converted.CdCurrency != "BRL" || converted.VlBill != 10000 || converted.Payments[0].VlOutstanding != 3000 {
+			t.Errorf("unexpected converted bill %+v", converted)
+		}
+
+		if bill.CdCurrency != "USD" || bill.Payments[0].VlOutstanding != 600 {
+			t.Errorf("expected the original bill to be unchanged, got %+v", bill)
+		}
+	})
+
+	t.Run("should keep a three way split adding up to the converted total", func(t *testing.T) {
+		// A 0,5 cada parte de 33.33 arredondaria para 16.67, somando 50.01
+		half := NewConverter("BRL", []types.ExchangeRate{{CdFrom: "USD", CdTo: "BRL", DtRate: day(1), VlRate: 500000}})
+
+		bill := types.Bill{CdCurrency: "USD", VlBill: 10000, CreatedAt: day(5), Payments: []types.BillPayment{
+			{VlPayment: 3334, VlPaid: 3334},
+			{VlPayment: 3333, VlPaid: 1000, VlOutstanding: 2333},
+			{VlPayment: 3333, VlOutstanding: 3333},
+		}, Summary: &types.PaymentSummary{VlTotal: 10000, VlPaid: 4334, VlOutstanding: 5666, QtPayments: 3, QtPayed: 1}}
+
+		converted, err := half.Bill(bill)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var total, paid, outstanding types.Money
+		for _, p := range converted.Payments {
+			if p.VlPaid+p.VlOutstanding != p.VlPayment {
+				t.Errorf("expected paid %s and outstanding %s to add up to %s", p.VlPaid, p.VlOutstanding, p.VlPayment)
+			}
+
+			total += p.VlPayment
+			paid += p.VlPaid
+			outstanding += p.VlOutstanding
+		}
+
+		if total != converted.VlBill || converted.VlBill != 5000 {
+			t.Errorf("expected payments to add up to the converted bill 50.00, got %s of %s", total, converted.VlBill)
+		}
+
+		if converted.Payments[0].VlOutstanding != 0 {
+			t.Errorf("expected the paid share to stay paid, got %s outstanding", converted.Payments[0].VlOutstanding)
+		}
+
+		summary := converted.Summary
+		if summary.VlTotal != total || summary.VlPaid != paid || summary.VlOutstanding != outstanding || summary.QtPayed != 1 {
+			t.Errorf("expected the summary to match the converted payments, got %+v", summary)
+		}
+	})
+}
+
+func TestParseRates(t *testing.T) {
+	t.Run("should read comma separated files", func(t *testing.T) {
+		rates, err := ParseRates("date,from,to,rate\n2025-03-01,usd,brl,5.1234\n\n2025-03-02,USD,BRL,5.2\n")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(rates) != 2 || rates[0].CdFrom != "USD" || rates[0].CdTo != "BRL" || rates[0].VlRate != 5123400 || !rates[0].DtRate.Equal(day(1)) {
+			t.Errorf("unexpected rates %+v", rates)
+		}
+	})
+
+	t.Run("should read semicolon separated files with decimal commas", func(t *testing.T) {
+		rates, err := ParseRates("Data;De;Para;Taxa\n01/03/2025;EUR;BRL;6,0512\n")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(rates) != 1 || rates[0].VlRate != 6051200 || !rates[0].DtRate.Equal(day(1)) {
+			t.Errorf("unexpected rates %+v", rates)
+		}
+	})
+
+	t.Run("should reject files without the required columns", func(t *testing.T) {
+		if _, err := ParseRates("date,from,rate\n2025-03-01,USD,5\n"); !errors.Is(err, ErrInvalidFile) {
+			t.Errorf("expected %v, got %v", ErrInvalidFile, err)
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package currency
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gfmanica/splitz-backend/service/auth"
+	"github.com/gfmanica/splitz-backend/types"
+	"github.com/gfmanica/splitz-backend/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store     types.ExchangeRateStore
+	userStore types.UserStore
+}
+
+func NewHandler(store types.ExchangeRateStore, userStore types.UserStore) *Handler {
+	return &Handler{
+		store:     store,
+		userStore: userStore,
+	}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/currency/rate", auth.WithJWTAuth(h.handleGetRates, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/currency/rate", auth.WithJWTAuth(h.handleSaveRates, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/currency/rate/import", auth.WithJWTAuth(h.handleImportRates, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/currency/rate/{id}", auth.WithJWTAuth(h.handleDeleteRate, h.userStore)).Methods(http.MethodDelete)
+}
+
+func (h *Handler) handleGetRates(w http.ResponseWriter, r *http.Request) {
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	rates, err := h.store.GetRates(userId)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, rates)
+}
+
+func (h *Handler) handleSaveRates(w http.ResponseWriter, r *http.Request) {
+	var payload types.SaveRatesPayload
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	h.saveRates(w, r, payload)
+}
+
+// handleImportRates carrega as taxas de um CSV (date, from, to, rate).
+func (h *Handler) handleImportRates(w http.ResponseWriter, r *http.Request) {
+	var payload types.ImportRatesPayload
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		error := err.(validator.ValidationErrors)
+		utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %s", error))
+		return
+	}
+
+	rates, err := ParseRates(payload.DsContent)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	h.saveRates(w, r, types.SaveRatesPayload{Rates: rates})
+}
+
+func (h *Handler) saveRates(w http.ResponseWriter, r *http.Request, payload types.SaveRatesPayload) {
+	if err := utils.Validate.Struct(payload); err != nil {
+		error := err.(validator.ValidationErrors)
+		utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %s", error))
+		return
+	}
+
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	if err := h.store.SaveRates(payload.Rates, userId); err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	rates, err := h.store.GetRates(userId)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, rates)
+}
+
+func (h *Handler) handleDeleteRate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, _ := strconv.Atoi(vars["id"])
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	err := h.store.DeleteRate(id, userId)
+
+	if errors.Is(err, ErrRateNotFound) {
+		utils.WriterError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
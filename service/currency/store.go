@@ -0,0 +1,83 @@
+package currency
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+var ErrRateNotFound = fmt.Errorf("exchange rate not found")
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) GetRates(userId int) ([]types.ExchangeRate, error) {
+	rows, err := s.db.Query(`
+		SELECT id_exchange_rate, cd_from, cd_to, dt_rate, vl_rate
+		FROM exchange_rate
+		WHERE id_user = $1
+		ORDER BY cd_from, cd_to, dt_rate`, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	rates := make([]types.ExchangeRate, 0)
+
+	for rows.Next() {
+		rate := types.ExchangeRate{}
+		if err := rows.Scan(&rate.IdExchangeRate, &rate.CdFrom, &rate.CdTo, &rate.DtRate, &rate.VlRate); err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+
+	return rates, nil
+}
+
+// SaveRates grava as taxas em uma transação. Uma taxa do mesmo par e dia
+// substitui a anterior, então o mesmo CSV pode ser carregado de novo.
+func (s *Store) SaveRates(rates []types.ExchangeRate, userId int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, rate := range rates {
+		_, err := tx.Exec(`
+			INSERT INTO exchange_rate (id_user, cd_from, cd_to, dt_rate, vl_rate) VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (id_user, cd_from, cd_to, dt_rate) DO UPDATE SET vl_rate = EXCLUDED.vl_rate`,
+			userId, rate.CdFrom, rate.CdTo, rate.DtRate, rate.VlRate)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *Store) DeleteRate(id int, userId int) error {
+	result, err := s.db.Exec("DELETE FROM exchange_rate WHERE id_exchange_rate = $1 AND id_user = $2", id, userId)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrRateNotFound
+	}
+
+	return nil
+}
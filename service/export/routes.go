@@ -0,0 +1,249 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gfmanica/splitz-backend/service/auth"
+	"github.com/gfmanica/splitz-backend/service/bill"
+	"github.com/gfmanica/splitz-backend/service/currency"
+	"github.com/gfmanica/splitz-backend/service/listing"
+	"github.com/gfmanica/splitz-backend/service/ride"
+	"github.com/gfmanica/splitz-backend/types"
+	"github.com/gfmanica/splitz-backend/utils"
+	"github.com/gorilla/mux"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+var contentTypes = map[string]string{
+	FormatCSV:  "text/csv; charset=utf-8",
+	FormatXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+type Handler struct {
+	billStore types.BillStore
+	rideStore types.RideStore
+	rateStore types.ExchangeRateStore
+	userStore types.UserStore
+}
+
+func NewHandler(billStore types.BillStore, rideStore types.RideStore, rateStore types.ExchangeRateStore, userStore types.UserStore) *Handler {
+	return &Handler{
+		billStore: billStore,
+		rideStore: rideStore,
+		rateStore: rateStore,
+		userStore: userStore,
+	}
+}
+
+// As listas aceitam os mesmos filtros de GET /bill e GET /ride; o formato
+// vem do parâmetro format (csv, o padrão, ou xlsx).
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/export/bill", auth.WithJWTAuth(h.handleExportBills, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/export/bill/{id}", auth.WithJWTAuth(h.handleExportBill, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/export/ride", auth.WithJWTAuth(h.handleExportRides, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/export/ride/{id}", auth.WithJWTAuth(h.handleExportRide, h.userStore)).Methods(http.MethodGet)
+}
+
+func (h *Handler) handleExportBills(w http.ResponseWriter, r *http.Request) {
+	format, ok := parseFormat(w, r)
+	if !ok {
+		return
+	}
+
+	query, err := listing.ParseQuery(r.URL.Query(), bill.Sorts)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	query.FgPayments = true
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	bills, err := listing.All(query, func(query types.ListQuery) (*types.Page[types.Bill], error) {
+		return h.billStore.GetBills(userId, query)
+	})
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.writeBills(w, r, format, "bills", bills)
+}
+
+func (h *Handler) handleExportBill(w http.ResponseWriter, r *http.Request) {
+	format, ok := parseFormat(w, r)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	id, _ := strconv.Atoi(vars["id"])
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	b, err := h.billStore.GetBillById(id, userId)
+
+	if errors.Is(err, bill.ErrBillNotFound) {
+		utils.WriterError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.writeBills(w, r, format, fmt.Sprintf("bill-%d", id), []types.Bill{*b})
+}
+
+func (h *Handler) handleExportRides(w http.ResponseWriter, r *http.Request) {
+	format, ok := parseFormat(w, r)
+	if !ok {
+		return
+	}
+
+	query, err := listing.ParseQuery(r.URL.Query(), ride.Sorts)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	list, err := listing.All(query, func(query types.ListQuery) (*types.Page[types.Ride], error) {
+		return h.rideStore.GetRides(userId, query)
+	})
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// A listagem não traz a grade de presenças, então cada ride é carregado inteiro
+	rides := make([]types.Ride, 0, len(list))
+	for _, item := range list {
+		rd, err := h.rideStore.GetRideById(item.IdRide, userId)
+
+		if err != nil {
+			utils.WriterError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		rides = append(rides, *rd)
+	}
+
+	writeRides(w, format, "rides", rides, rideRows(rides))
+}
+
+func (h *Handler) handleExportRide(w http.ResponseWriter, r *http.Request) {
+	format, ok := parseFormat(w, r)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	id, _ := strconv.Atoi(vars["id"])
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	rd, err := h.rideStore.GetRideById(id, userId)
+
+	if errors.Is(err, ride.ErrRideNotFound) {
+		utils.WriterError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeRides(w, format, fmt.Sprintf("ride-%d", id), []types.Ride{*rd}, presenceRows(*rd))
+}
+
+func parseFormat(w http.ResponseWriter, r *http.Request) (string, bool) {
+	format := r.URL.Query().Get("format")
+
+	if format == "" {
+		return FormatCSV, true
+	}
+
+	if _, ok := contentTypes[format]; !ok {
+		utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("invalid format %q, expected csv or xlsx", format))
+		return "", false
+	}
+
+	return format, true
+}
+
+// writeBills exporta os valores originais e os convertidos para a moeda do
+// usuário pela taxa da data de cada bill.
+func (h *Handler) writeBills(w http.ResponseWriter, r *http.Request, format string, name string, bills []types.Bill) {
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	converter, err := currency.Load(h.rateStore, h.userStore, userId)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	converted, err := converter.Bills(bills)
+
+	if errors.Is(err, currency.ErrMissingRate) {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	var buf bytes.Buffer
+
+	if format == FormatXLSX {
+		err = WriteXLSX(&buf, []Sheet{{Name: "Bills", Rows: billRows(bills, converted)}})
+	} else {
+		err = writeCSV(&buf, billRows(bills, converted))
+	}
+
+	writeFile(w, format, name, buf.Bytes(), err)
+}
+
+// O CSV tem uma única tabela, então quem chama escolhe as linhas: a grade de
+// presenças de um ride ou uma linha por participante nas listas. O xlsx traz
+// as duas coisas, com uma aba por ride.
+func writeRides(w http.ResponseWriter, format string, name string, rides []types.Ride, csvRows [][]any) {
+	var buf bytes.Buffer
+	var err error
+
+	if format == FormatXLSX {
+		err = WriteXLSX(&buf, rideSheets(rides))
+	} else {
+		err = writeCSV(&buf, csvRows)
+	}
+
+	writeFile(w, format, name, buf.Bytes(), err)
+}
+
+func writeFile(w http.ResponseWriter, format string, name string, data []byte, err error) {
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentTypes[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
@@ -0,0 +1,116 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+// billRows gera uma linha por pagamento, repetindo os dados do bill. Os
+// valores originais vêm primeiro e os convertidos para a moeda do usuário,
+// na mesma ordem dos bills, no fim da linha.
+func billRows(bills []types.Bill, converted []types.Bill) [][]any {
+	rows := [][]any{{"idBill", "dsBill", "createdAt", "cdCurrency", "vlBill", "tpSplit", "dsPerson", "vlPayment", "vlPaid", "vlOutstanding", "fgPayed", "dtPayment",
+		"cdHomeCurrency", "vlBillConverted", "vlPaymentConverted", "vlPaidConverted", "vlOutstandingConverted"}}
+
+	for i, bill := range bills {
+		home := converted[i]
+
+		for j, p := range bill.Payments {
+			c := home.Payments[j]
+			rows = append(rows, []any{bill.IdBill, bill.DsBill, bill.CreatedAt, bill.CdCurrency, bill.VlBill, bill.TpSplit, p.DsPerson, p.VlPayment, p.VlPaid, p.VlOutstanding, p.FgPayed, p.DtPayment,
+				home.CdCurrency, home.VlBill, c.VlPayment, c.VlPaid, c.VlOutstanding})
+		}
+	}
+
+	return rows
+}
+
+// rideRows gera uma linha por participante, repetindo os dados do ride.
+func rideRows(rides []types.Ride) [][]any {
+	rows := [][]any{{"idRide", "dsRide", "dtInit", "dtFinish", "tpPricing", "dsPerson", "qtRidingDays", "qtDrivingDays", "vlDriving", "vlPayment", "vlPaid", "vlOutstanding", "fgPayed"}}
+
+	for _, ride := range rides {
+		for _, p := range ride.Payments {
+			rows = append(rows, []any{ride.IdRide, ride.DsRide, ride.DtInit, ride.DtFinish, ride.TpPricing, p.DsPerson, p.QtRidingDays, p.QtDrivingDays, p.VlDriving, p.VlPayment, p.VlPaid, p.VlOutstanding, p.FgPayed})
+		}
+	}
+
+	return rows
+}
+
+// presenceRows monta a grade de presenças do ride: uma linha por dia, uma
+// coluna por participante e o motorista do dia, com os totais no fim.
+func presenceRows(ride types.Ride) [][]any {
+	header := []any{"date"}
+	column := make(map[int]int, len(ride.Payments))
+	names := make(map[int]string, len(ride.Payments))
+
+	for i, p := range ride.Payments {
+		header = append(header, p.DsPerson)
+		column[p.IdRidePayment] = i + 1
+		names[p.IdRidePayment] = p.DsPerson
+	}
+	header = append(header, "driver")
+
+	rows := [][]any{header}
+
+	for _, gp := range ride.GroupedPresences {
+		row := make([]any, len(header))
+		row[0] = gp.DtRide
+
+		for _, presence := range gp.Presences {
+			i, ok := column[presence.IdRidePayment]
+			if !ok {
+				continue
+			}
+
+			row[i] = presence.QtPresence
+
+			if presence.FgDriver {
+				row[len(row)-1] = names[presence.IdRidePayment]
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	total := make([]any, len(header))
+	total[0] = "total"
+	for i, p := range ride.Payments {
+		total[i+1] = p.VlPayment
+	}
+
+	return append(rows, total)
+}
+
+func writeCSV(w io.Writer, rows [][]any) error {
+	cw := csv.NewWriter(w)
+
+	for _, row := range rows {
+		record := make([]string, len(row))
+		for i, value := range row {
+			record[i] = formatValue(value)
+		}
+
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+func rideSheets(rides []types.Ride) []Sheet {
+	sheets := []Sheet{{Name: "Rides", Rows: rideRows(rides)}}
+
+	for _, ride := range rides {
+		sheets = append(sheets, Sheet{Name: fmt.Sprintf("%d %s", ride.IdRide, ride.DsRide), Rows: presenceRows(ride)})
+	}
+
+	return sheets
+}
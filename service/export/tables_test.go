@@ -0,0 +1,74 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+func TestPresenceRows(t *testing.T) {
+	monday := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	tuesday := monday.AddDate(0, 0, 1)
+
+	ride := types.Ride{
+		Payments: []types.RidePayment{
+			{IdRidePayment: 1, DsPerson: "Ana", VlPayment: 2000},
+			{IdRidePayment: 2, DsPerson: "Bruno", VlPayment: 1000},
+		},
+		GroupedPresences: []types.GroupedPresence{
+			{DtRide: monday, Presences: []types.Presence{{IdRidePayment: 1, QtPresence: 2}, {IdRidePayment: 2, QtPresence: 1, FgDriver: true}}},
+			{DtRide: tuesday, Presences: []types.Presence{{IdRidePayment: 1, QtPresence: 0}, {IdRidePayment: 2, QtPresence: 2}}},
+		},
+	}
+
+	t.Run("should build a date by person matrix with totals", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		if err := writeCSV(&buf, presenceRows(ride)); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		expected := "date,Ana,Bruno,driver\n" +
+			"2026-03-02,2,1,Bruno\n" +
+			"2026-03-03,0,2,\n" +
+			"total,20.00,10.00,\n"
+
+		if buf.String() != expected {
+			t.Errorf("expected\n%s\ngot\n%s", expected, buf.String())
+		}
+	})
+
+	t.Run("should add one sheet per ride after the summary", func(t *testing.T) {
+		ride.IdRide = 7
+		ride.DsRide = "Trabalho"
+
+		sheets := rideSheets([]types.Ride{ride})
+
+		if len(sheets) != 2 || sheets[1].Name != "7 Trabalho" || len(sheets[0].Rows) != 3 {
+			t.Errorf("unexpected sheets %+v", sheets)
+		}
+	})
+}
+
+func TestBillRows(t *testing.T) {
+	t.Run("should keep the original amounts next to the converted ones", func(t *testing.T) {
+		bill := types.Bill{IdBill: 1, DsBill: "Hotel", CdCurrency: "USD", VlBill: 2000, Payments: []types.BillPayment{{DsPerson: "Ana", VlPayment: 2000, VlOutstanding: 2000}}}
+		converted := bill
+		converted.CdCurrency = "BRL"
+		converted.VlBill = 10000
+		converted.Payments = []types.BillPayment{{DsPerson: "Ana", VlPayment: 10000, VlOutstanding: 10000}}
+
+		rows := billRows([]types.Bill{bill}, []types.Bill{converted})
+
+		if len(rows) != 2 || len(rows[1]) != len(rows[0]) {
+			t.Fatalf("expected a header and one row of the same width, got %v", rows)
+		}
+
+		row := rows[1]
+		if row[3] != "USD" || row[4] != types.Money(2000) || row[12] != "BRL" || row[13] != types.Money(10000) || row[16] != types.Money(10000) {
+			t.Errorf("unexpected row %v", row)
+		}
+	})
+}
@@ -0,0 +1,240 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+// Sheet é uma aba da planilha. As linhas aceitam textos, números, Money,
+// datas e bool; nil gera uma célula vazia.
+type Sheet struct {
+	Name string
+	Rows [][]any
+}
+
+const xmlHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+
+// WriteXLSX gera um arquivo .xlsx mínimo (SpreadsheetML) com as abas
+// informadas. Os textos vão como inline strings, sem tabela compartilhada.
+func WriteXLSX(w io.Writer, sheets []Sheet) error {
+	zw := zip.NewWriter(w)
+
+	names := sheetNames(sheets)
+
+	files := []struct {
+		name    string
+		content func(io.Writer) error
+	}{
+		{"[Content_Types].xml", func(w io.Writer) error { return writeContentTypes(w, len(sheets)) }},
+		{"_rels/.rels", writeRootRels},
+		{"xl/workbook.xml", func(w io.Writer) error { return writeWorkbook(w, names) }},
+		{"xl/_rels/workbook.xml.rels", func(w io.Writer) error { return writeWorkbookRels(w, len(sheets)) }},
+	}
+
+	for i, sheet := range sheets {
+		files = append(files, struct {
+			name    string
+			content func(io.Writer) error
+		}{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), func(w io.Writer) error { return writeSheet(w, sheet.Rows) }})
+	}
+
+	for _, file := range files {
+		fw, err := zw.Create(file.name)
+		if err != nil {
+			return err
+		}
+
+		if err := file.content(fw); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+func writeContentTypes(w io.Writer, count int) error {
+	var b strings.Builder
+
+	b.WriteString(xmlHeader)
+	b.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	b.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	b.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	b.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	for i := 1; i <= count; i++ {
+		fmt.Fprintf(&b, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i)
+	}
+	b.WriteString(`</Types>`)
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+func writeRootRels(w io.Writer) error {
+	_, err := io.WriteString(w, xmlHeader+
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`+
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>`+
+		`</Relationships>`)
+
+	return err
+}
+
+func writeWorkbook(w io.Writer, names []string) error {
+	var b strings.Builder
+
+	b.WriteString(xmlHeader)
+	b.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, name := range names {
+		fmt.Fprintf(&b, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escape(name), i+1, i+1)
+	}
+	b.WriteString(`</sheets></workbook>`)
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+func writeWorkbookRels(w io.Writer, count int) error {
+	var b strings.Builder
+
+	b.WriteString(xmlHeader)
+	b.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := 1; i <= count; i++ {
+		fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i, i)
+	}
+	b.WriteString(`</Relationships>`)
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+func writeSheet(w io.Writer, rows [][]any) error {
+	var b strings.Builder
+
+	b.WriteString(xmlHeader)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	for i, row := range rows {
+		fmt.Fprintf(&b, `<row r="%d">`, i+1)
+
+		for j, value := range row {
+			ref := columnName(j) + strconv.Itoa(i+1)
+
+			switch v := value.(type) {
+			case nil:
+				continue
+			case int:
+				fmt.Fprintf(&b, `<c r="%s"><v>%d</v></c>`, ref, v)
+			case float64:
+				fmt.Fprintf(&b, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(v, 'f', -1, 64))
+			case types.Money:
+				fmt.Fprintf(&b, `<c r="%s"><v>%s</v></c>`, ref, v.String())
+			case bool:
+				flag := 0
+				if v {
+					flag = 1
+				}
+				fmt.Fprintf(&b, `<c r="%s" t="b"><v>%d</v></c>`, ref, flag)
+			default:
+				fmt.Fprintf(&b, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, escape(formatValue(value)))
+			}
+		}
+
+		b.WriteString(`</row>`)
+	}
+
+	b.WriteString(`</sheetData></worksheet>`)
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+// columnName converte o índice da coluna para letras (0 é A, 26 é AA).
+func columnName(index int) string {
+	name := ""
+
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+
+	return name
+}
+
+// sheetNames ajusta os nomes às regras do Excel: até 31 caracteres, sem
+// []:*?/\ e sem repetição.
+func sheetNames(sheets []Sheet) []string {
+	replacer := strings.NewReplacer("[", "(", "]", ")", ":", "-", "*", "-", "?", "", "/", "-", `\`, "-")
+	used := make(map[string]bool)
+	names := make([]string, len(sheets))
+
+	for i, sheet := range sheets {
+		base := strings.TrimSpace(replacer.Replace(sheet.Name))
+		if base == "" {
+			base = fmt.Sprintf("Sheet%d", i+1)
+		}
+
+		name := truncate(base, 31)
+		for n := 2; used[strings.ToLower(name)]; n++ {
+			suffix := fmt.Sprintf(" (%d)", n)
+			name = truncate(base, 31-len(suffix)) + suffix
+		}
+
+		used[strings.ToLower(name)] = true
+		names[i] = name
+	}
+
+	return names
+}
+
+func truncate(s string, size int) string {
+	runes := []rune(s)
+	if len(runes) > size {
+		return string(runes[:size])
+	}
+
+	return s
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+
+	return b.String()
+}
+
+// formatValue é a representação em texto usada no CSV e nas células de texto.
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case types.Money:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format("2006-01-02")
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		return v.Format("2006-01-02")
+	default:
+		return fmt.Sprint(v)
+	}
+}
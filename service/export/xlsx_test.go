@@ -0,0 +1,86 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+func readZip(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("expected a valid zip, got %v", err)
+	}
+
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(content)
+	}
+
+	return files
+}
+
+func TestWriteXLSX(t *testing.T) {
+	t.Run("should write the workbook parts and typed cells", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		err := WriteXLSX(&buf, []Sheet{
+			{Name: "Bills", Rows: [][]any{{"dsBill", "vlBill"}, {"Mercado & cia", types.Money(1050)}}},
+			{Name: "Rides", Rows: [][]any{{time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), 2, nil, true}}},
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		files := readZip(t, buf.Bytes())
+
+		for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml", "xl/worksheets/sheet2.xml"} {
+			if _, ok := files[name]; !ok {
+				t.Errorf("expected %s in the workbook", name)
+			}
+		}
+
+		sheet1 := files["xl/worksheets/sheet1.xml"]
+		if !strings.Contains(sheet1, `<c r="B2"><v>10.50</v></c>`) || !strings.Contains(sheet1, "Mercado &amp; cia") {
+			t.Errorf("unexpected first sheet %s", sheet1)
+		}
+
+		sheet2 := files["xl/worksheets/sheet2.xml"]
+		if !strings.Contains(sheet2, "2026-03-02") || !strings.Contains(sheet2, `<c r="B1"><v>2</v></c>`) || !strings.Contains(sheet2, `<c r="D1" t="b"><v>1</v></c>`) {
+			t.Errorf("unexpected second sheet %s", sheet2)
+		}
+	})
+
+	t.Run("should make sheet names valid and unique", func(t *testing.T) {
+		names := sheetNames([]Sheet{
+			{Name: "Carona: trabalho/faculdade [2026] com um nome bem longo"},
+			{Name: "Carona: trabalho/faculdade [2026] com um nome bem longo"},
+			{Name: ""},
+		})
+
+		if names[0] != "Carona- trabalho-faculdade (202" || len([]rune(names[1])) > 31 || names[1] == names[0] || names[2] != "Sheet3" {
+			t.Errorf("unexpected names %q", names)
+		}
+	})
+
+	t.Run("should name columns like a spreadsheet", func(t *testing.T) {
+		for index, expected := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+			if name := columnName(index); name != expected {
+				t.Errorf("expected %s for %d, got %s", expected, index, name)
+			}
+		}
+	})
+}
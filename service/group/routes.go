@@ -0,0 +1,217 @@
+package group
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gfmanica/splitz-backend/service/auth"
+	"github.com/gfmanica/splitz-backend/types"
+	"github.com/gfmanica/splitz-backend/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store     types.GroupStore
+	userStore types.UserStore
+}
+
+func NewHandler(store types.GroupStore, userStore types.UserStore) *Handler {
+	return &Handler{
+		store:     store,
+		userStore: userStore,
+	}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/group", auth.WithJWTAuth(h.handleGetGroups, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/group", auth.WithJWTAuth(h.handleCreateGroup, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/group/{id}", auth.WithJWTAuth(h.handleGetGroup, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/group/{id}", auth.WithJWTAuth(h.handleDeleteGroup, h.userStore)).Methods(http.MethodDelete)
+	router.HandleFunc("/group/{id}/member", auth.WithJWTAuth(h.handleInviteMember, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/group/{id}/member/{idUser}", auth.WithJWTAuth(h.handleRemoveMember, h.userStore)).Methods(http.MethodDelete)
+	router.HandleFunc("/group/{id}/accept", auth.WithJWTAuth(h.handleAcceptInvitation, h.userStore)).Methods(http.MethodPost)
+}
+
+func (h *Handler) handleGetGroups(w http.ResponseWriter, r *http.Request) {
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	groups, err := h.store.GetGroups(userId)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, groups)
+}
+
+func (h *Handler) handleGetGroup(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, _ := strconv.Atoi(vars["id"])
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	group, err := h.store.GetGroupById(id, userId)
+
+	if errors.Is(err, ErrGroupNotFound) {
+		utils.WriterError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, group)
+}
+
+func (h *Handler) handleCreateGroup(w http.ResponseWriter, r *http.Request) {
+	var payload types.CreateGroupPayload
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		error := err.(validator.ValidationErrors)
+		utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %s", error))
+		return
+	}
+
+	userId := auth.GetUserIDFromContext(r.Context())
+	group, err := h.store.CreateGroup(types.Group{
+		DsGroup: payload.DsGroup,
+	}, userId)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, group)
+}
+
+func (h *Handler) handleDeleteGroup(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, _ := strconv.Atoi(vars["id"])
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	err := h.store.DeleteGroup(id, userId)
+
+	if errors.Is(err, ErrGroupNotFound) {
+		utils.WriterError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+func (h *Handler) handleInviteMember(w http.ResponseWriter, r *http.Request) {
+	var payload types.InviteGroupMemberPayload
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		error := err.(validator.ValidationErrors)
+		utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %s", error))
+		return
+	}
+
+	vars := mux.Vars(r)
+	id, _ := strconv.Atoi(vars["id"])
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	// Só é possível convidar quem já tem conta
+	member, err := h.userStore.GetUserByEmail(payload.Email)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusNotFound, fmt.Errorf("user with email %s not found", payload.Email))
+		return
+	}
+
+	err = h.store.InviteMember(id, userId, member.ID)
+
+	if errors.Is(err, ErrGroupNotFound) {
+		utils.WriterError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if errors.Is(err, ErrMemberAlreadyInGroup) {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	group, err := h.store.GetGroupById(id, userId)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, group)
+}
+
+func (h *Handler) handleAcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, _ := strconv.Atoi(vars["id"])
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	err := h.store.AcceptInvitation(id, userId)
+
+	if errors.Is(err, ErrInvitationNotFound) {
+		utils.WriterError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	group, err := h.store.GetGroupById(id, userId)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, group)
+}
+
+func (h *Handler) handleRemoveMember(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, _ := strconv.Atoi(vars["id"])
+	memberUserId, _ := strconv.Atoi(vars["idUser"])
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	err := h.store.RemoveMember(id, userId, memberUserId)
+
+	if errors.Is(err, ErrGroupNotFound) {
+		utils.WriterError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
@@ -0,0 +1,353 @@
+package group
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gfmanica/splitz-backend/service/auth"
+	"github.com/gfmanica/splitz-backend/types"
+	"github.com/gorilla/mux"
+)
+
+type mockGroupStore struct {
+	groups map[int]types.Group
+}
+
+// newMockGroupStore cria o grupo 1 do usuário 1, com o usuário 2 como membro
+// e o usuário 3 convidado.
+func newMockGroupStore() *mockGroupStore {
+	return &mockGroupStore{
+		groups: map[int]types.Group{
+			1: {IdGroup: 1, DsGroup: "Casa", IdUser: 1, Members: []types.GroupMember{
+				{IdGroupMember: 1, IdGroup: 1, IdUser: 1, Name: "Ana", Email: "ana@email.com", FgAccepted: true},
+				{IdGroupMember: 2, IdGroup: 1, IdUser: 2, Name: "Bruno", Email: "bruno@email.com", FgAccepted: true},
+				{IdGroupMember: 3, IdGroup: 1, IdUser: 3, Name: "Carla", Email: "carla@email.com", FgAccepted: false},
+			}},
+		},
+	}
+}
+
+func (m *mockGroupStore) member(id int, userId int) (int, bool) {
+	for i, member := range m.groups[id].Members {
+		if member.IdUser == userId {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+func (m *mockGroupStore) GetGroups(userId int) ([]types.Group, error) {
+	groups := make([]types.Group, 0)
+
+	for id := range m.groups {
+		if group, err := m.GetGroupById(id, userId); err == nil {
+			groups = append(groups, *group)
+		}
+	}
+
+	return groups, nil
+}
+
+func (m *mockGroupStore) GetGroupById(id int, userId int) (*types.Group, error) {
+	group, ok := m.groups[id]
+
+	if _, member := m.member(id, userId); !ok || !member {
+		return nil, ErrGroupNotFound
+	}
+
+	group.Members = visibleMembers(group.Members, group.IdUser, userId)
+
+	return &group, nil
+}
+
+func (m *mockGroupStore) CreateGroup(g types.Group, userId int) (*types.Group, error) {
+	g.IdGroup = len(m.groups) + 1
+	g.IdUser = userId
+	g.Members = []types.GroupMember{{IdGroup: g.IdGroup, IdUser: userId, FgAccepted: true}}
+	m.groups[g.IdGroup] = g
+
+	return &g, nil
+}
+
+func (m *mockGroupStore) DeleteGroup(id int, userId int) error {
+	if group, ok := m.groups[id]; !ok || group.IdUser != userId {
+		return ErrGroupNotFound
+	}
+
+	delete(m.groups, id)
+
+	return nil
+}
+
+func (m *mockGroupStore) InviteMember(id int, userId int, memberUserId int) error {
+	group, ok := m.groups[id]
+
+	if !ok || group.IdUser != userId {
+		return ErrGroupNotFound
+	}
+
+	if _, member := m.member(id, memberUserId); member {
+		return ErrMemberAlreadyInGroup
+	}
+
+	group.Members = append(group.Members, types.GroupMember{IdGroup: id, IdUser: memberUserId})
+	m.groups[id] = group
+
+	return nil
+}
+
+func (m *mockGroupStore) AcceptInvitation(id int, userId int) error {
+	i, member := m.member(id, userId)
+
+	if !member || m.groups[id].Members[i].FgAccepted {
+		return ErrInvitationNotFound
+	}
+
+	m.groups[id].Members[i].FgAccepted = true
+
+	return nil
+}
+
+func (m *mockGroupStore) RemoveMember(id int, userId int, memberUserId int) error {
+	return nil
+}
+
+func (m *mockGroupStore) AreGroupMates(userId int, otherUserId int) (bool, error) {
+	if userId == otherUserId {
+		return true, nil
+	}
+
+	for id := range m.groups {
+		a, okA := m.member(id, userId)
+		b, okB := m.member(id, otherUserId)
+
+		if okA && okB && m.groups[id].Members[a].FgAccepted && m.groups[id].Members[b].FgAccepted {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// mockUserStore só implementa as buscas usadas pelo grupo; o restante da
+// interface fica no valor nulo embutido.
+type mockUserStore struct {
+	types.UserStore
+	users map[int]types.User
+}
+
+func newMockUserStore() *mockUserStore {
+	return &mockUserStore{
+		users: map[int]types.User{
+			1: {ID: 1, Name: "Ana", Email: "ana@email.com"},
+			2: {ID: 2, Name: "Bruno", Email: "bruno@email.com"},
+			3: {ID: 3, Name: "Carla", Email: "carla@email.com"},
+			4: {ID: 4, Name: "Davi", Email: "davi@email.com"},
+		},
+	}
+}
+
+func (m *mockUserStore) GetUserByEmail(email string) (*types.User, error) {
+	for _, user := range m.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+
+	return nil, errors.New("user not found")
+}
+
+func (m *mockUserStore) GetUserByID(id int) (*types.User, error) {
+	user, ok := m.users[id]
+
+	if !ok {
+		return nil, errors.New("user not found")
+	}
+
+	return &user, nil
+}
+
+func serveAs(userId int, method, path string, body []byte, route string, handler http.HandlerFunc) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBuffer(body))
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, userId))
+
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+
+	router.HandleFunc(route, handler)
+	router.ServeHTTP(rr, req)
+
+	return rr
+}
+
+func TestCheckParticipant(t *testing.T) {
+	t.Run("should return an accepted group mate", func(t *testing.T) {
+		participant, err := CheckParticipant(newMockGroupStore(), newMockUserStore(), 1, 2)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if participant.Name != "Bruno" {
+			t.Errorf("expected Bruno, got %s", participant.Name)
+		}
+	})
+
+	t.Run("should reject a pending invitee", func(t *testing.T) {
+		_, err := CheckParticipant(newMockGroupStore(), newMockUserStore(), 1, 3)
+
+		if !errors.Is(err, ErrNotGroupMate) {
+			t.Errorf("expected %v, got %v", ErrNotGroupMate, err)
+		}
+	})
+
+	t.Run("should reject a user outside the groups", func(t *testing.T) {
+		_, err := CheckParticipant(newMockGroupStore(), newMockUserStore(), 1, 4)
+
+		if !errors.Is(err, ErrNotGroupMate) {
+			t.Errorf("expected %v, got %v", ErrNotGroupMate, err)
+		}
+	})
+}
+
+func TestGroupServiceHandlers(t *testing.T) {
+	t.Run("should invite a user by email", func(t *testing.T) {
+		store := newMockGroupStore()
+		handler := NewHandler(store, newMockUserStore())
+
+		marshalled, _ := json.Marshal(types.InviteGroupMemberPayload{Email: "davi@email.com"})
+
+		rr := serveAs(1, http.MethodPost, "/group/1/member", marshalled, "/group/{id}/member", handler.handleInviteMember)
+
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d", http.StatusCreated, rr.Code)
+		}
+
+		if _, member := store.member(1, 4); !member {
+			t.Errorf("expected user 4 to be invited")
+		}
+	})
+
+	t.Run("should not invite to another user's group", func(t *testing.T) {
+		store := newMockGroupStore()
+		handler := NewHandler(store, newMockUserStore())
+
+		marshalled, _ := json.Marshal(types.InviteGroupMemberPayload{Email: "davi@email.com"})
+
+		rr := serveAs(2, http.MethodPost, "/group/1/member", marshalled, "/group/{id}/member", handler.handleInviteMember)
+
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("should not invite an unknown email", func(t *testing.T) {
+		handler := NewHandler(newMockGroupStore(), newMockUserStore())
+
+		marshalled, _ := json.Marshal(types.InviteGroupMemberPayload{Email: "ninguem@email.com"})
+
+		rr := serveAs(1, http.MethodPost, "/group/1/member", marshalled, "/group/{id}/member", handler.handleInviteMember)
+
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("should not invite a user twice", func(t *testing.T) {
+		handler := NewHandler(newMockGroupStore(), newMockUserStore())
+
+		marshalled, _ := json.Marshal(types.InviteGroupMemberPayload{Email: "carla@email.com"})
+
+		rr := serveAs(1, http.MethodPost, "/group/1/member", marshalled, "/group/{id}/member", handler.handleInviteMember)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("should make the invitee a group mate after accepting", func(t *testing.T) {
+		store := newMockGroupStore()
+		handler := NewHandler(store, newMockUserStore())
+
+		rr := serveAs(3, http.MethodPost, "/group/1/accept", nil, "/group/{id}/accept", handler.handleAcceptInvitation)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		if ok, _ := store.AreGroupMates(1, 3); !ok {
+			t.Errorf("expected user 3 to be a group mate after accepting")
+		}
+
+		rr = serveAs(3, http.MethodPost, "/group/1/accept", nil, "/group/{id}/accept", handler.handleAcceptInvitation)
+
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status %d for a second accept, got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("should not accept an invitation that does not exist", func(t *testing.T) {
+		handler := NewHandler(newMockGroupStore(), newMockUserStore())
+
+		rr := serveAs(4, http.MethodPost, "/group/1/accept", nil, "/group/{id}/accept", handler.handleAcceptInvitation)
+
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("should hide pending invitations from members", func(t *testing.T) {
+		handler := NewHandler(newMockGroupStore(), newMockUserStore())
+
+		rr := serveAs(2, http.MethodGet, "/group/1", nil, "/group/{id}", handler.handleGetGroup)
+
+		var group types.Group
+		json.NewDecoder(rr.Body).Decode(&group)
+
+		if len(group.Members) != 2 {
+			t.Errorf("expected 2 visible members, got %+v", group.Members)
+		}
+	})
+}
+
+func TestVisibleMembers(t *testing.T) {
+	members := newMockGroupStore().groups[1].Members
+
+	t.Run("should show every member to the owner", func(t *testing.T) {
+		if visible := visibleMembers(members, 1, 1); len(visible) != 3 {
+			t.Errorf("expected 3 members, got %d", len(visible))
+		}
+	})
+
+	t.Run("should hide pending invitees from members", func(t *testing.T) {
+		for _, member := range visibleMembers(members, 1, 2) {
+			if member.IdUser == 3 {
+				t.Errorf("expected the pending invitee to be hidden")
+			}
+		}
+	})
+
+	t.Run("should hide other emails from a pending invitee", func(t *testing.T) {
+		visible := visibleMembers(members, 1, 3)
+
+		if len(visible) != 3 {
+			t.Fatalf("expected 3 members, got %d", len(visible))
+		}
+
+		for _, member := range visible {
+			if member.IdUser != 3 && member.Email != "" {
+				t.Errorf("expected the email of user %d to be hidden, got %s", member.IdUser, member.Email)
+			}
+
+			if member.IdUser == 3 && member.Email == "" {
+				t.Errorf("expected the invitee to see their own email")
+			}
+		}
+	})
+}
@@ -0,0 +1,336 @@
+package group
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+var ErrGroupNotFound = fmt.Errorf("group not found")
+var ErrMemberAlreadyInGroup = fmt.Errorf("user is already a member of this group")
+var ErrInvitationNotFound = fmt.Errorf("invitation not found")
+var ErrNotGroupMate = fmt.Errorf("participant is not a member of any of your groups")
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) GetGroups(userId int) ([]types.Group, error) {
+	rows, err := s.db.Query(`
+		SELECT g.id_group, g.ds_group, g.id_user
+		FROM groups g
+		INNER JOIN group_member gm ON gm.id_group = g.id_group
+		WHERE gm.id_user = $1
+		ORDER BY g.id_group DESC`, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]types.Group, 0)
+
+	for rows.Next() {
+		group, err := scanRowIntoGroup(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		groups = append(groups, *group)
+	}
+
+	for i := range groups {
+		groups[i].Members, err = s.getMembers(groups[i], userId)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return groups, nil
+}
+
+func (s *Store) GetGroupById(id int, userId int) (*types.Group, error) {
+	rows, err := s.db.Query(`
+		SELECT g.id_group, g.ds_group, g.id_user
+		FROM groups g
+		INNER JOIN group_member gm ON gm.id_group = g.id_group
+		WHERE g.id_group = $1 AND gm.id_user = $2`, id, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	group := &types.Group{}
+
+	for rows.Next() {
+		group, err = scanRowIntoGroup(rows)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if group.IdGroup == 0 {
+		return nil, ErrGroupNotFound
+	}
+
+	group.Members, err = s.getMembers(*group, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+func (s *Store) CreateGroup(groupPayload types.Group, userId int) (*types.Group, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	var id int
+
+	err = tx.QueryRow("INSERT INTO groups (ds_group, id_user) VALUES ($1, $2) RETURNING id_group", groupPayload.DsGroup, userId).Scan(&id)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// O criador do grupo já entra como membro aceito
+	_, err = tx.Exec("INSERT INTO group_member (id_group, id_user, fg_accepted) VALUES ($1, $2, TRUE)", id, userId)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetGroupById(id, userId)
+}
+
+func (s *Store) DeleteGroup(id int, userId int) error {
+	result, err := s.db.Exec("DELETE FROM groups WHERE id_group = $1 AND id_user = $2", id, userId)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrGroupNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) InviteMember(id int, userId int, memberUserId int) error {
+	// Apenas o dono do grupo pode convidar
+	if err := s.checkOwner(id, userId); err != nil {
+		return err
+	}
+
+	var exists bool
+
+	err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM group_member WHERE id_group = $1 AND id_user = $2)", id, memberUserId).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return ErrMemberAlreadyInGroup
+	}
+
+	_, err = s.db.Exec("INSERT INTO group_member (id_group, id_user, fg_accepted) VALUES ($1, $2, FALSE)", id, memberUserId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) AcceptInvitation(id int, userId int) error {
+	result, err := s.db.Exec("UPDATE group_member SET fg_accepted = TRUE WHERE id_group = $1 AND id_user = $2 AND fg_accepted = FALSE", id, userId)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrInvitationNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) RemoveMember(id int, userId int, memberUserId int) error {
+	// O dono remove qualquer membro, os demais só podem sair do grupo
+	if userId != memberUserId {
+		if err := s.checkOwner(id, userId); err != nil {
+			return err
+		}
+	}
+
+	result, err := s.db.Exec(`
+		DELETE FROM group_member
+		WHERE id_group = $1 AND id_user = $2
+		AND id_user <> (SELECT id_user FROM groups WHERE id_group = $1)`, id, memberUserId)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrGroupNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) AreGroupMates(userId int, otherUserId int) (bool, error) {
+	if userId == otherUserId {
+		return true, nil
+	}
+
+	var exists bool
+
+	err := s.db.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1
+			FROM group_member a
+			INNER JOIN group_member b ON a.id_group = b.id_group
+			WHERE a.id_user = $1 AND b.id_user = $2
+			AND a.fg_accepted AND b.fg_accepted
+		)`, userId, otherUserId).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func (s *Store) checkOwner(id int, userId int) error {
+	var exists bool
+
+	err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM groups WHERE id_group = $1 AND id_user = $2)", id, userId).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return ErrGroupNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) getMembers(group types.Group, userId int) ([]types.GroupMember, error) {
+	rows, err := s.db.Query(`
+		SELECT gm.id_group_member, gm.id_group, gm.id_user, u.name, u.email, gm.fg_accepted
+		FROM group_member gm
+		INNER JOIN users u ON u.id = gm.id_user
+		WHERE gm.id_group = $1
+		ORDER BY gm.id_group_member ASC`, group.IdGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]types.GroupMember, 0)
+
+	for rows.Next() {
+		member := types.GroupMember{}
+		err := rows.Scan(&member.IdGroupMember, &member.IdGroup, &member.IdUser, &member.Name, &member.Email, &member.FgAccepted)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	return visibleMembers(members, group.IdUser, userId), nil
+}
+
+// visibleMembers filtra os membros conforme quem está vendo o grupo: só o
+// dono enxerga os convites pendentes, e quem ainda não aceitou o convite não
+// vê o email dos demais.
+func visibleMembers(members []types.GroupMember, ownerId int, userId int) []types.GroupMember {
+	if userId == ownerId {
+		return members
+	}
+
+	accepted := false
+
+	for _, member := range members {
+		if member.IdUser == userId {
+			accepted = member.FgAccepted
+		}
+	}
+
+	visible := make([]types.GroupMember, 0, len(members))
+
+	for _, member := range members {
+		if member.IdUser != userId {
+			if !member.FgAccepted {
+				continue
+			}
+
+			if !accepted {
+				member.Email = ""
+			}
+		}
+
+		visible = append(visible, member)
+	}
+
+	return visible
+}
+
+func scanRowIntoGroup(rows *sql.Rows) (*types.Group, error) {
+	g := &types.Group{}
+
+	err := rows.Scan(
+		&g.IdGroup,
+		&g.DsGroup,
+		&g.IdUser,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+// CheckParticipant garante que o usuário referenciado em um pagamento
+// participa de algum grupo com o dono da conta e retorna os dados dele.
+func CheckParticipant(store types.GroupStore, userStore types.UserStore, userId int, participantId int) (*types.User, error) {
+	ok, err := store.AreGroupMates(userId, participantId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, ErrNotGroupMate
+	}
+
+	return userStore.GetUserByID(participantId)
+}
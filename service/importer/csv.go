@@ -0,0 +1,140 @@
+package importer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+const defaultDateLayout = "02/01/2006"
+
+// ParseCSV lê os lançamentos de um CSV com cabeçalho, usando o mapeamento
+// de colunas informado.
+func ParseCSV(r io.Reader, mapping types.CSVMapping) ([]types.ImportedTransaction, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	if mapping.DsDelimiter != "" {
+		reader.Comma = []rune(mapping.DsDelimiter)[0]
+	}
+
+	layout := mapping.DsDateLayout
+	if layout == "" {
+		layout = defaultDateLayout
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing header", ErrInvalidFile)
+	}
+
+	// Remove o BOM que planilhas costumam gravar no início do arquivo
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	columns := make(map[string]int)
+	for _, field := range []struct {
+		name   string
+		column string
+	}{
+		{"date", mapping.DsDateColumn},
+		{"description", mapping.DsDescriptionColumn},
+		{"amount", mapping.DsAmountColumn},
+		{"id", mapping.DsIdColumn},
+	} {
+		if field.column == "" {
+			continue
+		}
+
+		index, err := columnIndex(header, field.column)
+		if err != nil {
+			return nil, err
+		}
+		columns[field.name] = index
+	}
+
+	transactions := make([]types.ImportedTransaction, 0)
+	ids := &hashIds{}
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+
+		if blank(record) {
+			continue
+		}
+
+		value := func(name string) string {
+			index, ok := columns[name]
+			if !ok || index >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[index])
+		}
+
+		date, err := time.Parse(layout, value("date"))
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d has invalid date %q", ErrInvalidFile, line, value("date"))
+		}
+
+		amount, err := parseAmount(value("amount"), mapping.FgDecimalComma)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		description := value("description")
+		vlAmount, tpEntry := entry(amount, mapping.FgNegativeDebit)
+
+		externalId := ids.next(date, amount, description)
+		if id := value("id"); id != "" {
+			externalId = "csv:" + id
+		}
+
+		transactions = append(transactions, types.ImportedTransaction{
+			DsExternalId:  externalId,
+			DtTransaction: date,
+			DsDescription: description,
+			VlAmount:      vlAmount,
+			TpEntry:       tpEntry,
+		})
+	}
+
+	return transactions, nil
+}
+
+// columnIndex aceita o nome da coluna no cabeçalho ou a posição a partir de 1.
+func columnIndex(header []string, column string) (int, error) {
+	for i, name := range header {
+		if strings.EqualFold(strings.TrimSpace(name), strings.TrimSpace(column)) {
+			return i, nil
+		}
+	}
+
+	if position, err := strconv.Atoi(column); err == nil && position >= 1 && position <= len(header) {
+		return position - 1, nil
+	}
+
+	return 0, fmt.Errorf("%w: column %q not found", ErrInvalidFile, column)
+}
+
+func blank(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+
+	return true
+}
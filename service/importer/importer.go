@@ -0,0 +1,82 @@
+package importer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+var ErrInvalidFile = fmt.Errorf("invalid import file")
+
+// hashIds gera o id externo dos lançamentos sem identificador próprio. Lançamentos
+// idênticos no mesmo arquivo (dois cafés no mesmo dia) recebem a ordem da
+// repetição no hash para continuarem distintos.
+type hashIds struct {
+	seen map[string]int
+}
+
+func (h *hashIds) next(date time.Time, amount types.Money, description string) string {
+	if h.seen == nil {
+		h.seen = make(map[string]int)
+	}
+
+	key := fmt.Sprintf("%s|%s|%s", date.Format("2006-01-02"), amount, strings.ToLower(strings.TrimSpace(description)))
+	h.seen[key]++
+
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d", key, h.seen[key])))
+
+	return "hash:" + hex.EncodeToString(sum[:16])
+}
+
+// parseAmount lê valores como "1.234,56", "R$ -12,50" ou "1,234.56".
+func parseAmount(raw string, decimalComma bool) (types.Money, error) {
+	value := strings.TrimSpace(raw)
+	value = strings.TrimPrefix(value, "R$")
+	value = strings.ReplaceAll(value, " ", "")
+
+	negative := false
+	if strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")") {
+		negative = true
+		value = strings.Trim(value, "()")
+	}
+
+	if decimalComma {
+		value = strings.ReplaceAll(value, ".", "")
+		value = strings.ReplaceAll(value, ",", ".")
+	} else {
+		value = strings.ReplaceAll(value, ",", "")
+	}
+
+	amount, err := types.ParseMoney(value)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+
+	if negative {
+		amount = -amount
+	}
+
+	return amount, nil
+}
+
+// entry converte o valor com sinal em um lançamento de valor positivo.
+func entry(amount types.Money, negativeDebit bool) (types.Money, string) {
+	debit := amount > 0
+	if negativeDebit {
+		debit = amount < 0
+	}
+
+	if amount < 0 {
+		amount = -amount
+	}
+
+	if debit {
+		return amount, types.EntryDebit
+	}
+
+	return amount, types.EntryCredit
+}
@@ -0,0 +1,165 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+func TestParseCSV(t *testing.T) {
+	mapping := types.CSVMapping{
+		DsDateColumn:        "Data",
+		DsDescriptionColumn: "Descrição",
+		DsAmountColumn:      "Valor",
+		DsDelimiter:         ";",
+		FgDecimalComma:      true,
+		FgNegativeDebit:     true,
+	}
+
+	content := "Data;Descrição;Valor\n" +
+		"03/02/2025;Mercado;-1.234,56\n" +
+		"04/02/2025;Salário;5.000,00\n" +
+		"05/02/2025;Café;-8,50\n" +
+		"05/02/2025;Café;-8,50\n"
+
+	t.Run("should read the mapped columns", func(t *testing.T) {
+		transactions, err := ParseCSV(strings.NewReader(content), mapping)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(transactions) != 4 {
+			t.Fatalf("expected 4 transactions, got %d", len(transactions))
+		}
+
+		first := transactions[0]
+		if !first.DtTransaction.Equal(time.Date(2025, 2, 3, 0, 0, 0, 0, time.UTC)) || first.DsDescription != "Mercado" ||
+			first.VlAmount != 123456 || first.TpEntry != types.EntryDebit {
+			t.Errorf("unexpected first transaction %+v", first)
+		}
+
+		if transactions[1].TpEntry != types.EntryCredit || transactions[1].VlAmount != 500000 {
+			t.Errorf("expected a credit of 5000,00, got %+v", transactions[1])
+		}
+	})
+
+	t.Run("should hash repeated transactions into distinct and stable ids", func(t *testing.T) {
+		first, _ := ParseCSV(strings.NewReader(content), mapping)
+		second, _ := ParseCSV(strings.NewReader(content), mapping)
+
+		if first[2].DsExternalId == first[3].DsExternalId {
+			t.Errorf("expected repeated transactions to have distinct ids")
+		}
+
+		for i := range first {
+			if first[i].DsExternalId != second[i].DsExternalId {
+				t.Errorf("expected id %s to be stable, got %s", first[i].DsExternalId, second[i].DsExternalId)
+			}
+		}
+	})
+
+	t.Run("should use the id column and column positions", func(t *testing.T) {
+		transactions, err := ParseCSV(strings.NewReader("date,description,amount,id\n2025-02-03,Mercado,\"1,234.56\",A1\n"), types.CSVMapping{
+			DsDateColumn:        "1",
+			DsDescriptionColumn: "2",
+			DsAmountColumn:      "3",
+			DsIdColumn:          "id",
+			DsDateLayout:        "2006-01-02",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if transactions[0].DsExternalId != "csv:A1" || transactions[0].VlAmount != 123456 || transactions[0].TpEntry != types.EntryDebit {
+			t.Errorf("unexpected transaction %+v", transactions[0])
+		}
+	})
+
+	t.Run("should reject unknown columns and invalid dates", func(t *testing.T) {
+		invalid := mapping
+		invalid.DsAmountColumn = "Montante"
+
+		if _, err := ParseCSV(strings.NewReader(content), invalid); err == nil {
+			t.Errorf("expected an error for an unknown column")
+		}
+
+		if _, err := ParseCSV(strings.NewReader("Data;Descrição;Valor\n2025-02-03;Mercado;1,00\n"), mapping); err == nil {
+			t.Errorf("expected an error for an invalid date")
+		}
+	})
+}
+
+func TestParseOFX(t *testing.T) {
+	t.Run("should read SGML statements", func(t *testing.T) {
+		content := `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+
+<OFX>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<BANKACCTFROM><BANKID>0341<ACCTID>12345-6</BANKACCTFROM>
+<BANKTRANLIST>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20250203120000[-3:BRT]
+<TRNAMT>-120.50
+<FITID>202502030001
+<MEMO>Posto &amp; Conveniência
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20250205
+<TRNAMT>300.00
+<FITID>202502050001
+<NAME>PIX RECEBIDO
+</STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>`
+
+		transactions, err := ParseOFX(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(transactions) != 2 {
+			t.Fatalf("expected 2 transactions, got %d", len(transactions))
+		}
+
+		first := transactions[0]
+		if first.DsExternalId != "ofx:12345-6:202502030001" || first.DsDescription != "Posto & Conveniência" ||
+			first.VlAmount != 12050 || first.TpEntry != types.EntryDebit ||
+			!first.DtTransaction.Equal(time.Date(2025, 2, 3, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("unexpected first transaction %+v", first)
+		}
+
+		if transactions[1].TpEntry != types.EntryCredit || transactions[1].DsDescription != "PIX RECEBIDO" {
+			t.Errorf("unexpected second transaction %+v", transactions[1])
+		}
+	})
+
+	t.Run("should read XML statements", func(t *testing.T) {
+		content := `<?xml version="1.0"?><?OFX OFXHEADER="200" VERSION="211"?>
+<OFX><CREDITCARDMSGSRSV1><CCSTMTTRNRS><CCSTMTRS>
+<CCACCTFROM><ACCTID>4111</ACCTID></CCACCTFROM>
+<BANKTRANLIST><STMTTRN><TRNTYPE>DEBIT</TRNTYPE><DTPOSTED>20250210</DTPOSTED><TRNAMT>-42,90</TRNAMT><FITID>X9</FITID><NAME>Restaurante</NAME></STMTTRN></BANKTRANLIST>
+</CCSTMTRS></CCSTMTTRNRS></CREDITCARDMSGSRSV1></OFX>`
+
+		transactions, err := ParseOFX(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(transactions) != 1 || transactions[0].DsExternalId != "ofx:4111:X9" || transactions[0].VlAmount != 4290 {
+			t.Errorf("unexpected transactions %+v", transactions)
+		}
+	})
+
+	t.Run("should reject files that are not OFX", func(t *testing.T) {
+		if _, err := ParseOFX("Data;Valor\n"); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+}
@@ -0,0 +1,88 @@
+package importer
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+var (
+	transactionBlock = regexp.MustCompile(`(?is)<STMTTRN>(.*?)</STMTTRN>`)
+	// No OFX 1.x (SGML) as tags de valor não são fechadas, então o valor vai
+	// até a próxima tag ou quebra de linha
+	tagValue = regexp.MustCompile(`(?i)<([A-Z0-9.]+)>([^<\r\n]*)`)
+)
+
+// ParseOFX lê os lançamentos (STMTTRN) de extratos OFX 1.x e 2.x. O id
+// externo combina a conta e o FITID, que só é único dentro da conta.
+func ParseOFX(content string) ([]types.ImportedTransaction, error) {
+	if !strings.Contains(strings.ToUpper(content), "<OFX>") {
+		return nil, fmt.Errorf("%w: missing <OFX>", ErrInvalidFile)
+	}
+
+	account := tags(content)["ACCTID"]
+
+	transactions := make([]types.ImportedTransaction, 0)
+	ids := &hashIds{}
+
+	for _, block := range transactionBlock.FindAllStringSubmatch(content, -1) {
+		values := tags(block[1])
+
+		posted := values["DTPOSTED"]
+		if len(posted) < 8 {
+			return nil, fmt.Errorf("%w: transaction without DTPOSTED", ErrInvalidFile)
+		}
+
+		date, err := time.Parse("20060102", posted[:8])
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid DTPOSTED %q", ErrInvalidFile, posted)
+		}
+
+		amount, err := parseAmount(values["TRNAMT"], strings.Contains(values["TRNAMT"], ","))
+		if err != nil {
+			return nil, err
+		}
+
+		description := values["NAME"]
+		if description == "" {
+			description = values["MEMO"]
+		}
+
+		vlAmount, tpEntry := entry(amount, true)
+
+		externalId := ids.next(date, amount, description)
+		if fitId := values["FITID"]; fitId != "" {
+			externalId = "ofx:" + account + ":" + fitId
+		}
+
+		transactions = append(transactions, types.ImportedTransaction{
+			DsExternalId:  externalId,
+			DtTransaction: date,
+			DsDescription: description,
+			VlAmount:      vlAmount,
+			TpEntry:       tpEntry,
+		})
+	}
+
+	return transactions, nil
+}
+
+// tags retorna o primeiro valor de cada tag do trecho.
+func tags(content string) map[string]string {
+	values := make(map[string]string)
+
+	for _, match := range tagValue.FindAllStringSubmatch(content, -1) {
+		name := strings.ToUpper(match[1])
+		value := strings.TrimSpace(html.UnescapeString(match[2]))
+
+		if _, ok := values[name]; !ok && value != "" {
+			values[name] = value
+		}
+	}
+
+	return values
+}
@@ -0,0 +1,254 @@
+package listing
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+var ErrInvalidQuery = fmt.Errorf("invalid list query")
+
+// Sort descreve uma chave de ordenação: a expressão SQL, o tipo usado para
+// comparar o valor do cursor e como obter esse valor de um item da página.
+type Sort[T any] struct {
+	Expr  string
+	Cast  string
+	Value func(item T) string
+}
+
+// ParseQuery lê os parâmetros de listagem da URL. Só as chaves de ordenação
+// informadas são aceitas e, sem ordenação, a listagem é por id decrescente.
+func ParseQuery[T any](values url.Values, sorts map[string]Sort[T]) (types.ListQuery, error) {
+	query := types.ListQuery{
+		Search: strings.TrimSpace(values.Get("search")),
+		Person: strings.TrimSpace(values.Get("person")),
+		Status: values.Get("status"),
+		Sort:   values.Get("sort"),
+		Order:  strings.ToLower(values.Get("order")),
+		Limit:  DefaultLimit,
+	}
+
+	if query.Sort == "" {
+		query.Sort = "id"
+	}
+
+	if _, ok := sorts[query.Sort]; !ok {
+		return query, fmt.Errorf("%w: sort must be one of %s", ErrInvalidQuery, strings.Join(sortKeys(sorts), ", "))
+	}
+
+	if query.Order == "" {
+		query.Order = types.OrderDesc
+	}
+
+	if query.Order != types.OrderAsc && query.Order != types.OrderDesc {
+		return query, fmt.Errorf("%w: order must be asc or desc", ErrInvalidQuery)
+	}
+
+	if query.Status != "" && query.Status != types.StatusSettled && query.Status != types.StatusUnsettled {
+		return query, fmt.Errorf("%w: status must be settled or unsettled", ErrInvalidQuery)
+	}
+
+	if raw := values.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > MaxLimit {
+			return query, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, MaxLimit)
+		}
+		query.Limit = limit
+	}
+
+	var err error
+
+	if query.DtFrom, err = parseDate(values, "dtFrom"); err != nil {
+		return query, err
+	}
+
+	if query.DtTo, err = parseDate(values, "dtTo"); err != nil {
+		return query, err
+	}
+
+	for _, include := range strings.Split(values.Get("include"), ",") {
+		switch strings.TrimSpace(include) {
+		case "":
+		case "payments":
+			query.FgPayments = true
+		case "summary":
+			query.FgSummary = true
+		default:
+			return query, fmt.Errorf("%w: include accepts payments and summary", ErrInvalidQuery)
+		}
+	}
+
+	if raw := values.Get("cursor"); raw != "" {
+		cursor, err := decodeCursor(raw)
+
+		// O cursor só vale para a mesma ordenação em que foi gerado
+		if err != nil || cursor.Sort != query.Sort || cursor.Order != query.Order {
+			return query, fmt.Errorf("%w: invalid cursor", ErrInvalidQuery)
+		}
+
+		query.Cursor = cursor
+	}
+
+	return query, nil
+}
+
+func parseDate(values url.Values, name string) (*time.Time, error) {
+	raw := values.Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+
+	date, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s must be YYYY-MM-DD", ErrInvalidQuery, name)
+	}
+
+	return &date, nil
+}
+
+func sortKeys[T any](sorts map[string]Sort[T]) []string {
+	keys := make([]string, 0, len(sorts))
+	for key := range sorts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func encodeCursor(cursor types.Cursor) string {
+	data, _ := json.Marshal(cursor)
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(raw string) (*types.Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := &types.Cursor{}
+	if err := json.Unmarshal(data, cursor); err != nil {
+		return nil, err
+	}
+
+	return cursor, nil
+}
+
+// Builder monta as condições do WHERE com os argumentos numerados.
+type Builder struct {
+	conditions []string
+	args       []any
+}
+
+func (b *Builder) Arg(value any) string {
+	b.args = append(b.args, value)
+
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+func (b *Builder) Where(condition string) {
+	b.conditions = append(b.conditions, condition)
+}
+
+func (b *Builder) Args() []any {
+	return b.args
+}
+
+func (b *Builder) WhereClause() string {
+	if len(b.conditions) == 0 {
+		return ""
+	}
+
+	return "WHERE " + strings.Join(b.conditions, " AND ")
+}
+
+// After filtra os itens depois do cursor, comparando a chave de ordenação e o
+// id juntos para que empates não repitam nem pulem itens.
+func After[T any](b *Builder, sort Sort[T], idExpr string, query types.ListQuery) {
+	if query.Cursor == nil {
+		return
+	}
+
+	operator := "<"
+	if query.Order == types.OrderAsc {
+		operator = ">"
+	}
+
+	b.Where(fmt.Sprintf("(%s, %s) %s (%s::%s, %s)", sort.Expr, idExpr, operator, b.Arg(query.Cursor.Value), sort.Cast, b.Arg(query.Cursor.Id)))
+}
+
+func OrderBy[T any](sort Sort[T], idExpr string, order string) string {
+	direction := "DESC"
+	if order == types.OrderAsc {
+		direction = "ASC"
+	}
+
+	return fmt.Sprintf("%s %s, %s %s", sort.Expr, direction, idExpr, direction)
+}
+
+// Contains monta o padrão de busca do ILIKE, escapando os curingas do texto.
+func Contains(text string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+	return "%" + replacer.Replace(text) + "%"
+}
+
+// NewPage corta a página no limite (a consulta busca um item a mais para
+// saber se há próxima página) e gera o cursor a partir do último item.
+func NewPage[T any](items []T, query types.ListQuery, sort Sort[T], id func(item T) int) *types.Page[T] {
+	page := &types.Page[T]{Items: items, Limit: query.Limit}
+
+	if len(items) > query.Limit {
+		page.Items = items[:query.Limit]
+		page.FgHasMore = true
+
+		last := page.Items[len(page.Items)-1]
+		page.NextCursor = encodeCursor(types.Cursor{
+			Sort:  query.Sort,
+			Order: query.Order,
+			Value: sort.Value(last),
+			Id:    id(last),
+		})
+	}
+
+	return page
+}
+
+// All percorre as páginas a partir da consulta até a última, para as
+// exportações que precisam da listagem filtrada inteira.
+func All[T any](query types.ListQuery, fetch func(query types.ListQuery) (*types.Page[T], error)) ([]T, error) {
+	query.Limit = MaxLimit
+	items := make([]T, 0)
+
+	for {
+		page, err := fetch(query)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, page.Items...)
+
+		if !page.FgHasMore {
+			return items, nil
+		}
+
+		query.Cursor, err = decodeCursor(page.NextCursor)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package listing
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+type item struct {
+	id   int
+	name string
+}
+
+var sorts = map[string]Sort[item]{
+	"id":   {Expr: "t.id", Cast: "integer", Value: func(i item) string { return strconv.Itoa(i.id) }},
+	"name": {Expr: "t.name", Cast: "text", Value: func(i item) string { return i.name }},
+}
+
+func TestParseQuery(t *testing.T) {
+	t.Run("should default to id descending with the default limit", func(t *testing.T) {
+		query, err := ParseQuery(url.Values{}, sorts)
+
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if query.Sort != "id" || query.Order != types.OrderDesc || query.Limit != DefaultLimit {
+			t.Errorf("expected id desc limited to %d, got %+v", DefaultLimit, query)
+		}
+	})
+
+	t.Run("should parse filters and includes", func(t *testing.T) {
+		query, err := ParseQuery(url.Values{
+			"search":  {"mercado"},
+			"status":  {"unsettled"},
+			"dtFrom":  {"2026-01-01"},
+			"include": {"payments,summary"},
+		}, sorts)
+
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if query.Search != "mercado" || query.Status != types.StatusUnsettled || query.DtFrom == nil || !query.FgPayments || !query.FgSummary {
+			t.Errorf("unexpected query %+v", query)
+		}
+	})
+
+	invalid := map[string]url.Values{
+		"unknown sort":   {"sort": {"password"}},
+		"invalid order":  {"order": {"sideways"}},
+		"invalid status": {"status": {"paid"}},
+		"limit too high": {"limit": {"1000"}},
+		"invalid date":   {"dtTo": {"18/10/2026"}},
+		"invalid cursor": {"cursor": {"not-a-cursor"}},
+	}
+
+	for name, values := range invalid {
+		t.Run("should reject "+name, func(t *testing.T) {
+			if _, err := ParseQuery(values, sorts); !errors.Is(err, ErrInvalidQuery) {
+				t.Errorf("expected ErrInvalidQuery, got %v", err)
+			}
+		})
+	}
+}
+
+func TestNewPage(t *testing.T) {
+	items := []item{{1, "a"}, {2, "b"}, {3, "c"}}
+
+	t.Run("should cut the extra item and point the cursor to the last one", func(t *testing.T) {
+		query := types.ListQuery{Sort: "name", Order: types.OrderAsc, Limit: 2}
+
+		page := NewPage(items, query, sorts["name"], func(i item) int { return i.id })
+
+		if len(page.Items) != 2 || !page.FgHasMore || page.NextCursor == "" {
+			t.Fatalf("expected 2 items and a next cursor, got %+v", page)
+		}
+
+		next, err := ParseQuery(url.Values{"sort": {"name"}, "order": {"asc"}, "cursor": {page.NextCursor}}, sorts)
+		if err != nil {
+			t.Fatalf("expected the cursor to be accepted, got %v", err)
+		}
+
+		if next.Cursor.Value != "b" || next.Cursor.Id != 2 {
+			t.Errorf("expected the cursor at b/2, got %+v", next.Cursor)
+		}
+	})
+
+	t.Run("should not have a cursor on the last page", func(t *testing.T) {
+		page := NewPage(items, types.ListQuery{Sort: "id", Order: types.OrderDesc, Limit: 3}, sorts["id"], func(i item) int { return i.id })
+
+		if page.FgHasMore || page.NextCursor != "" {
+			t.Errorf("expected no next page, got %+v", page)
+		}
+	})
+
+	t.Run("should reject a cursor from another sort", func(t *testing.T) {
+		page := NewPage(items, types.ListQuery{Sort: "id", Order: types.OrderDesc, Limit: 1}, sorts["id"], func(i item) int { return i.id })
+
+		_, err := ParseQuery(url.Values{"sort": {"name"}, "cursor": {page.NextCursor}}, sorts)
+
+		if !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("expected ErrInvalidQuery, got %v", err)
+		}
+	})
+}
+
+func TestBuilder(t *testing.T) {
+	t.Run("should number arguments and compare sort key and id after the cursor", func(t *testing.T) {
+		builder := &Builder{}
+		builder.Where("t.id_user = " + builder.Arg(1))
+
+		After(builder, sorts["name"], "t.id", types.ListQuery{Order: types.OrderDesc, Cursor: &types.Cursor{Value: "b", Id: 2}})
+
+		expected := "WHERE t.id_user = $1 AND (t.name, t.id) < ($2::text, $3)"
+		if builder.WhereClause() != expected {
+			t.Errorf("expected %q, got %q", expected, builder.WhereClause())
+		}
+
+		if len(builder.Args()) != 3 {
+			t.Errorf("expected 3 arguments, got %v", builder.Args())
+		}
+	})
+
+	t.Run("should escape wildcards in searches", func(t *testing.T) {
+		if pattern := Contains("50%_off"); pattern != `%50\%\_off%` {
+			t.Errorf("unexpected pattern %q", pattern)
+		}
+	})
+}
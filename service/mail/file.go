@@ -0,0 +1,41 @@
+package mail
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9@._-]`)
+
+// FileMailer grava cada email como um .eml, para abrir num cliente de email
+// ou ler nos testes.
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir string, from string) *FileMailer {
+	return &FileMailer{dir: dir, from: from}
+}
+
+func (m *FileMailer) Send(email types.Email) error {
+	now := time.Now()
+
+	msg, err := message(m.from, email, now)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%d-%s.eml", now.UnixNano(), unsafeFileChars.ReplaceAllString(email.To, "_"))
+
+	return os.WriteFile(filepath.Join(m.dir, name), msg, 0o600)
+}
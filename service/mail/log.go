@@ -0,0 +1,28 @@
+package mail
+
+import (
+	"log"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+// LogMailer só escreve o email no log do servidor.
+type LogMailer struct {
+	from string
+}
+
+func NewLogMailer(from string) *LogMailer {
+	return &LogMailer{from: from}
+}
+
+func (m *LogMailer) Send(email types.Email) error {
+	msg, err := message(m.from, email, time.Now())
+	if err != nil {
+		return err
+	}
+
+	log.Printf("email to %s:\n%s", email.To, msg)
+
+	return nil
+}
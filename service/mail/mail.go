@@ -0,0 +1,62 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/config"
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+const (
+	DriverSMTP = "smtp"
+	DriverFile = "file"
+	DriverLog  = "log"
+)
+
+var ErrInvalidHeader = fmt.Errorf("invalid email header")
+
+// New escolhe o mailer pelo MAIL_DRIVER: smtp, file (um .eml por email em
+// MAIL_DIR) ou log, o padrão.
+func New(cfg config.Config) (types.Mailer, error) {
+	switch cfg.MailDriver {
+	case DriverSMTP:
+		if cfg.SMTPHost == "" {
+			return nil, fmt.Errorf("SMTP_HOST is required for the smtp mail driver")
+		}
+
+		return NewSMTPMailer(cfg.SMTPHost, int(cfg.SMTPPort), cfg.SMTPUser, cfg.SMTPPassword, cfg.MailFrom), nil
+	case DriverFile:
+		return NewFileMailer(cfg.MailDir, cfg.MailFrom), nil
+	case DriverLog, "":
+		return NewLogMailer(cfg.MailFrom), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.MailDriver)
+	}
+}
+
+// message monta o email em texto puro. Quebras de linha nos cabeçalhos
+// permitiriam injetar outros cabeçalhos, então são recusadas.
+func message(from string, email types.Email, date time.Time) ([]byte, error) {
+	for _, header := range []string{from, email.To, email.Subject} {
+		if strings.ContainsAny(header, "\r\n") {
+			return nil, ErrInvalidHeader
+		}
+	}
+
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", email.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", email.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(strings.ReplaceAll(email.Body, "\r\n", "\n"), "\n", "\r\n"))
+
+	return buf.Bytes(), nil
+}
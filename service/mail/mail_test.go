@@ -0,0 +1,52 @@
+package mail
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+func TestFileMailer(t *testing.T) {
+	t.Run("should write the email to the directory", func(t *testing.T) {
+		dir := t.TempDir()
+		mailer := NewFileMailer(dir, "Splitz <no-reply@splitz.local>")
+
+		err := mailer.Send(types.Email{To: "ana@example.com", Subject: "Verificação", Body: "linha 1\nlinha 2"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		files, _ := filepath.Glob(filepath.Join(dir, "*-ana@example.com.eml"))
+		if len(files) != 1 {
+			t.Fatalf("expected one email file, got %v", files)
+		}
+
+		content, _ := os.ReadFile(files[0])
+
+		if !strings.Contains(string(content), "To: ana@example.com\r\n") {
+			t.Errorf("expected the To header, got %q", content)
+		}
+
+		if !strings.Contains(string(content), "Subject: =?utf-8?q?Verifica=C3=A7=C3=A3o?=\r\n") {
+			t.Errorf("expected an encoded subject, got %q", content)
+		}
+
+		if !strings.HasSuffix(string(content), "\r\n\r\nlinha 1\r\nlinha 2") {
+			t.Errorf("expected the body with CRLF line endings, got %q", content)
+		}
+	})
+
+	t.Run("should reject line breaks in headers", func(t *testing.T) {
+		mailer := NewFileMailer(t.TempDir(), "no-reply@splitz.local")
+
+		err := mailer.Send(types.Email{To: "ana@example.com\r\nBcc: eve@example.com", Subject: "Oi"})
+
+		if !errors.Is(err, ErrInvalidHeader) {
+			t.Errorf("expected %v, got %v", ErrInvalidHeader, err)
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package mail
+
+import (
+	"fmt"
+	"net/mail"
+	"net/smtp"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+type SMTPMailer struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+}
+
+func NewSMTPMailer(host string, port int, username string, password string, from string) *SMTPMailer {
+	return &SMTPMailer{
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
+
+// Send usa STARTTLS quando o servidor oferece, e só autentica se houver usuário.
+func (m *SMTPMailer) Send(email types.Email) error {
+	msg, err := message(m.from, email, time.Now())
+	if err != nil {
+		return err
+	}
+
+	from, err := mail.ParseAddress(m.from)
+	if err != nil {
+		return fmt.Errorf("invalid MAIL_FROM: %w", err)
+	}
+
+	to, err := mail.ParseAddress(email.To)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+
+	return smtp.SendMail(fmt.Sprintf("%s:%d", m.host, m.port), auth, from.Address, []string{to.Address}, msg)
+}
@@ -0,0 +1,197 @@
+package payment
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gfmanica/splitz-backend/service/auth"
+	"github.com/gfmanica/splitz-backend/service/currency"
+	"github.com/gfmanica/splitz-backend/service/pix"
+	"github.com/gfmanica/splitz-backend/types"
+	"github.com/gfmanica/splitz-backend/utils"
+	"github.com/gorilla/mux"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+var (
+	ErrPixKeyMissing = fmt.Errorf("the owner has no pix key")
+	ErrPaymentPaid   = fmt.Errorf("payment is already paid")
+)
+
+const (
+	defaultQRCodeSize = 256
+	maxQRCodeSize     = 1024
+)
+
+// GetPixCharge carrega o pagamento com o valor já pago e a chave Pix do dono
+// do bill ou ride. Só o dono ou a própria pessoa do pagamento podem consultar.
+func (s *Store) GetPixCharge(tpPayment string, idParent int, idPayment int, userId int) (*types.PixCharge, error) {
+	t, ok := paymentTables[tpPayment]
+	if !ok {
+		return nil, ErrPaymentNotFound
+	}
+
+	charge := &types.PixCharge{TpPayment: tpPayment, IdParent: idParent, IdPayment: idPayment}
+
+	err := s.db.QueryRow(fmt.Sprintf(`
+		SELECT p.vl_payment, p.ds_person, o.%[5]s, %[6]s, %[7]s, u.id, u.name, u.ds_pix_key, u.ds_pix_city,
+		(SELECT COALESCE(SUM(pt.vl_payment), 0) FROM payment_transaction pt WHERE pt.%[2]s = p.%[2]s)
+		FROM %[1]s p
+		INNER JOIN %[3]s o ON o.%[4]s = p.%[4]s
+		INNER JOIN users u ON u.id = o.id_user
+		WHERE p.%[2]s = $1 AND p.%[4]s = $2
+		AND (o.id_user = $3 OR p.id_user = $3)`, t.table, t.idColumn, t.parentTable, t.parentColumn, t.descColumn, t.currencyExpr, t.dateExpr),
+		idPayment, idParent, userId).Scan(
+		&charge.VlPayment,
+		&charge.DsPerson,
+		&charge.DsParent,
+		&charge.CdCurrency,
+		&charge.DtParent,
+		&charge.IdReceiver,
+		&charge.DsReceiver,
+		&charge.DsPixKey,
+		&charge.DsPixCity,
+		&charge.VlPaid,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrPaymentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	charge.VlOutstanding, _ = Status(charge.VlPayment, charge.VlPaid)
+
+	return charge, nil
+}
+
+// brCode preenche a referência e o BR Code da cobrança. O valor cobrado é o
+// que falta pagar, que para um pagamento sem transações é o VlPayment inteiro.
+// Pix só aceita reais, então bills em outra moeda são convertidos pelas taxas
+// de quem recebe.
+func brCode(charge *types.PixCharge, converter *currency.Converter) error {
+	if charge.DsPixKey == nil || charge.DsPixCity == nil {
+		return ErrPixKeyMissing
+	}
+
+	if charge.VlOutstanding <= 0 {
+		return ErrPaymentPaid
+	}
+
+	vlCharge, err := converter.Convert(charge.VlOutstanding, charge.CdCurrency, charge.DtParent)
+	if err != nil {
+		return err
+	}
+	charge.VlCharge = vlCharge
+
+	charge.DsReference = fmt.Sprintf("%s%dP%d", strings.ToUpper(charge.TpPayment), charge.IdParent, charge.IdPayment)
+	charge.DsBrCode = pix.BRCode(pix.Charge{
+		Key:         *charge.DsPixKey,
+		Receiver:    charge.DsReceiver,
+		City:        *charge.DsPixCity,
+		Amount:      charge.VlCharge,
+		Reference:   charge.DsReference,
+		Description: charge.DsParent,
+	})
+
+	return nil
+}
+
+func (h *Handler) getPixCharge(tpPayment string, w http.ResponseWriter, r *http.Request) (*types.PixCharge, bool) {
+	vars := mux.Vars(r)
+	id, _ := strconv.Atoi(vars["id"])
+	idPayment, _ := strconv.Atoi(vars["idPayment"])
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	charge, err := h.store.GetPixCharge(tpPayment, id, idPayment, userId)
+
+	if errors.Is(err, ErrPaymentNotFound) {
+		utils.WriterError(w, http.StatusNotFound, err)
+		return nil, false
+	}
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+
+	rates, err := h.rateStore.GetRates(charge.IdReceiver)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+
+	err = brCode(charge, currency.NewConverter(types.DefaultCurrency, rates))
+
+	if errors.Is(err, ErrPixKeyMissing) {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return nil, false
+	}
+
+	if errors.Is(err, ErrPaymentPaid) {
+		utils.WriterError(w, http.StatusConflict, err)
+		return nil, false
+	}
+
+	// Sem a taxa a cobrança não pode ser gerada até o dono cadastrá-la
+	if errors.Is(err, currency.ErrMissingRate) {
+		utils.WriterError(w, http.StatusUnprocessableEntity, err)
+		return nil, false
+	}
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+
+	return charge, true
+}
+
+// handleGetPixCharge devolve a cobrança com o "Pix copia e cola".
+func (h *Handler) handleGetPixCharge(tpPayment string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		charge, ok := h.getPixCharge(tpPayment, w, r)
+		if !ok {
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, charge)
+	}
+}
+
+// handleGetPixQRCode devolve o BR Code como PNG. Aceita size em pixels.
+func (h *Handler) handleGetPixQRCode(tpPayment string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		size := defaultQRCodeSize
+
+		if value := r.URL.Query().Get("size"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 64 || parsed > maxQRCodeSize {
+				utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("size must be between 64 and %d", maxQRCodeSize))
+				return
+			}
+			size = parsed
+		}
+
+		charge, ok := h.getPixCharge(tpPayment, w, r)
+		if !ok {
+			return
+		}
+
+		png, err := qrcode.Encode(charge.DsBrCode, qrcode.Medium, size)
+
+		if err != nil {
+			utils.WriterError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "image/png")
+		w.WriteHeader(http.StatusOK)
+		w.Write(png)
+	}
+}
@@ -0,0 +1,123 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/service/auth"
+	"github.com/gfmanica/splitz-backend/service/currency"
+	"github.com/gfmanica/splitz-backend/types"
+	"github.com/gorilla/mux"
+)
+
+func TestBrCode(t *testing.T) {
+	key := "fulano@example.com"
+	city := "Curitiba"
+	brl := currency.NewConverter(types.DefaultCurrency, []types.ExchangeRate{
+		{CdFrom: "USD", CdTo: "BRL", DtRate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), VlRate: 5000000},
+	})
+
+	t.Run("should charge only the outstanding amount", func(t *testing.T) {
+		charge := &types.PixCharge{TpPayment: types.PaymentBill, IdParent: 12, IdPayment: 34, DsParent: "Mercado", DsReceiver: "Fulano",
+			DsPixKey: &key, DsPixCity: &city, VlPayment: 5000, VlPaid: 2000, VlOutstanding: 3000}
+
+		if err := brCode(charge, brl); err != nil {
+			t.Fatal(err)
+		}
+
+		if charge.DsReference != "BILL12P34" || !strings.Contains(charge.DsBrCode, "540530.00") {
+			t.Errorf("unexpected charge %+v", charge)
+		}
+	})
+
+	t.Run("should charge bills in other currencies in reais", func(t *testing.T) {
+		charge := &types.PixCharge{TpPayment: types.PaymentBill, IdParent: 1, IdPayment: 2, DsReceiver: "Fulano", DsPixKey: &key, DsPixCity: &city,
+			CdCurrency: "USD", DtParent: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), VlPayment: 1000, VlOutstanding: 1000}
+
+		if err := brCode(charge, brl); err != nil {
+			t.Fatal(err)
+		}
+
+		if charge.VlCharge != 5000 || !strings.Contains(charge.DsBrCode, "540550.00") {
+			t.Errorf("expected a charge of 50.00, got %+v", charge)
+		}
+	})
+
+	t.Run("should require the owner's pix key", func(t *testing.T) {
+		charge := &types.PixCharge{VlPayment: 5000, VlOutstanding: 5000}
+
+		if err := brCode(charge, brl); !errors.Is(err, ErrPixKeyMissing) {
+			t.Errorf("expected %v, got %v", ErrPixKeyMissing, err)
+		}
+	})
+
+	t.Run("should not charge a paid payment", func(t *testing.T) {
+		charge := &types.PixCharge{DsPixKey: &key, DsPixCity: &city, VlPayment: 5000, VlPaid: 5000}
+
+		if err := brCode(charge, brl); !errors.Is(err, ErrPaymentPaid) {
+			t.Errorf("expected %v, got %v", ErrPaymentPaid, err)
+		}
+	})
+}
+
+// mockPixStore só implementa a busca da cobrança; o restante da interface
+// fica no valor nulo embutido.
+type mockPixStore struct {
+	types.PaymentTransactionStore
+	charge types.PixCharge
+}
+
+func (m *mockPixStore) GetPixCharge(tpPayment string, idParent int, idPayment int, userId int) (*types.PixCharge, error) {
+	charge := m.charge
+
+	return &charge, nil
+}
+
+type mockRateStore struct {
+	types.ExchangeRateStore
+}
+
+func (m *mockRateStore) GetRates(userId int) ([]types.ExchangeRate, error) {
+	return nil, nil
+}
+
+func TestPixChargeHandler(t *testing.T) {
+	key := "fulano@example.com"
+	city := "Curitiba"
+
+	for _, tc := range []struct {
+		name     string
+		charge   types.PixCharge
+		expected int
+	}{
+		{"should answer 400 without the owner's pix key", types.PixCharge{VlPayment: 5000}, http.StatusBadRequest},
+		{"should answer 409 for a paid payment", types.PixCharge{DsPixKey: &key, DsPixCity: &city, VlPayment: 5000, VlPaid: 5000}, http.StatusConflict},
+		{"should answer 422 without an exchange rate", types.PixCharge{DsPixKey: &key, DsPixCity: &city, VlPayment: 5000, CdCurrency: "USD"}, http.StatusUnprocessableEntity},
+		{"should answer 200 with the charge", types.PixCharge{DsPixKey: &key, DsPixCity: &city, VlPayment: 5000}, http.StatusOK},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			charge := tc.charge
+			charge.VlOutstanding, _ = Status(charge.VlPayment, charge.VlPaid)
+
+			handler := NewHandler(&mockPixStore{charge: charge}, &mockRateStore{}, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/bill/1/payment/1/pix", nil)
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, 1))
+
+			rr := httptest.NewRecorder()
+			router := mux.NewRouter()
+
+			router.HandleFunc("/bill/{id}/payment/{idPayment}/pix", handler.handleGetPixCharge(types.PaymentBill))
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tc.expected {
+				t.Errorf("expected status %d, got %d: %s", tc.expected, rr.Code, rr.Body)
+			}
+		})
+	}
+}
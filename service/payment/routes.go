@@ -0,0 +1,141 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/service/auth"
+	"github.com/gfmanica/splitz-backend/types"
+	"github.com/gfmanica/splitz-backend/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store     types.PaymentTransactionStore
+	rateStore types.ExchangeRateStore
+	userStore types.UserStore
+}
+
+func NewHandler(store types.PaymentTransactionStore, rateStore types.ExchangeRateStore, userStore types.UserStore) *Handler {
+	return &Handler{
+		store:     store,
+		rateStore: rateStore,
+		userStore: userStore,
+	}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/bill/{id}/payment/{idPayment}/transaction", auth.WithJWTAuth(h.handleGetTransactions(types.PaymentBill), h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/bill/{id}/payment/{idPayment}/transaction", auth.WithJWTAuth(h.handleCreateTransaction(types.PaymentBill), h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/ride/{id}/payment/{idPayment}/transaction", auth.WithJWTAuth(h.handleGetTransactions(types.PaymentRide), h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/ride/{id}/payment/{idPayment}/transaction", auth.WithJWTAuth(h.handleCreateTransaction(types.PaymentRide), h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/bill/{id}/payment/{idPayment}/pix", auth.WithJWTAuth(h.handleGetPixCharge(types.PaymentBill), h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/bill/{id}/payment/{idPayment}/pix/qrcode", auth.WithJWTAuth(h.handleGetPixQRCode(types.PaymentBill), h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/ride/{id}/payment/{idPayment}/pix", auth.WithJWTAuth(h.handleGetPixCharge(types.PaymentRide), h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/ride/{id}/payment/{idPayment}/pix/qrcode", auth.WithJWTAuth(h.handleGetPixQRCode(types.PaymentRide), h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/transaction/{id}/reverse", auth.WithJWTAuth(h.handleReverseTransaction, h.userStore)).Methods(http.MethodPost)
+}
+
+func (h *Handler) handleGetTransactions(tpPayment string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+		idPayment, _ := strconv.Atoi(vars["idPayment"])
+		userId := auth.GetUserIDFromContext(r.Context())
+
+		transactions, err := h.store.GetTransactions(tpPayment, id, idPayment, userId)
+
+		if errors.Is(err, ErrPaymentNotFound) {
+			utils.WriterError(w, http.StatusNotFound, err)
+			return
+		}
+
+		if err != nil {
+			utils.WriterError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, transactions)
+	}
+}
+
+func (h *Handler) handleCreateTransaction(tpPayment string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload types.CreatePaymentTransactionPayload
+
+		if err := utils.ParseJSON(r, &payload); err != nil {
+			utils.WriterError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := utils.Validate.Struct(payload); err != nil {
+			error := err.(validator.ValidationErrors)
+			utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %s", error))
+			return
+		}
+
+		transaction := types.PaymentTransaction{
+			VlPayment: payload.VlPayment,
+			DtPayment: time.Now(),
+			TpMethod:  payload.TpMethod,
+			DsNote:    payload.DsNote,
+		}
+
+		if payload.DtPayment != nil {
+			transaction.DtPayment = *payload.DtPayment
+		}
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+		idPayment, _ := strconv.Atoi(vars["idPayment"])
+		userId := auth.GetUserIDFromContext(r.Context())
+
+		created, err := h.store.CreateTransaction(tpPayment, id, idPayment, transaction, userId)
+
+		if errors.Is(err, ErrPaymentNotFound) {
+			utils.WriterError(w, http.StatusNotFound, err)
+			return
+		}
+
+		if errors.Is(err, ErrOverpayment) {
+			utils.WriterError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err != nil {
+			utils.WriterError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusCreated, created)
+	}
+}
+
+func (h *Handler) handleReverseTransaction(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, _ := strconv.Atoi(vars["id"])
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	reversal, err := h.store.ReverseTransaction(id, userId)
+
+	if errors.Is(err, ErrTransactionNotFound) {
+		utils.WriterError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if errors.Is(err, ErrInvalidReversal) {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, reversal)
+}
@@ -0,0 +1,52 @@
+package payment
+
+import (
+	"fmt"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+// Status deriva o valor em aberto e a quitação a partir do total já pago.
+// Pagamentos acima do devido não geram valor em aberto negativo.
+func Status(vlPayment types.Money, vlPaid types.Money) (types.Money, bool) {
+	outstanding := vlPayment - vlPaid
+
+	if outstanding <= 0 {
+		return 0, true
+	}
+
+	return outstanding, false
+}
+
+// Summarize resume os pagamentos de um bill ou ride a partir do valor devido
+// e do valor pago de cada um. Sem pendências, o resumo está quitado.
+func Summarize(vlPayments []types.Money, vlPaids []types.Money) *types.PaymentSummary {
+	summary := &types.PaymentSummary{}
+
+	for i, vlPayment := range vlPayments {
+		outstanding, payed := Status(vlPayment, vlPaids[i])
+
+		summary.VlTotal += vlPayment
+		summary.VlPaid += vlPaids[i]
+		summary.VlOutstanding += outstanding
+		summary.QtPayments++
+		if payed {
+			summary.QtPayed++
+		}
+	}
+
+	summary.FgSettled = summary.QtPayed == summary.QtPayments
+
+	return summary
+}
+
+// ValidateAmount confere se uma nova transação cabe no valor em aberto.
+func ValidateAmount(vlPayment types.Money, vlPaid types.Money, amount types.Money) error {
+	outstanding, _ := Status(vlPayment, vlPaid)
+
+	if amount > outstanding {
+		return fmt.Errorf("%w: %s is greater than the outstanding %s", ErrOverpayment, amount, outstanding)
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package payment
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+func TestStatus(t *testing.T) {
+	t.Run("should keep the remainder of a partial payment outstanding", func(t *testing.T) {
+		outstanding, payed := Status(10000, 4000)
+
+		if outstanding != 6000 || payed {
+			t.Errorf("expected 60.00 outstanding and not payed, got %s and %v", outstanding, payed)
+		}
+	})
+
+	t.Run("should mark the payment as payed when fully paid", func(t *testing.T) {
+		outstanding, payed := Status(10000, 10000)
+
+		if outstanding != 0 || !payed {
+			t.Errorf("expected nothing outstanding and payed, got %s and %v", outstanding, payed)
+		}
+	})
+
+	t.Run("should reopen the payment after a reversal", func(t *testing.T) {
+		// Pagamento de 100.00 estornado
+		outstanding, payed := Status(10000, 10000-10000)
+
+		if outstanding != 10000 || payed {
+			t.Errorf("expected 100.00 outstanding and not payed, got %s and %v", outstanding, payed)
+		}
+	})
+
+	t.Run("should not go negative when the share is reduced after payment", func(t *testing.T) {
+		outstanding, payed := Status(5000, 8000)
+
+		if outstanding != 0 || !payed {
+			t.Errorf("expected nothing outstanding and payed, got %s and %v", outstanding, payed)
+		}
+	})
+}
+
+func TestValidateAmount(t *testing.T) {
+	t.Run("should accept amounts up to the outstanding value", func(t *testing.T) {
+		if err := ValidateAmount(10000, 4000, types.Money(6000)); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	t.Run("should reject amounts above the outstanding value", func(t *testing.T) {
+		err := ValidateAmount(10000, 4000, types.Money(6001))
+
+		if !errors.Is(err, ErrOverpayment) {
+			t.Errorf("expected ErrOverpayment, got %v", err)
+		}
+	})
+}
+
+func TestSummarize(t *testing.T) {
+	t.Run("should add up totals and count payed payments", func(t *testing.T) {
+		summary := Summarize([]types.Money{5000, 3000, 2000}, []types.Money{5000, 1000, 0})
+
+		if summary.VlTotal != 10000 || summary.VlPaid != 6000 || summary.VlOutstanding != 4000 {
+			t.Errorf("expected 100.00 total, 60.00 paid and 40.00 outstanding, got %+v", summary)
+		}
+
+		if summary.QtPayments != 3 || summary.QtPayed != 1 || summary.FgSettled {
+			t.Errorf("expected 1 of 3 payed and not settled, got %+v", summary)
+		}
+	})
+
+	t.Run("should be settled when every payment is payed", func(t *testing.T) {
+		summary := Summarize([]types.Money{5000, 0}, []types.Money{5000, 0})
+
+		if !summary.FgSettled {
+			t.Errorf("expected summary to be settled, got %+v", summary)
+		}
+	})
+}
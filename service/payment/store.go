@@ -0,0 +1,287 @@
+package payment
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+var (
+	ErrPaymentNotFound     = fmt.Errorf("payment not found")
+	ErrTransactionNotFound = fmt.Errorf("transaction not found")
+	ErrOverpayment         = fmt.Errorf("amount exceeds the outstanding payment")
+	ErrInvalidReversal     = fmt.Errorf("transaction cannot be reversed")
+	ErrPaymentHasHistory   = fmt.Errorf("payment has transactions and cannot be removed")
+)
+
+// paymentTable descreve onde ficam os pagamentos de bills e rides, que
+// compartilham a mesma tabela de transações.
+type paymentTable struct {
+	table        string
+	idColumn     string
+	parentTable  string
+	parentColumn string
+	descColumn   string
+	// Moeda e data usadas para converter a cobrança; rides são sempre em reais
+	currencyExpr string
+	dateExpr     string
+}
+
+var paymentTables = map[string]paymentTable{
+	types.PaymentBill: {table: "bill_payment", idColumn: "id_bill_payment", parentTable: "bill", parentColumn: "id_bill", descColumn: "ds_bill",
+		currencyExpr: "o.cd_currency", dateExpr: "o.created_at"},
+	types.PaymentRide: {table: "ride_payment", idColumn: "id_ride_payment", parentTable: "ride", parentColumn: "id_ride", descColumn: "ds_ride",
+		currencyExpr: "'" + types.DefaultCurrency + "'", dateExpr: "o.dt_init"},
+}
+
+const transactionColumns = `
+	pt.id_payment_transaction, pt.id_bill_payment, pt.id_ride_payment, pt.vl_payment, pt.dt_payment, pt.tp_method,
+	pt.ds_note, pt.id_reversed_transaction, pt.id_user, pt.created_at,
+	EXISTS (SELECT 1 FROM payment_transaction r WHERE r.id_reversed_transaction = pt.id_payment_transaction)`
+
+// CheckRemovable recusa a exclusão de um pagamento que já tem transações,
+// que seriam apagadas em cascata junto com ele. Usado dentro da transação
+// que edita o bill ou ride.
+func CheckRemovable(tx *sql.Tx, tpPayment string, idPayment int) error {
+	t, ok := paymentTables[tpPayment]
+	if !ok {
+		return ErrPaymentNotFound
+	}
+
+	var hasHistory bool
+
+	err := tx.QueryRow(fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM payment_transaction WHERE %s = $1)", t.idColumn), idPayment).Scan(&hasHistory)
+	if err != nil {
+		return err
+	}
+
+	if hasHistory {
+		return fmt.Errorf("%w: %d", ErrPaymentHasHistory, idPayment)
+	}
+
+	return nil
+}
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// GetTransactions lista o histórico de um pagamento. Qualquer participante do
+// bill ou ride pode consultar.
+func (s *Store) GetTransactions(tpPayment string, idParent int, idPayment int, userId int) ([]types.PaymentTransaction, error) {
+	t, ok := paymentTables[tpPayment]
+	if !ok {
+		return nil, ErrPaymentNotFound
+	}
+
+	var visible bool
+
+	err := s.db.QueryRow(fmt.Sprintf(`
+		SELECT EXISTS (
+			SELECT 1 FROM %[1]s p
+			INNER JOIN %[3]s o ON o.%[4]s = p.%[4]s
+			WHERE p.%[2]s = $1 AND p.%[4]s = $2
+			AND (o.id_user = $3 OR EXISTS (SELECT 1 FROM %[1]s x WHERE x.%[4]s = o.%[4]s AND x.id_user = $3))
+		)`, t.table, t.idColumn, t.parentTable, t.parentColumn), idPayment, idParent, userId).Scan(&visible)
+	if err != nil {
+		return nil, err
+	}
+
+	if !visible {
+		return nil, ErrPaymentNotFound
+	}
+
+	rows, err := s.db.Query(fmt.Sprintf("SELECT"+transactionColumns+" FROM payment_transaction pt WHERE pt.%s = $1 ORDER BY pt.id_payment_transaction ASC", t.idColumn), idPayment)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	transactions := make([]types.PaymentTransaction, 0)
+
+	for rows.Next() {
+		transaction, err := scanRowIntoTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		transactions = append(transactions, *transaction)
+	}
+
+	return transactions, nil
+}
+
+// CreateTransaction registra um pagamento parcial ou total. Só o dono do
+// registro pode registrar; quem deve não pode dar a própria parte como paga.
+func (s *Store) CreateTransaction(tpPayment string, idParent int, idPayment int, transaction types.PaymentTransaction, userId int) (*types.PaymentTransaction, error) {
+	t, ok := paymentTables[tpPayment]
+	if !ok {
+		return nil, ErrPaymentNotFound
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	// Trava o pagamento para que transações simultâneas não ultrapassem o valor devido
+	var vlPayment types.Money
+
+	err = tx.QueryRow(fmt.Sprintf(`
+		SELECT p.vl_payment
+		FROM %[1]s p
+		INNER JOIN %[3]s o ON o.%[4]s = p.%[4]s
+		WHERE p.%[2]s = $1 AND p.%[4]s = $2 AND o.id_user = $3
+		FOR UPDATE OF p`, t.table, t.idColumn, t.parentTable, t.parentColumn), idPayment, idParent, userId).Scan(&vlPayment)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		return nil, ErrPaymentNotFound
+	}
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	var vlPaid types.Money
+
+	err = tx.QueryRow(fmt.Sprintf("SELECT COALESCE(SUM(vl_payment), 0) FROM payment_transaction WHERE %s = $1", t.idColumn), idPayment).Scan(&vlPaid)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := ValidateAmount(vlPayment, vlPaid, transaction.VlPayment); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	var id int
+
+	err = tx.QueryRow(fmt.Sprintf(`
+		INSERT INTO payment_transaction (%s, vl_payment, dt_payment, tp_method, ds_note, id_user)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id_payment_transaction`, t.idColumn),
+		idPayment, transaction.VlPayment, transaction.DtPayment, transaction.TpMethod, transaction.DsNote, userId).Scan(&id)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getTransactionById(id)
+}
+
+// ReverseTransaction estorna uma transação com um lançamento de valor negativo,
+// mantendo o histórico. Só o dono do registro ou quem lançou podem estornar.
+func (s *Store) ReverseTransaction(id int, userId int) (*types.PaymentTransaction, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		idBillPayment         *int
+		idRidePayment         *int
+		vlPayment             types.Money
+		tpMethod              string
+		idReversedTransaction *int
+		fgReversed            bool
+	)
+
+	err = tx.QueryRow(`
+		SELECT pt.id_bill_payment, pt.id_ride_payment, pt.vl_payment, pt.tp_method, pt.id_reversed_transaction,
+		EXISTS (SELECT 1 FROM payment_transaction r WHERE r.id_reversed_transaction = pt.id_payment_transaction)
+		FROM payment_transaction pt
+		LEFT JOIN bill_payment bp ON bp.id_bill_payment = pt.id_bill_payment
+		LEFT JOIN bill b ON b.id_bill = bp.id_bill
+		LEFT JOIN ride_payment rp ON rp.id_ride_payment = pt.id_ride_payment
+		LEFT JOIN ride r ON r.id_ride = rp.id_ride
+		WHERE pt.id_payment_transaction = $1
+		AND (pt.id_user = $2 OR b.id_user = $2 OR r.id_user = $2)
+		FOR UPDATE OF pt`, id, userId).Scan(&idBillPayment, &idRidePayment, &vlPayment, &tpMethod, &idReversedTransaction, &fgReversed)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		return nil, ErrTransactionNotFound
+	}
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if idReversedTransaction != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("%w: it is already a reversal", ErrInvalidReversal)
+	}
+
+	if fgReversed {
+		tx.Rollback()
+		return nil, fmt.Errorf("%w: it was already reversed", ErrInvalidReversal)
+	}
+
+	var reversalId int
+
+	err = tx.QueryRow(`
+		INSERT INTO payment_transaction (id_bill_payment, id_ride_payment, vl_payment, dt_payment, tp_method, id_reversed_transaction, id_user)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id_payment_transaction`,
+		idBillPayment, idRidePayment, -vlPayment, time.Now(), tpMethod, id, userId).Scan(&reversalId)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getTransactionById(reversalId)
+}
+
+func (s *Store) getTransactionById(id int) (*types.PaymentTransaction, error) {
+	rows, err := s.db.Query("SELECT"+transactionColumns+" FROM payment_transaction pt WHERE pt.id_payment_transaction = $1", id)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, ErrTransactionNotFound
+	}
+
+	return scanRowIntoTransaction(rows)
+}
+
+func scanRowIntoTransaction(rows *sql.Rows) (*types.PaymentTransaction, error) {
+	transaction := &types.PaymentTransaction{}
+
+	err := rows.Scan(
+		&transaction.IdPaymentTransaction,
+		&transaction.IdBillPayment,
+		&transaction.IdRidePayment,
+		&transaction.VlPayment,
+		&transaction.DtPayment,
+		&transaction.TpMethod,
+		&transaction.DsNote,
+		&transaction.IdReversedTransaction,
+		&transaction.IdUser,
+		&transaction.CreatedAt,
+		&transaction.FgReversed,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
@@ -0,0 +1,116 @@
+package pix
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/gfmanica/splitz-backend/types"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Charge são os dados de uma cobrança Pix estática com valor fixo.
+type Charge struct {
+	Key         string
+	Receiver    string
+	City        string
+	Amount      types.Money
+	Reference   string
+	Description string
+}
+
+// BRCode monta o payload EMV (padrão BR Code do Banco Central) usado tanto
+// no "Pix copia e cola" quanto no QR code.
+func BRCode(charge Charge) string {
+	description := ascii(charge.Description)
+
+	// O campo 26 tem no máximo 99 caracteres; a descrição é o que sobra
+	room := 99 - len(field("00", "br.gov.bcb.pix")) - len(field("01", charge.Key)) - 4
+	if len(description) > room {
+		description = strings.TrimSpace(description[:max(room, 0)])
+	}
+
+	account := field("00", "br.gov.bcb.pix") + field("01", charge.Key)
+	if description != "" {
+		account += field("02", description)
+	}
+
+	reference := txid(charge.Reference)
+
+	payload := field("00", "01") +
+		field("26", account) +
+		field("52", "0000") +
+		field("53", "986") +
+		field("54", charge.Amount.String()) +
+		field("58", "BR") +
+		field("59", truncate(ascii(charge.Receiver), 25)) +
+		field("60", truncate(ascii(charge.City), 15)) +
+		field("62", field("05", reference)) +
+		"6304"
+
+	return payload + fmt.Sprintf("%04X", crc16(payload))
+}
+
+func field(id string, value string) string {
+	return fmt.Sprintf("%s%02d%s", id, len(value), value)
+}
+
+// txid só aceita letras e dígitos, até 25 caracteres. Sem referência o
+// padrão usa "***".
+func txid(reference string) string {
+	id := strings.Map(func(r rune) rune {
+		if r < 128 && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return r
+		}
+		return -1
+	}, ascii(reference))
+
+	if id == "" {
+		return "***"
+	}
+
+	return truncate(id, 25)
+}
+
+// ascii remove acentos e descarta o que não for ASCII imprimível, já que
+// nem todos os bancos leem UTF-8 no BR Code.
+func ascii(text string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	text, _, _ = transform.String(t, text)
+
+	return strings.TrimSpace(strings.Map(func(r rune) rune {
+		if r < 32 || r > 126 {
+			return -1
+		}
+		return r
+	}, text))
+}
+
+func truncate(text string, size int) string {
+	if len(text) > size {
+		return strings.TrimSpace(text[:size])
+	}
+
+	return text
+}
+
+// crc16 é o CRC16-CCITT (polinômio 0x1021, valor inicial 0xFFFF) exigido
+// no campo 63.
+func crc16(payload string) uint16 {
+	crc := uint16(0xFFFF)
+
+	for i := 0; i < len(payload); i++ {
+		crc ^= uint16(payload[i]) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+
+	return crc
+}
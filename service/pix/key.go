@@ -0,0 +1,101 @@
+package pix
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var ErrInvalidKey = fmt.Errorf("invalid pix key")
+
+var (
+	phoneKey  = regexp.MustCompile(`^\+[1-9][0-9]{10,13}$`)
+	emailKey  = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+	randomKey = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+)
+
+// NormalizeKey valida a chave Pix e a devolve no formato usado no BR Code:
+// CPF e CNPJ só com dígitos, telefone como +55..., e-mail e chave aleatória
+// em minúsculas. Telefones precisam do +55 para não serem lidos como CPF.
+func NormalizeKey(key string) (string, error) {
+	key = strings.TrimSpace(key)
+
+	switch {
+	case strings.HasPrefix(key, "+"):
+		phone := strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(key)
+		if phoneKey.MatchString(phone) {
+			return phone, nil
+		}
+	case strings.Contains(key, "@"):
+		email := strings.ToLower(key)
+		if len(email) <= 77 && emailKey.MatchString(email) {
+			return email, nil
+		}
+	case randomKey.MatchString(strings.ToLower(key)):
+		return strings.ToLower(key), nil
+	default:
+		document := strings.NewReplacer(".", "", "-", "", "/", "", " ", "").Replace(key)
+		if (len(document) == 11 && validCPF(document)) || (len(document) == 14 && validCNPJ(document)) {
+			return document, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+}
+
+func digits(document string) ([]int, bool) {
+	values := make([]int, len(document))
+
+	for i, c := range document {
+		if c < '0' || c > '9' {
+			return nil, false
+		}
+		values[i] = int(c - '0')
+	}
+
+	return values, true
+}
+
+// checkDigit calcula o dígito verificador módulo 11 de CPF e CNPJ.
+func checkDigit(values []int, weights []int) int {
+	sum := 0
+	for i, weight := range weights {
+		sum += values[i] * weight
+	}
+
+	if rest := sum % 11; rest >= 2 {
+		return 11 - rest
+	}
+
+	return 0
+}
+
+func repeated(values []int) bool {
+	for _, v := range values {
+		if v != values[0] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func validCPF(document string) bool {
+	values, ok := digits(document)
+	if !ok || repeated(values) {
+		return false
+	}
+
+	return checkDigit(values, []int{10, 9, 8, 7, 6, 5, 4, 3, 2}) == values[9] &&
+		checkDigit(values, []int{11, 10, 9, 8, 7, 6, 5, 4, 3, 2}) == values[10]
+}
+
+func validCNPJ(document string) bool {
+	values, ok := digits(document)
+	if !ok || repeated(values) {
+		return false
+	}
+
+	return checkDigit(values, []int{5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2}) == values[12] &&
+		checkDigit(values, []int{6, 5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2}) == values[13]
+}
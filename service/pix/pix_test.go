@@ -0,0 +1,84 @@
+package pix
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBRCode(t *testing.T) {
+	t.Run("should match the checksum of the central bank example", func(t *testing.T) {
+		example := "00020126580014br.gov.bcb.pix0136123e4567-e12b-12d1-a456-4266554400005204000053039865802BR5913Fulano de Tal6008BRASILIA62070503***6304"
+
+		if crc := crc16(example); crc != 0x1D3D {
+			t.Errorf("expected crc 1D3D, got %04X", crc)
+		}
+	})
+
+	t.Run("should encode the amount, receiver and reference", func(t *testing.T) {
+		code := BRCode(Charge{
+			Key:         "fulano@example.com",
+			Receiver:    "José da Conceição",
+			City:        "São José dos Pinhais",
+			Amount:      12345,
+			Reference:   "BILL12P34",
+			Description: "Mercado",
+		})
+
+		for _, expected := range []string{
+			"0014br.gov.bcb.pix0118fulano@example.com0207Mercado",
+			"5406123.45",
+			"5917Jose da Conceicao",
+			"6015Sao Jose dos Pi",
+			"62130509BILL12P34",
+		} {
+			if !strings.Contains(code, expected) {
+				t.Errorf("expected %s in %s", expected, code)
+			}
+		}
+
+		if !strings.HasSuffix(code[:len(code)-4], "6304") || code[len(code)-4:] != strings.ToUpper(code[len(code)-4:]) {
+			t.Errorf("expected the code to end with the crc, got %s", code)
+		}
+	})
+
+	t.Run("should keep the account field within 99 characters", func(t *testing.T) {
+		code := BRCode(Charge{
+			Key:         "123e4567-e12b-12d1-a456-426655440000",
+			Receiver:    "Fulano",
+			City:        "Brasilia",
+			Amount:      100,
+			Description: strings.Repeat("descrição longa ", 10),
+		})
+
+		if !strings.HasPrefix(code, "00020126") || code[8:10] != "99" {
+			t.Errorf("expected the account field to be truncated to 99 characters, got %s", code)
+		}
+	})
+}
+
+func TestNormalizeKey(t *testing.T) {
+	valid := map[string]string{
+		"529.982.247-25":                       "52998224725",
+		"11.222.333/0001-81":                   "11222333000181",
+		"+55 (11) 98765-4321":                  "+5511987654321",
+		"Fulano@Example.com":                   "fulano@example.com",
+		"123E4567-E12B-12D1-A456-426655440000": "123e4567-e12b-12d1-a456-426655440000",
+	}
+
+	for key, expected := range valid {
+		t.Run("should accept "+key, func(t *testing.T) {
+			normalized, err := NormalizeKey(key)
+			if err != nil || normalized != expected {
+				t.Errorf("expected %s, got %s (%v)", expected, normalized, err)
+			}
+		})
+	}
+
+	for _, key := range []string{"11987654321", "111.111.111-11", "fulano@", "+55", ""} {
+		t.Run("should reject "+key, func(t *testing.T) {
+			if _, err := NormalizeKey(key); err == nil {
+				t.Errorf("expected %q to be rejected", key)
+			}
+		})
+	}
+}
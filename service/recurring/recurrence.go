@@ -0,0 +1,95 @@
+package recurring
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+var ErrInvalidRecurrence = fmt.Errorf("invalid recurrence")
+
+// ValidateRecurrence confere o dia (e o mês) da regra: dia do mês para as
+// recorrências mensais e anuais, dia da semana (0 é domingo) para as semanais.
+func ValidateRecurrence(r types.RecurringBill) error {
+	switch r.TpRecurrence {
+	case types.RecurrenceMonthly:
+		if r.NrDay < 1 || r.NrDay > 31 {
+			return fmt.Errorf("%w: monthly bills need a day between 1 and 31", ErrInvalidRecurrence)
+		}
+	case types.RecurrenceWeekly:
+		if r.NrDay < 0 || r.NrDay > 6 {
+			return fmt.Errorf("%w: weekly bills need a weekday between 0 (sunday) and 6 (saturday)", ErrInvalidRecurrence)
+		}
+	case types.RecurrenceYearly:
+		if r.NrMonth < 1 || r.NrMonth > 12 || r.NrDay < 1 || r.NrDay > 31 {
+			return fmt.Errorf("%w: yearly bills need a month between 1 and 12 and a day between 1 and 31", ErrInvalidRecurrence)
+		}
+	default:
+		return fmt.Errorf("%w: unknown recurrence %q", ErrInvalidRecurrence, r.TpRecurrence)
+	}
+
+	if r.DtFinish != nil && r.DtFinish.Before(r.DtStart) {
+		return fmt.Errorf("%w: dtFinish is before dtStart", ErrInvalidRecurrence)
+	}
+
+	return nil
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// dayInMonth limita o dia ao último dia do mês, então o dia 31 cai em 28/02.
+func dayInMonth(year int, month time.Month, day int) time.Time {
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+
+	return time.Date(year, month, min(day, last), 0, 0, 0, 0, time.UTC)
+}
+
+// PendingPeriods retorna os períodos ainda não gerados até a data informada,
+// incluindo os perdidos enquanto o servidor esteve fora do ar.
+func PendingPeriods(r types.RecurringBill, until time.Time) []time.Time {
+	from := truncateDay(r.DtStart)
+
+	if r.DtLastPeriod != nil {
+		from = truncateDay(*r.DtLastPeriod).AddDate(0, 0, 1)
+	}
+
+	to := truncateDay(until)
+
+	if r.DtFinish != nil && truncateDay(*r.DtFinish).Before(to) {
+		to = truncateDay(*r.DtFinish)
+	}
+
+	periods := make([]time.Time, 0)
+
+	if to.Before(from) {
+		return periods
+	}
+
+	add := func(period time.Time) {
+		if !period.Before(from) && !period.After(to) {
+			periods = append(periods, period)
+		}
+	}
+
+	switch r.TpRecurrence {
+	case types.RecurrenceMonthly:
+		for month := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC); !month.After(to); month = month.AddDate(0, 1, 0) {
+			add(dayInMonth(month.Year(), month.Month(), r.NrDay))
+		}
+	case types.RecurrenceWeekly:
+		offset := (r.NrDay - int(from.Weekday()) + 7) % 7
+
+		for day := from.AddDate(0, 0, offset); !day.After(to); day = day.AddDate(0, 0, 7) {
+			add(day)
+		}
+	case types.RecurrenceYearly:
+		for year := from.Year(); year <= to.Year(); year++ {
+			add(dayInMonth(year, time.Month(r.NrMonth), r.NrDay))
+		}
+	}
+
+	return periods
+}
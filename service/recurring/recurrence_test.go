@@ -0,0 +1,340 @@
+package recurring
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestPendingPeriods(t *testing.T) {
+	t.Run("should clamp monthly bills to the last day of the month", func(t *testing.T) {
+		periods := PendingPeriods(types.RecurringBill{
+			TpRecurrence: types.RecurrenceMonthly,
+			NrDay:        31,
+			DtStart:      date(2026, time.January, 1),
+		}, date(2026, time.March, 31))
+
+		expected := []time.Time{date(2026, time.January, 31), date(2026, time.February, 28), date(2026, time.March, 31)}
+
+		if len(periods) != len(expected) {
+			t.Fatalf("expected %d periods, got %v", len(expected), periods)
+		}
+
+		for i, period := range periods {
+			if !period.Equal(expected[i]) {
+				t.Errorf("expected period %d to be %s, got %s", i, expected[i], period)
+			}
+		}
+	})
+
+	t.Run("should catch up on periods missed after the last generated one", func(t *testing.T) {
+		last := date(2026, time.February, 10)
+
+		periods := PendingPeriods(types.RecurringBill{
+			TpRecurrence: types.RecurrenceMonthly,
+			NrDay:        10,
+			DtStart:      date(2026, time.January, 1),
+			DtLastPeriod: &last,
+		}, date(2026, time.May, 9))
+
+		expected := []time.Time{date(2026, time.March, 10), date(2026, time.April, 10)}
+
+		if len(periods) != len(expected) {
+			t.Fatalf("expected %d periods, got %v", len(expected), periods)
+		}
+
+		for i, period := range periods {
+			if !period.Equal(expected[i]) {
+				t.Errorf("expected period %d to be %s, got %s", i, expected[i], period)
+			}
+		}
+	})
+
+	t.Run("should generate weekly bills on the given weekday", func(t *testing.T) {
+		// 01/10/2026 é uma quinta-feira
+		periods := PendingPeriods(types.RecurringBill{
+			TpRecurrence: types.RecurrenceWeekly,
+			NrDay:        int(time.Monday),
+			DtStart:      date(2026, time.October, 1),
+		}, date(2026, time.October, 18))
+
+		expected := []time.Time{date(2026, time.October, 5), date(2026, time.October, 12)}
+
+		if len(periods) != len(expected) {
+			t.Fatalf("expected %d periods, got %v", len(expected), periods)
+		}
+
+		for i, period := range periods {
+			if !period.Equal(expected[i]) {
+				t.Errorf("expected period %d to be %s, got %s", i, expected[i], period)
+			}
+		}
+	})
+
+	t.Run("should stop at the finish date", func(t *testing.T) {
+		finish := date(2025, time.December, 31)
+
+		periods := PendingPeriods(types.RecurringBill{
+			TpRecurrence: types.RecurrenceYearly,
+			NrDay:        15,
+			NrMonth:      3,
+			DtStart:      date(2024, time.January, 1),
+			DtFinish:     &finish,
+		}, date(2026, time.October, 18))
+
+		if len(periods) != 2 || !periods[0].Equal(date(2024, time.March, 15)) || !periods[1].Equal(date(2025, time.March, 15)) {
+			t.Errorf("expected 2024 and 2025 periods, got %v", periods)
+		}
+	})
+}
+
+func newRentStore() *mockRecurringBillStore {
+	return &mockRecurringBillStore{
+		recurringBills: []types.RecurringBill{{
+			IdRecurringBill: 1,
+			IdUser:          7,
+			DsBill:          "Aluguel",
+			VlBill:          150000,
+			QtPerson:        2,
+			TpSplit:         types.SplitEqual,
+			TpRecurrence:    types.RecurrenceMonthly,
+			NrDay:           5,
+			DtStart:         date(2026, time.August, 1),
+			FgActive:        true,
+		}},
+		periods: map[string]int{},
+		fail:    map[string]bool{},
+	}
+}
+
+func TestSchedulerRunOnce(t *testing.T) {
+	t.Run("should create each period once and catch up on missed ones", func(t *testing.T) {
+		store := newRentStore()
+		scheduler := NewScheduler(store, time.Hour)
+		scheduler.now = func() time.Time { return date(2026, time.October, 18) }
+
+		if err := scheduler.RunOnce(); err != nil {
+			t.Fatal(err)
+		}
+
+		// O período anterior ainda não foi gravado em DtLastPeriod, mas
+		// a reserva impede que o mesmo bill seja criado de novo
+		if err := scheduler.RunOnce(); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(store.bills) != 3 {
+			t.Fatalf("expected 3 bills, got %d", len(store.bills))
+		}
+
+		if store.bills[0].DsBill != "Aluguel - 05/08/2026" {
+			t.Errorf("unexpected bill description %q", store.bills[0].DsBill)
+		}
+
+		for _, idBill := range store.periods {
+			if idBill == 0 {
+				t.Errorf("expected every period to be linked to its bill")
+			}
+		}
+	})
+
+	t.Run("should keep generating the periods after a failed one", func(t *testing.T) {
+		store := newRentStore()
+		store.fail[periodKey(1, date(2026, time.September, 5))] = true
+
+		scheduler := NewScheduler(store, time.Hour)
+		scheduler.now = func() time.Time { return date(2026, time.October, 18) }
+
+		if err := scheduler.RunOnce(); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, ok := store.periods[periodKey(1, date(2026, time.September, 5))]; ok {
+			t.Fatalf("expected the failed period not to stay claimed")
+		}
+
+		if len(store.bills) != 2 {
+			t.Fatalf("expected the August and October bills, got %d", len(store.bills))
+		}
+
+		if store.bills[1].DsBill != "Aluguel - 05/10/2026" {
+			t.Errorf("expected the October bill after the failed period, got %q", store.bills[1].DsBill)
+		}
+	})
+
+	t.Run("should retry the last period whose bill failed to be created", func(t *testing.T) {
+		store := newRentStore()
+		store.fail[periodKey(1, date(2026, time.October, 5))] = true
+
+		scheduler := NewScheduler(store, time.Hour)
+		scheduler.now = func() time.Time { return date(2026, time.October, 18) }
+
+		if err := scheduler.RunOnce(); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(store.bills) != 2 {
+			t.Fatalf("expected the August and September bills, got %d", len(store.bills))
+		}
+
+		store.fail = map[string]bool{}
+
+		if err := scheduler.RunOnce(); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(store.bills) != 3 {
+			t.Fatalf("expected 3 bills after the retry, got %d", len(store.bills))
+		}
+
+		if store.bills[2].DsBill != "Aluguel - 05/10/2026" {
+			t.Errorf("expected the October bill to be generated on retry, got %q", store.bills[2].DsBill)
+		}
+	})
+
+	t.Run("should fall back to the default interval", func(t *testing.T) {
+		for _, interval := range []time.Duration{0, -time.Second} {
+			if scheduler := NewScheduler(newRentStore(), interval); scheduler.interval != defaultInterval {
+				t.Errorf("expected %s for interval %s, got %s", defaultInterval, interval, scheduler.interval)
+			}
+		}
+	})
+}
+
+func TestRecurringBillHandlers(t *testing.T) {
+	payload := types.CreateRecurringBillPayload{
+		DsBill:       "Aluguel",
+		VlBill:       150000,
+		QtPerson:     2,
+		TpSplit:      types.SplitPercentage,
+		TpRecurrence: types.RecurrenceMonthly,
+		NrDay:        5,
+		DtStart:      date(2026, time.August, 1),
+		Payments: []types.RecurringBillPayment{
+			{DsPerson: "Ana", PcPayment: 6000},
+			{DsPerson: "Bruno", PcPayment: 3000},
+		},
+	}
+
+	t.Run("should reject a template whose split would never create a bill", func(t *testing.T) {
+		handler := NewHandler(newRentStore(), nil, nil)
+
+		marshalled, _ := json.Marshal(payload)
+		rr := httptest.NewRecorder()
+
+		handler.handleCreateRecurringBill(rr, httptest.NewRequest(http.MethodPost, "/recurring-bill", bytes.NewBuffer(marshalled)))
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("should reject the same split when updating a template", func(t *testing.T) {
+		handler := NewHandler(newRentStore(), nil, nil)
+
+		marshalled, _ := json.Marshal(types.RecurringBill{
+			IdRecurringBill: 1,
+			DsBill:          payload.DsBill,
+			VlBill:          payload.VlBill,
+			QtPerson:        payload.QtPerson,
+			TpSplit:         payload.TpSplit,
+			TpRecurrence:    payload.TpRecurrence,
+			NrDay:           payload.NrDay,
+			DtStart:         payload.DtStart,
+			Payments:        payload.Payments,
+		})
+		rr := httptest.NewRecorder()
+
+		handler.handleUpdateRecurringBill(rr, httptest.NewRequest(http.MethodPut, "/recurring-bill", bytes.NewBuffer(marshalled)))
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("should create a template with a valid split", func(t *testing.T) {
+		handler := NewHandler(newRentStore(), nil, nil)
+
+		valid := payload
+		valid.Payments = []types.RecurringBillPayment{
+			{DsPerson: "Ana", PcPayment: 6000},
+			{DsPerson: "Bruno", PcPayment: 4000},
+		}
+
+		marshalled, _ := json.Marshal(valid)
+		rr := httptest.NewRecorder()
+
+		handler.handleCreateRecurringBill(rr, httptest.NewRequest(http.MethodPost, "/recurring-bill", bytes.NewBuffer(marshalled)))
+
+		if rr.Code != http.StatusCreated {
+			t.Errorf("expected status %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+		}
+	})
+}
+
+type mockRecurringBillStore struct {
+	recurringBills []types.RecurringBill
+	periods        map[string]int
+	bills          []types.Bill
+	fail           map[string]bool
+}
+
+func periodKey(id int, period time.Time) string {
+	return fmt.Sprintf("%d-%s", id, period.Format("2006-01-02"))
+}
+
+func (m *mockRecurringBillStore) GetRecurringBills(userId int) ([]types.RecurringBill, error) {
+	return m.recurringBills, nil
+}
+
+func (m *mockRecurringBillStore) GetRecurringBillById(id int, userId int) (*types.RecurringBill, error) {
+	return nil, ErrRecurringBillNotFound
+}
+
+func (m *mockRecurringBillStore) CreateRecurringBill(r types.RecurringBill, userId int) (*types.RecurringBill, error) {
+	return &r, nil
+}
+
+func (m *mockRecurringBillStore) UpdateRecurringBill(r types.RecurringBill, userId int) error {
+	return nil
+}
+
+func (m *mockRecurringBillStore) DeleteRecurringBill(id int, userId int) error {
+	return nil
+}
+
+func (m *mockRecurringBillStore) GetActiveRecurringBills() ([]types.RecurringBill, error) {
+	return m.recurringBills, nil
+}
+
+// GeneratePeriod imita a transação do banco: a reserva só fica gravada
+// quando o bill é criado.
+func (m *mockRecurringBillStore) GeneratePeriod(id int, period time.Time, b types.Bill, userId int) (bool, error) {
+	key := periodKey(id, period)
+
+	if _, ok := m.periods[key]; ok {
+		return false, nil
+	}
+
+	if m.fail[key] {
+		return false, errors.New("bill creation failed")
+	}
+
+	b.IdBill = len(m.bills) + 1
+	b.IdUser = userId
+	m.bills = append(m.bills, b)
+	m.periods[key] = b.IdBill
+
+	return true, nil
+}
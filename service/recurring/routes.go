@@ -0,0 +1,228 @@
+package recurring
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gfmanica/splitz-backend/service/auth"
+	"github.com/gfmanica/splitz-backend/service/bill"
+	"github.com/gfmanica/splitz-backend/service/group"
+	"github.com/gfmanica/splitz-backend/types"
+	"github.com/gfmanica/splitz-backend/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store      types.RecurringBillStore
+	userStore  types.UserStore
+	groupStore types.GroupStore
+}
+
+func NewHandler(store types.RecurringBillStore, userStore types.UserStore, groupStore types.GroupStore) *Handler {
+	return &Handler{
+		store:      store,
+		userStore:  userStore,
+		groupStore: groupStore,
+	}
+}
+
+// resolveParticipants valida os participantes padrão vinculados a usuários e
+// preenche o nome da pessoa quando ele não foi informado.
+func (h *Handler) resolveParticipants(payments []types.RecurringBillPayment, userId int) error {
+	for i, payment := range payments {
+		if payment.IdUser == nil {
+			continue
+		}
+
+		participant, err := group.CheckParticipant(h.groupStore, h.userStore, userId, *payment.IdUser)
+
+		if err != nil {
+			return err
+		}
+
+		if payment.DsPerson == "" {
+			payments[i].DsPerson = participant.Name
+		}
+	}
+
+	return nil
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/recurring-bill", auth.WithJWTAuth(h.handleGetRecurringBills, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/recurring-bill", auth.WithJWTAuth(h.handleCreateRecurringBill, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/recurring-bill", auth.WithJWTAuth(h.handleUpdateRecurringBill, h.userStore)).Methods(http.MethodPut)
+	router.HandleFunc("/recurring-bill/{id}", auth.WithJWTAuth(h.handleGetRecurringBill, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/recurring-bill/{id}", auth.WithJWTAuth(h.handleDeleteRecurringBill, h.userStore)).Methods(http.MethodDelete)
+}
+
+func (h *Handler) handleGetRecurringBills(w http.ResponseWriter, r *http.Request) {
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	recurringBills, err := h.store.GetRecurringBills(userId)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, recurringBills)
+}
+
+func (h *Handler) handleGetRecurringBill(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, _ := strconv.Atoi(vars["id"])
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	recurringBill, err := h.store.GetRecurringBillById(id, userId)
+
+	if errors.Is(err, ErrRecurringBillNotFound) {
+		utils.WriterError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, recurringBill)
+}
+
+func (h *Handler) handleCreateRecurringBill(w http.ResponseWriter, r *http.Request) {
+	var payload types.CreateRecurringBillPayload
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		error := err.(validator.ValidationErrors)
+		utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %s", error))
+		return
+	}
+
+	recurringBill := types.RecurringBill{
+		DsBill:       payload.DsBill,
+		VlBill:       payload.VlBill,
+		QtPerson:     payload.QtPerson,
+		TpSplit:      payload.TpSplit,
+		TpRecurrence: payload.TpRecurrence,
+		NrDay:        payload.NrDay,
+		NrMonth:      payload.NrMonth,
+		DtStart:      payload.DtStart,
+		DtFinish:     payload.DtFinish,
+		Payments:     payload.Payments,
+	}
+
+	if recurringBill.TpSplit == "" {
+		recurringBill.TpSplit = types.SplitEqual
+	}
+
+	if err := ValidateRecurrence(recurringBill); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// A divisão é conferida agora, já que um modelo inválido só falharia ao gerar os bills
+	if err := bill.ValidateBill(billFromRecurring(recurringBill, recurringBill.DtStart)); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	if err := h.resolveParticipants(recurringBill.Payments, userId); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	created, err := h.store.CreateRecurringBill(recurringBill, userId)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, created)
+}
+
+func (h *Handler) handleUpdateRecurringBill(w http.ResponseWriter, r *http.Request) {
+	var payload types.RecurringBill
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		error := err.(validator.ValidationErrors)
+		utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %s", error))
+		return
+	}
+
+	if payload.TpSplit == "" {
+		payload.TpSplit = types.SplitEqual
+	}
+
+	if err := ValidateRecurrence(payload); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// A divisão é conferida agora, já que um modelo inválido só falharia ao gerar os bills
+	if err := bill.ValidateBill(billFromRecurring(payload, payload.DtStart)); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	if err := h.resolveParticipants(payload.Payments, userId); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.store.UpdateRecurringBill(payload, userId); err != nil {
+		if errors.Is(err, ErrRecurringBillNotFound) {
+			utils.WriterError(w, http.StatusNotFound, err)
+			return
+		}
+
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	updated, err := h.store.GetRecurringBillById(payload.IdRecurringBill, userId)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, updated)
+}
+
+func (h *Handler) handleDeleteRecurringBill(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, _ := strconv.Atoi(vars["id"])
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	err := h.store.DeleteRecurringBill(id, userId)
+
+	if errors.Is(err, ErrRecurringBillNotFound) {
+		utils.WriterError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
@@ -0,0 +1,98 @@
+package recurring
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+// defaultInterval é usado quando o intervalo configurado não é positivo, que
+// faria o time.NewTicker entrar em pânico.
+const defaultInterval = time.Hour
+
+type Scheduler struct {
+	store    types.RecurringBillStore
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewScheduler(store types.RecurringBillStore, interval time.Duration) *Scheduler {
+	if interval <= 0 {
+		log.Printf("Invalid recurring bill interval %s, using %s", interval, defaultInterval)
+		interval = defaultInterval
+	}
+
+	return &Scheduler{
+		store:    store,
+		interval: interval,
+		now:      time.Now,
+	}
+}
+
+// Start gera os bills pendentes imediatamente, recuperando os períodos
+// perdidos enquanto a API esteve parada, e depois a cada intervalo.
+func (s *Scheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.RunOnce(); err != nil {
+			log.Printf("Error generating recurring bills: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) RunOnce() error {
+	recurringBills, err := s.store.GetActiveRecurringBills()
+	if err != nil {
+		return err
+	}
+
+	for _, r := range recurringBills {
+		s.generate(r)
+	}
+
+	return nil
+}
+
+func (s *Scheduler) generate(r types.RecurringBill) {
+	for _, period := range PendingPeriods(r, s.now().UTC()) {
+		// Um período já reservado por outra instância é apenas ignorado. Um
+		// período com erro vai para o log e é pulado para não travar os
+		// seguintes; ele só é tentado de novo se nenhum posterior for gerado
+		if _, err := s.store.GeneratePeriod(r.IdRecurringBill, period, billFromRecurring(r, period), r.IdUser); err != nil {
+			log.Printf("Error generating period %s of recurring bill %d: %v", period.Format("2006-01-02"), r.IdRecurringBill, err)
+		}
+	}
+}
+
+func billFromRecurring(r types.RecurringBill, period time.Time) types.Bill {
+	payments := make([]types.BillPayment, len(r.Payments))
+
+	for i, p := range r.Payments {
+		payments[i] = types.BillPayment{
+			DsPerson:  p.DsPerson,
+			IdUser:    p.IdUser,
+			VlPayment: p.VlPayment,
+			QtShare:   p.QtShare,
+			PcPayment: p.PcPayment,
+		}
+	}
+
+	return types.Bill{
+		DsBill:   fmt.Sprintf("%s - %s", r.DsBill, period.Format("02/01/2006")),
+		VlBill:   r.VlBill,
+		QtPerson: r.QtPerson,
+		TpSplit:  r.TpSplit,
+		Payments: payments,
+	}
+}
@@ -0,0 +1,277 @@
+package recurring
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/service/bill"
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+var ErrRecurringBillNotFound = fmt.Errorf("recurring bill not found")
+
+const recurringBillColumns = `
+	rb.id_recurring_bill, rb.id_user, rb.ds_bill, rb.vl_bill, rb.qt_person, rb.tp_split,
+	rb.tp_recurrence, rb.nr_day, rb.nr_month, rb.dt_start, rb.dt_finish, rb.fg_active,
+	(SELECT MAX(p.dt_period) FROM recurring_bill_period p WHERE p.id_recurring_bill = rb.id_recurring_bill)`
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) GetRecurringBills(userId int) ([]types.RecurringBill, error) {
+	return s.queryRecurringBills("SELECT"+recurringBillColumns+" FROM recurring_bill rb WHERE rb.id_user = $1 ORDER BY rb.id_recurring_bill DESC", userId)
+}
+
+func (s *Store) GetRecurringBillById(id int, userId int) (*types.RecurringBill, error) {
+	recurringBills, err := s.queryRecurringBills("SELECT"+recurringBillColumns+" FROM recurring_bill rb WHERE rb.id_recurring_bill = $1 AND rb.id_user = $2", id, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(recurringBills) == 0 {
+		return nil, ErrRecurringBillNotFound
+	}
+
+	return &recurringBills[0], nil
+}
+
+// GetActiveRecurringBills retorna os modelos de todos os usuários que ainda
+// podem gerar bills, usado pelo agendador.
+func (s *Store) GetActiveRecurringBills() ([]types.RecurringBill, error) {
+	return s.queryRecurringBills("SELECT" + recurringBillColumns + " FROM recurring_bill rb WHERE rb.fg_active = TRUE ORDER BY rb.id_recurring_bill ASC")
+}
+
+func (s *Store) CreateRecurringBill(r types.RecurringBill, userId int) (*types.RecurringBill, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	var id int
+
+	err = tx.QueryRow(`
+		INSERT INTO recurring_bill (ds_bill, vl_bill, qt_person, tp_split, tp_recurrence, nr_day, nr_month, dt_start, dt_finish, fg_active, id_user)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, TRUE, $10) RETURNING id_recurring_bill`,
+		r.DsBill, r.VlBill, r.QtPerson, r.TpSplit, r.TpRecurrence, r.NrDay, r.NrMonth, r.DtStart, r.DtFinish, userId,
+	).Scan(&id)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := insertPayments(tx, id, r.Payments); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetRecurringBillById(id, userId)
+}
+
+func (s *Store) UpdateRecurringBill(r types.RecurringBill, userId int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`
+		UPDATE recurring_bill SET ds_bill = $1, vl_bill = $2, qt_person = $3, tp_split = $4, tp_recurrence = $5,
+		nr_day = $6, nr_month = $7, dt_start = $8, dt_finish = $9, fg_active = $10
+		WHERE id_recurring_bill = $11 AND id_user = $12`,
+		r.DsBill, r.VlBill, r.QtPerson, r.TpSplit, r.TpRecurrence, r.NrDay, r.NrMonth, r.DtStart, r.DtFinish, r.FgActive, r.IdRecurringBill, userId)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if affected == 0 {
+		tx.Rollback()
+		return ErrRecurringBillNotFound
+	}
+
+	// Os participantes padrão são regravados a cada atualização
+	_, err = tx.Exec("DELETE FROM recurring_bill_payment WHERE id_recurring_bill = $1", r.IdRecurringBill)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := insertPayments(tx, r.IdRecurringBill, r.Payments); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Store) DeleteRecurringBill(id int, userId int) error {
+	result, err := s.db.Exec("DELETE FROM recurring_bill WHERE id_recurring_bill = $1 AND id_user = $2", id, userId)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrRecurringBillNotFound
+	}
+
+	return nil
+}
+
+// GeneratePeriod reserva o período e cria o bill dele na mesma transação.
+// A chave primária garante que cada período seja gerado uma única vez, mesmo
+// com várias instâncias da API rodando o agendador, e uma falha ao criar o
+// bill desfaz também a reserva, então o período nunca fica marcado sem bill.
+func (s *Store) GeneratePeriod(id int, period time.Time, b types.Bill, userId int) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	result, err := tx.Exec("INSERT INTO recurring_bill_period (id_recurring_bill, dt_period) VALUES ($1, $2) ON CONFLICT DO NOTHING", id, period)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	// Outra instância já gerou este período
+	if affected == 0 {
+		tx.Rollback()
+		return false, nil
+	}
+
+	idBill, err := bill.CreateBillTx(tx, b, userId)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	_, err = tx.Exec("UPDATE recurring_bill_period SET id_bill = $1 WHERE id_recurring_bill = $2 AND dt_period = $3", idBill, id, period)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (s *Store) queryRecurringBills(query string, args ...any) ([]types.RecurringBill, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	recurringBills := make([]types.RecurringBill, 0)
+
+	for rows.Next() {
+		r, err := scanRowIntoRecurringBill(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		recurringBills = append(recurringBills, *r)
+	}
+
+	for i := range recurringBills {
+		recurringBills[i].Payments, err = s.getPayments(recurringBills[i].IdRecurringBill)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return recurringBills, nil
+}
+
+func (s *Store) getPayments(id int) ([]types.RecurringBillPayment, error) {
+	rows, err := s.db.Query(`
+		SELECT id_recurring_bill_payment, ds_person, id_user, vl_payment, qt_share, pc_payment
+		FROM recurring_bill_payment
+		WHERE id_recurring_bill = $1
+		ORDER BY id_recurring_bill_payment ASC`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	payments := make([]types.RecurringBillPayment, 0)
+
+	for rows.Next() {
+		p := types.RecurringBillPayment{}
+		err := rows.Scan(&p.IdRecurringBillPayment, &p.DsPerson, &p.IdUser, &p.VlPayment, &p.QtShare, &p.PcPayment)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, p)
+	}
+
+	return payments, nil
+}
+
+func insertPayments(tx *sql.Tx, id int, payments []types.RecurringBillPayment) error {
+	for _, p := range payments {
+		_, err := tx.Exec(`
+			INSERT INTO recurring_bill_payment (id_recurring_bill, ds_person, id_user, vl_payment, qt_share, pc_payment)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			id, p.DsPerson, p.IdUser, p.VlPayment, p.QtShare, p.PcPayment)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func scanRowIntoRecurringBill(rows *sql.Rows) (*types.RecurringBill, error) {
+	r := &types.RecurringBill{}
+
+	err := rows.Scan(
+		&r.IdRecurringBill,
+		&r.IdUser,
+		&r.DsBill,
+		&r.VlBill,
+		&r.QtPerson,
+		&r.TpSplit,
+		&r.TpRecurrence,
+		&r.NrDay,
+		&r.NrMonth,
+		&r.DtStart,
+		&r.DtFinish,
+		&r.FgActive,
+		&r.DtLastPeriod,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
@@ -0,0 +1,188 @@
+package ride
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+// DailyCost é o custo de um dia do ride. No modo flat é o valor por viagem
+// vezes a quantidade de viagens; no modo fuel é o combustível gasto nas
+// viagens do dia, pelo preço vigente, mais pedágio e estacionamento.
+func DailyCost(r types.Ride, day time.Time) types.Money {
+	qtRide := max(r.QtRide, 1)
+
+	if r.TpPricing != types.PricingFuel {
+		return r.VlRide.Mul(qtRide)
+	}
+
+	var fuel types.Money
+	if r.NrConsumption > 0 {
+		liters := r.NrDistance * float64(qtRide) / r.NrConsumption
+		fuel = types.Money(math.Round(liters * float64(fuelPriceAt(r.FuelPrices, day))))
+	}
+
+	return fuel + r.VlToll + r.VlParking
+}
+
+// fuelPriceAt retorna o preço com a maior vigência até o dia. Dias antes do
+// primeiro preço usam o mais antigo.
+func fuelPriceAt(prices []types.FuelPrice, day time.Time) types.Money {
+	if len(prices) == 0 {
+		return 0
+	}
+
+	sorted := append([]types.FuelPrice(nil), prices...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].DtEffective.Before(sorted[j].DtEffective)
+	})
+
+	current := sorted[0].VlFuelPrice
+	for _, price := range sorted[1:] {
+		if price.DtEffective.After(day) {
+			break
+		}
+		current = price.VlFuelPrice
+	}
+
+	return current
+}
+
+// presencesByDay agrupa as presenças efetivas (quantidade maior que zero)
+// dos participantes informados por dia.
+func presencesByDay(paymentIDs map[int]bool, presences []types.Presence) map[string][]types.Presence {
+	byDay := make(map[string][]types.Presence)
+
+	for _, presence := range presences {
+		if !paymentIDs[presence.IdRidePayment] || presence.QtPresence <= 0 {
+			continue
+		}
+
+		key := dateKey(presence.DtRide)
+		byDay[key] = append(byDay[key], presence)
+	}
+
+	return byDay
+}
+
+// passengers retorna quem divide o custo do dia: todos os presentes menos o
+// motorista, ordenados para que a divisão dos centavos seja estável.
+func passengers(dailyPresences []types.Presence) []types.Presence {
+	result := make([]types.Presence, 0, len(dailyPresences))
+
+	for _, presence := range dailyPresences {
+		if !presence.FgDriver {
+			result = append(result, presence)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].IdRidePayment < result[j].IdRidePayment
+	})
+
+	return result
+}
+
+// RideCosts divide o custo de cada dia da grade entre os passageiros,
+// proporcionalmente às presenças, sem perder centavos. O motorista do dia não
+// paga. Todo participante recebe um valor, zero quando não tem presença, e
+// presenças fora da grade são ignoradas.
+func RideCosts(dailyCost func(day time.Time) types.Money, days []time.Time, paymentIDs []int, presences []types.Presence) map[int]types.Money {
+	totals := make(map[int]types.Money, len(paymentIDs))
+	known := make(map[int]bool, len(paymentIDs))
+	for _, idRidePayment := range paymentIDs {
+		totals[idRidePayment] = 0
+		known[idRidePayment] = true
+	}
+
+	byDay := presencesByDay(known, presences)
+
+	for _, day := range days {
+		dailyPassengers := passengers(byDay[dateKey(day)])
+		if len(dailyPassengers) == 0 {
+			continue
+		}
+
+		weights := make([]int64, len(dailyPassengers))
+		for i, presence := range dailyPassengers {
+			weights[i] = int64(presence.QtPresence)
+		}
+
+		shares := dailyCost(day).AllocateByWeights(weights)
+		for i, presence := range dailyPassengers {
+			totals[presence.IdRidePayment] += shares[i]
+		}
+	}
+
+	return totals
+}
+
+type Participation struct {
+	QtDrivingDays int
+	QtRidingDays  int
+	VlDriving     types.Money
+}
+
+// RideParticipation conta, por participante, os dias em que dirigiu e os dias
+// em que foi passageiro. Os dias dirigidos somam o custo pago pelos
+// passageiros; dirigir sozinho conta o dia, mas não tem custo a receber.
+func RideParticipation(dailyCost func(day time.Time) types.Money, days []time.Time, paymentIDs []int, presences []types.Presence) map[int]Participation {
+	totals := make(map[int]Participation, len(paymentIDs))
+	known := make(map[int]bool, len(paymentIDs))
+	for _, idRidePayment := range paymentIDs {
+		totals[idRidePayment] = Participation{}
+		known[idRidePayment] = true
+	}
+
+	byDay := presencesByDay(known, presences)
+
+	for _, day := range days {
+		dailyPresences := byDay[dateKey(day)]
+		hasPassengers := len(passengers(dailyPresences)) > 0
+
+		for _, presence := range dailyPresences {
+			total := totals[presence.IdRidePayment]
+
+			if presence.FgDriver {
+				total.QtDrivingDays++
+				if hasPassengers {
+					total.VlDriving += dailyCost(day)
+				}
+			} else {
+				total.QtRidingDays++
+			}
+
+			totals[presence.IdRidePayment] = total
+		}
+	}
+
+	return totals
+}
+
+// checkDrivers garante no máximo um motorista por dia, presente no ride.
+func checkDrivers(presences []types.Presence) error {
+	drivers := make(map[string]int)
+
+	for _, presence := range presences {
+		if !presence.FgDriver {
+			continue
+		}
+
+		key := dateKey(presence.DtRide)
+
+		if presence.QtPresence <= 0 {
+			return fmt.Errorf("%w: driver %d is not present on %s", ErrInvalidPresence, presence.IdRidePayment, key)
+		}
+
+		if idRidePayment, ok := drivers[key]; ok {
+			return fmt.Errorf("%w: payments %d and %d both drive on %s", ErrInvalidPresence, idRidePayment, presence.IdRidePayment, key)
+		}
+
+		drivers[key] = presence.IdRidePayment
+	}
+
+	return nil
+}
@@ -0,0 +1,201 @@
+package ride
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+func flat(cost types.Money) func(time.Time) types.Money {
+	return func(time.Time) types.Money { return cost }
+}
+
+func TestRideCosts(t *testing.T) {
+	monday := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	tuesday := monday.AddDate(0, 0, 1)
+	days := []time.Time{monday, tuesday}
+
+	t.Run("should split each day by presence weight without losing cents", func(t *testing.T) {
+		totals := RideCosts(flat(1000), days, []int{1, 2, 3}, []types.Presence{
+			{IdRidePayment: 1, DtRide: monday, QtPresence: 1},
+			{IdRidePayment: 2, DtRide: monday, QtPresence: 1},
+			{IdRidePayment: 3, DtRide: monday, QtPresence: 1},
+			{IdRidePayment: 1, DtRide: tuesday, QtPresence: 2},
+			{IdRidePayment: 2, DtRide: tuesday, QtPresence: 2},
+		})
+
+		if totals[1]+totals[2]+totals[3] != 2000 {
+			t.Errorf("expected totals to add up to 2000, got %v", totals)
+		}
+
+		if totals[1] != 834 || totals[2] != 833 || totals[3] != 333 {
+			t.Errorf("expected 834, 833 and 333, got %v", totals)
+		}
+	})
+
+	t.Run("should return zero for participants without presences", func(t *testing.T) {
+		totals := RideCosts(flat(1000), days, []int{1, 2}, []types.Presence{
+			{IdRidePayment: 1, DtRide: monday, QtPresence: 1},
+			{IdRidePayment: 2, DtRide: monday, QtPresence: 0},
+		})
+
+		if total, ok := totals[2]; !ok || total != 0 {
+			t.Errorf("expected participant 2 to be reset to zero, got %v", totals)
+		}
+
+		if totals[1] != 1000 {
+			t.Errorf("expected participant 1 to pay 1000, got %v", totals[1])
+		}
+	})
+
+	t.Run("should ignore presences outside the grid or from other rides", func(t *testing.T) {
+		totals := RideCosts(flat(1000), []time.Time{monday}, []int{1}, []types.Presence{
+			{IdRidePayment: 1, DtRide: monday, QtPresence: 1},
+			{IdRidePayment: 1, DtRide: tuesday, QtPresence: 1},
+			{IdRidePayment: 9, DtRide: monday, QtPresence: 1},
+		})
+
+		if len(totals) != 1 || totals[1] != 1000 {
+			t.Errorf("expected only participant 1 with 1000, got %v", totals)
+		}
+	})
+}
+
+func TestDailyCost(t *testing.T) {
+	monday := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+
+	t.Run("should multiply the flat price by the trips per day", func(t *testing.T) {
+		cost := DailyCost(types.Ride{VlRide: 1250, QtRide: 2}, monday)
+
+		if cost != 2500 {
+			t.Errorf("expected 2500, got %v", cost)
+		}
+	})
+
+	t.Run("should derive the fuel cost from distance, consumption and price", func(t *testing.T) {
+		ride := types.Ride{
+			TpPricing:     types.PricingFuel,
+			QtRide:        2,
+			NrDistance:    15,
+			NrConsumption: 12,
+			VlToll:        550,
+			VlParking:     1000,
+			FuelPrices:    []types.FuelPrice{{VlFuelPrice: 589, DtEffective: monday}},
+		}
+
+		// 30 km / 12 km/l = 2.5 l * 5.89 = 14.725, arredondado para 14.73
+		if cost := DailyCost(ride, monday); cost != 1473+550+1000 {
+			t.Errorf("expected 30.23, got %v", cost)
+		}
+	})
+
+	t.Run("should use the price effective on each day", func(t *testing.T) {
+		ride := types.Ride{
+			TpPricing:     types.PricingFuel,
+			NrDistance:    10,
+			NrConsumption: 10,
+			FuelPrices: []types.FuelPrice{
+				{VlFuelPrice: 600, DtEffective: monday.AddDate(0, 0, 7)},
+				{VlFuelPrice: 500, DtEffective: monday},
+			},
+		}
+
+		cases := map[time.Time]types.Money{
+			monday.AddDate(0, 0, -1): 500,
+			monday:                   500,
+			monday.AddDate(0, 0, 6):  500,
+			monday.AddDate(0, 0, 7):  600,
+			monday.AddDate(0, 0, 30): 600,
+		}
+
+		for day, expected := range cases {
+			if cost := DailyCost(ride, day); cost != expected {
+				t.Errorf("expected %v on %s, got %v", expected, dateKey(day), cost)
+			}
+		}
+	})
+}
+
+func TestRideCostsWithDriver(t *testing.T) {
+	monday := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	tuesday := monday.AddDate(0, 0, 1)
+	days := []time.Time{monday, tuesday}
+
+	presences := []types.Presence{
+		{IdRidePayment: 1, DtRide: monday, QtPresence: 1, FgDriver: true},
+		{IdRidePayment: 2, DtRide: monday, QtPresence: 2},
+		{IdRidePayment: 3, DtRide: monday, QtPresence: 1},
+		{IdRidePayment: 1, DtRide: tuesday, QtPresence: 1},
+		{IdRidePayment: 2, DtRide: tuesday, QtPresence: 1, FgDriver: true},
+	}
+
+	t.Run("should split the day only among passengers by weight", func(t *testing.T) {
+		totals := RideCosts(flat(900), days, []int{1, 2, 3}, presences)
+
+		if totals[1] != 900 || totals[2] != 600 || totals[3] != 300 {
+			t.Errorf("expected 900, 600 and 300, got %v", totals)
+		}
+	})
+
+	t.Run("should not charge anyone when the driver rides alone", func(t *testing.T) {
+		totals := RideCosts(flat(900), []time.Time{monday}, []int{1}, []types.Presence{
+			{IdRidePayment: 1, DtRide: monday, QtPresence: 1, FgDriver: true},
+		})
+
+		if totals[1] != 0 {
+			t.Errorf("expected the driver to pay nothing, got %v", totals[1])
+		}
+	})
+
+	t.Run("should report driving and riding days per participant", func(t *testing.T) {
+		participation := RideParticipation(flat(900), days, []int{1, 2, 3}, presences)
+
+		expected := map[int]Participation{
+			1: {QtDrivingDays: 1, QtRidingDays: 1, VlDriving: 900},
+			2: {QtDrivingDays: 1, QtRidingDays: 1, VlDriving: 900},
+			3: {QtDrivingDays: 0, QtRidingDays: 1},
+		}
+
+		for id, want := range expected {
+			if participation[id] != want {
+				t.Errorf("expected %+v for payment %d, got %+v", want, id, participation[id])
+			}
+		}
+	})
+}
+
+func TestCheckDrivers(t *testing.T) {
+	monday := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+
+	t.Run("should reject two drivers on the same day", func(t *testing.T) {
+		err := checkDrivers([]types.Presence{
+			{IdRidePayment: 1, DtRide: monday, QtPresence: 1, FgDriver: true},
+			{IdRidePayment: 2, DtRide: monday, QtPresence: 1, FgDriver: true},
+		})
+
+		if !errors.Is(err, ErrInvalidPresence) {
+			t.Errorf("expected ErrInvalidPresence, got %v", err)
+		}
+	})
+
+	t.Run("should reject an absent driver", func(t *testing.T) {
+		err := checkDrivers([]types.Presence{{IdRidePayment: 1, DtRide: monday, FgDriver: true}})
+
+		if !errors.Is(err, ErrInvalidPresence) {
+			t.Errorf("expected ErrInvalidPresence, got %v", err)
+		}
+	})
+
+	t.Run("should accept one driver per day", func(t *testing.T) {
+		err := checkDrivers([]types.Presence{
+			{IdRidePayment: 1, DtRide: monday, QtPresence: 1, FgDriver: true},
+			{IdRidePayment: 2, DtRide: monday.AddDate(0, 0, 1), QtPresence: 1, FgDriver: true},
+		})
+
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+}
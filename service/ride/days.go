@@ -0,0 +1,123 @@
+package ride
+
+import (
+	"time"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+// Weekdays é o conjunto de dias da semana em que o ride acontece, com um
+// bit por dia (bit 0 é domingo). É gravado assim em nr_weekdays.
+type Weekdays int
+
+const (
+	businessDays Weekdays = 1<<time.Monday | 1<<time.Tuesday | 1<<time.Wednesday | 1<<time.Thursday | 1<<time.Friday
+	allDays      Weekdays = businessDays | 1<<time.Saturday | 1<<time.Sunday
+)
+
+func NewWeekdays(days []int) Weekdays {
+	var w Weekdays
+
+	for _, day := range days {
+		w |= 1 << day
+	}
+
+	return w
+}
+
+func (w Weekdays) Has(day time.Weekday) bool {
+	return w&(1<<day) != 0
+}
+
+func (w Weekdays) Days() []int {
+	days := make([]int, 0)
+
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if w.Has(day) {
+			days = append(days, int(day))
+		}
+	}
+
+	return days
+}
+
+// rideWeekdays usa os dias informados no ride ou, sem eles, os dias úteis
+// mais o fim de semana quando FgCountWeekend está marcado.
+func rideWeekdays(r types.Ride) Weekdays {
+	if len(r.NrWeekdays) > 0 {
+		return NewWeekdays(r.NrWeekdays)
+	}
+
+	if r.FgCountWeekend {
+		return allDays
+	}
+
+	return businessDays
+}
+
+// paymentWeekdays restringe os dias do ride ao padrão do participante,
+// quando ele tem um.
+func paymentWeekdays(r types.Ride, p types.RidePayment) Weekdays {
+	weekdays := rideWeekdays(r)
+
+	if len(p.NrWeekdays) > 0 {
+		weekdays &= NewWeekdays(p.NrWeekdays)
+	}
+
+	return weekdays
+}
+
+// nullableWeekdays converte os dias para a coluna nr_weekdays, nula quando
+// não há um padrão explícito.
+func nullableWeekdays(days []int) *int {
+	if len(days) == 0 {
+		return nil
+	}
+
+	w := int(NewWeekdays(days))
+
+	return &w
+}
+
+func weekdaysFromColumn(column *int) []int {
+	if column == nil {
+		return nil
+	}
+
+	return Weekdays(*column).Days()
+}
+
+func dateKey(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+// RideDays gera a grade de dias do ride entre DtInit e DtFinish, só com os
+// dias da semana informados e sem as datas excluídas. Os dias excluídos que
+// cairiam na grade são retornados à parte.
+func RideDays(dtInit time.Time, dtFinish time.Time, weekdays Weekdays, excluded []types.CalendarDate) ([]time.Time, []types.CalendarDate) {
+	excludedByDate := make(map[string]types.CalendarDate)
+	for _, date := range excluded {
+		key := dateKey(date.DtDate)
+		if current, ok := excludedByDate[key]; !ok || current.DsDate == "" {
+			excludedByDate[key] = date
+		}
+	}
+
+	days := make([]time.Time, 0)
+	skipped := make([]types.CalendarDate, 0)
+
+	for currentDate := dtInit; !currentDate.After(dtFinish); currentDate = currentDate.AddDate(0, 0, 1) {
+		if !weekdays.Has(currentDate.Weekday()) {
+			continue
+		}
+
+		if date, ok := excludedByDate[dateKey(currentDate)]; ok {
+			skipped = append(skipped, types.CalendarDate{IdCalendar: date.IdCalendar, DtDate: currentDate, DsDate: date.DsDate})
+			continue
+		}
+
+		days = append(days, currentDate)
+	}
+
+	return days, skipped
+}
@@ -0,0 +1,96 @@
+package ride
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+func TestRideDays(t *testing.T) {
+	// 02/03/2026 é uma segunda-feira
+	dtInit := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	dtFinish := time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)
+
+	t.Run("should skip weekends unless they are counted", func(t *testing.T) {
+		days, _ := RideDays(dtInit, dtFinish, businessDays, nil)
+
+		if len(days) != 5 {
+			t.Errorf("expected 5 weekdays, got %d", len(days))
+		}
+
+		days, _ = RideDays(dtInit, dtFinish, allDays, nil)
+
+		if len(days) != 7 {
+			t.Errorf("expected 7 days, got %d", len(days))
+		}
+	})
+
+	t.Run("should skip excluded dates and report them", func(t *testing.T) {
+		days, skipped := RideDays(dtInit, dtFinish, businessDays, []types.CalendarDate{
+			{DtDate: time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC), DsDate: "Recesso"},
+			// Cai no fim de semana, então não aparece como pulado
+			{DtDate: time.Date(2026, 3, 7, 0, 0, 0, 0, time.UTC), DsDate: "Sábado"},
+		})
+
+		if len(days) != 4 {
+			t.Fatalf("expected 4 days, got %d", len(days))
+		}
+
+		for _, day := range days {
+			if day.Day() == 4 {
+				t.Errorf("expected 04/03 to be skipped")
+			}
+		}
+
+		if len(skipped) != 1 || skipped[0].DsDate != "Recesso" {
+			t.Errorf("expected only the recess to be reported, got %v", skipped)
+		}
+	})
+
+	t.Run("should only keep the ride weekdays", func(t *testing.T) {
+		ride := types.Ride{NrWeekdays: []int{1, 3, 5}}
+
+		days, _ := RideDays(dtInit, dtFinish, rideWeekdays(ride), nil)
+
+		if len(days) != 3 {
+			t.Fatalf("expected 3 days, got %d", len(days))
+		}
+
+		for _, day := range days {
+			if day.Weekday() != time.Monday && day.Weekday() != time.Wednesday && day.Weekday() != time.Friday {
+				t.Errorf("unexpected day %s", day.Weekday())
+			}
+		}
+	})
+}
+
+func TestPaymentWeekdays(t *testing.T) {
+	t.Run("should follow the ride when the participant has no pattern", func(t *testing.T) {
+		ride := types.Ride{NrWeekdays: []int{1, 3, 5}}
+
+		if paymentWeekdays(ride, types.RidePayment{}) != rideWeekdays(ride) {
+			t.Errorf("expected the ride weekdays")
+		}
+	})
+
+	t.Run("should restrict the participant pattern to the ride weekdays", func(t *testing.T) {
+		ride := types.Ride{NrWeekdays: []int{1, 3, 5}}
+
+		weekdays := paymentWeekdays(ride, types.RidePayment{NrWeekdays: []int{1, 2}})
+
+		if days := weekdays.Days(); len(days) != 1 || days[0] != 1 {
+			t.Errorf("expected only monday, got %v", days)
+		}
+	})
+
+	t.Run("should derive the weekdays from FgCountWeekend without a pattern", func(t *testing.T) {
+		if days := rideWeekdays(types.Ride{FgCountWeekend: true}).Days(); len(days) != 7 {
+			t.Errorf("expected every day, got %v", days)
+		}
+
+		if days := rideWeekdays(types.Ride{}).Days(); len(days) != 5 {
+			t.Errorf("expected business days, got %v", days)
+		}
+	})
+}
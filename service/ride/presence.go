@@ -0,0 +1,269 @@
+package ride
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+var ErrInvalidPresence = fmt.Errorf("invalid presence")
+
+func presenceKey(idRidePayment int, date time.Time) string {
+	return strconv.Itoa(idRidePayment) + "|" + dateKey(date)
+}
+
+// presenceGrid monta a grade esperada de presenças: um registro por
+// participante em cada dia do seu padrão de dias da semana.
+func presenceGrid(days []time.Time, weekdaysByPayment map[int]Weekdays) map[string]types.Presence {
+	grid := make(map[string]types.Presence)
+
+	for _, day := range days {
+		for idRidePayment, weekdays := range weekdaysByPayment {
+			if weekdays.Has(day.Weekday()) {
+				grid[presenceKey(idRidePayment, day)] = types.Presence{IdRidePayment: idRidePayment, DtRide: day}
+			}
+		}
+	}
+
+	return grid
+}
+
+// diffPresences compara as presenças gravadas com a grade esperada e com as
+// alterações enviadas. Só o que mudou é inserido, atualizado ou excluído, então
+// as presenças que continuam na grade mantêm o IdPresence.
+func diffPresences(existing map[string]types.Presence, grid map[string]types.Presence, changes map[string]types.Presence) (inserts []types.Presence, updates []types.Presence, deletes []types.Presence) {
+	for key, presence := range grid {
+		change, changed := changes[key]
+		current, exists := existing[key]
+
+		switch {
+		case !exists:
+			if changed {
+				presence.QtPresence = change.QtPresence
+				presence.FgDriver = change.FgDriver
+			}
+			inserts = append(inserts, presence)
+		case changed && (change.QtPresence != current.QtPresence || change.FgDriver != current.FgDriver):
+			current.QtPresence = change.QtPresence
+			current.FgDriver = change.FgDriver
+			updates = append(updates, current)
+		}
+	}
+
+	for key, presence := range existing {
+		if _, ok := grid[key]; !ok {
+			deletes = append(deletes, presence)
+		}
+	}
+
+	for _, presences := range [][]types.Presence{inserts, updates, deletes} {
+		sortPresences(presences)
+	}
+
+	return inserts, updates, deletes
+}
+
+func sortPresences(presences []types.Presence) {
+	sort.Slice(presences, func(i, j int) bool {
+		if !presences[i].DtRide.Equal(presences[j].DtRide) {
+			return presences[i].DtRide.Before(presences[j].DtRide)
+		}
+		return presences[i].IdRidePayment < presences[j].IdRidePayment
+	})
+}
+
+func presenceChanges(presences []types.Presence) map[string]types.Presence {
+	changes := make(map[string]types.Presence)
+
+	for _, presence := range presences {
+		changes[presenceKey(presence.IdRidePayment, presence.DtRide)] = presence
+	}
+
+	return changes
+}
+
+func groupedPresenceChanges(groupedPresences []types.GroupedPresence) map[string]types.Presence {
+	changes := make(map[string]types.Presence)
+
+	for _, gp := range groupedPresences {
+		for _, presence := range gp.Presences {
+			presence.DtRide = gp.DtRide
+			changes[presenceKey(presence.IdRidePayment, gp.DtRide)] = presence
+		}
+	}
+
+	return changes
+}
+
+// PatchPresences grava só as presenças enviadas, sem reconstruir a grade do ride.
+func (s *Store) PatchPresences(idRide int, presences []types.Presence, userId int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(`
+		SELECT r.id_ride, r.ds_ride, r.vl_ride, r.dt_init, r.dt_finish, r.fg_count_weekend, r.qt_ride, r.id_user, r.nr_weekdays,
+		r.tp_pricing, r.nr_distance, r.nr_consumption, r.vl_toll, r.vl_parking
+		FROM ride r
+		WHERE r.id_ride = $1 AND r.id_user = $2
+		FOR UPDATE`, idRide, userId)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	ride := &types.Ride{}
+	for rows.Next() {
+		ride, err = scanRowIntoRide(rows)
+		if err != nil {
+			rows.Close()
+			tx.Rollback()
+			return err
+		}
+	}
+	rows.Close()
+
+	if ride.IdRide == 0 {
+		tx.Rollback()
+		return ErrRideNotFound
+	}
+
+	if err := getDateRules(tx, ride); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := getFuelPrices(tx, ride); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	excluded, err := excludedDates(tx, *ride, userId)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	weekdaysByPayment, err := getPaymentWeekdays(tx, *ride)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	days, _ := RideDays(ride.DtInit, ride.DtFinish, rideWeekdays(*ride), excluded)
+	grid := presenceGrid(days, weekdaysByPayment)
+
+	existing, err := getPresences(tx, idRide)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	changes := presenceChanges(presences)
+
+	// Só aceita presenças de participantes do ride em dias da grade
+	target := make(map[string]types.Presence)
+	for key, presence := range existing {
+		target[key] = presence
+	}
+	for key, change := range changes {
+		presence, ok := grid[key]
+		if !ok {
+			tx.Rollback()
+			return fmt.Errorf("%w: payment %d has no ride on %s", ErrInvalidPresence, change.IdRidePayment, dateKey(change.DtRide))
+		}
+		target[key] = presence
+	}
+
+	inserts, updates, _ := diffPresences(existing, target, changes)
+
+	if err := savePresences(tx, inserts, updates, nil); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := updatePaymentValues(tx, *ride, days); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func getPaymentWeekdays(q querier, ride types.Ride) (map[int]Weekdays, error) {
+	rows, err := q.Query("SELECT id_ride_payment, nr_weekdays FROM ride_payment WHERE id_ride = $1", ride.IdRide)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	weekdaysByPayment := make(map[int]Weekdays)
+
+	for rows.Next() {
+		var idRidePayment int
+		var nrWeekdays *int
+		if err := rows.Scan(&idRidePayment, &nrWeekdays); err != nil {
+			return nil, err
+		}
+		weekdaysByPayment[idRidePayment] = paymentWeekdays(ride, types.RidePayment{NrWeekdays: weekdaysFromColumn(nrWeekdays)})
+	}
+
+	return weekdaysByPayment, nil
+}
+
+func getPresences(q querier, idRide int) (map[string]types.Presence, error) {
+	rows, err := q.Query(`
+		SELECT p.id_presence, p.id_ride_payment, p.qt_presence, p.dt_ride, p.fg_driver
+		FROM presence p
+		INNER JOIN ride_payment rp ON rp.id_ride_payment = p.id_ride_payment
+		WHERE rp.id_ride = $1`, idRide)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	presences := make(map[string]types.Presence)
+
+	for rows.Next() {
+		presence := types.Presence{}
+		if err := rows.Scan(&presence.IdPresence, &presence.IdRidePayment, &presence.QtPresence, &presence.DtRide, &presence.FgDriver); err != nil {
+			return nil, err
+		}
+		presences[presenceKey(presence.IdRidePayment, presence.DtRide)] = presence
+	}
+
+	return presences, nil
+}
+
+func savePresences(tx *sql.Tx, inserts []types.Presence, updates []types.Presence, deletes []types.Presence) error {
+	for _, presence := range deletes {
+		if _, err := tx.Exec("DELETE FROM presence WHERE id_presence = $1", presence.IdPresence); err != nil {
+			return err
+		}
+	}
+
+	for _, presence := range inserts {
+		_, err := tx.Exec(`
+			INSERT INTO presence (id_ride_payment, dt_ride, qt_presence, fg_driver) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (id_ride_payment, dt_ride) DO UPDATE SET qt_presence = EXCLUDED.qt_presence, fg_driver = EXCLUDED.fg_driver`,
+			presence.IdRidePayment, presence.DtRide, presence.QtPresence, presence.FgDriver)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, presence := range updates {
+		if _, err := tx.Exec("UPDATE presence SET qt_presence = $1, fg_driver = $2 WHERE id_presence = $3", presence.QtPresence, presence.FgDriver, presence.IdPresence); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package ride
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+func TestDiffPresences(t *testing.T) {
+	monday := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	tuesday := monday.AddDate(0, 0, 1)
+
+	existing := map[string]types.Presence{
+		presenceKey(1, monday):  {IdPresence: 10, IdRidePayment: 1, DtRide: monday, QtPresence: 2},
+		presenceKey(2, monday):  {IdPresence: 11, IdRidePayment: 2, DtRide: monday, QtPresence: 1},
+		presenceKey(1, tuesday): {IdPresence: 12, IdRidePayment: 1, DtRide: tuesday, QtPresence: 2},
+		presenceKey(2, tuesday): {IdPresence: 13, IdRidePayment: 2, DtRide: tuesday, QtPresence: 1},
+	}
+
+	t.Run("should only update changed quantities when the grid is the same", func(t *testing.T) {
+		grid := presenceGrid([]time.Time{monday, tuesday}, map[int]Weekdays{1: businessDays, 2: businessDays})
+
+		inserts, updates, deletes := diffPresences(existing, grid, presenceChanges([]types.Presence{
+			{IdRidePayment: 1, DtRide: monday, QtPresence: 2},
+			{IdRidePayment: 2, DtRide: tuesday, QtPresence: 0},
+		}))
+
+		if len(inserts) != 0 || len(deletes) != 0 {
+			t.Errorf("expected no inserts or deletes, got %v and %v", inserts, deletes)
+		}
+
+		if len(updates) != 1 || updates[0].IdPresence != 13 || updates[0].QtPresence != 0 {
+			t.Errorf("expected only presence 13 to be updated, got %v", updates)
+		}
+	})
+
+	t.Run("should update a presence when only the driver changes", func(t *testing.T) {
+		grid := presenceGrid([]time.Time{monday, tuesday}, map[int]Weekdays{1: businessDays, 2: businessDays})
+
+		_, updates, _ := diffPresences(existing, grid, presenceChanges([]types.Presence{
+			{IdRidePayment: 1, DtRide: monday, QtPresence: 2, FgDriver: true},
+		}))
+
+		if len(updates) != 1 || updates[0].IdPresence != 10 || !updates[0].FgDriver {
+			t.Errorf("expected presence 10 to become the driver, got %v", updates)
+		}
+	})
+
+	t.Run("should keep existing presences when the range grows", func(t *testing.T) {
+		wednesday := tuesday.AddDate(0, 0, 1)
+		grid := presenceGrid([]time.Time{monday, tuesday, wednesday}, map[int]Weekdays{1: businessDays, 2: businessDays})
+
+		inserts, updates, deletes := diffPresences(existing, grid, nil)
+
+		if len(inserts) != 2 || !inserts[0].DtRide.Equal(wednesday) || inserts[0].QtPresence != 0 {
+			t.Errorf("expected two empty presences on wednesday, got %v", inserts)
+		}
+
+		if len(updates) != 0 || len(deletes) != 0 {
+			t.Errorf("expected no updates or deletes, got %v and %v", updates, deletes)
+		}
+	})
+
+	t.Run("should drop presences outside the participant weekdays", func(t *testing.T) {
+		grid := presenceGrid([]time.Time{monday, tuesday}, map[int]Weekdays{1: businessDays, 2: NewWeekdays([]int{int(time.Monday)})})
+
+		_, _, deletes := diffPresences(existing, grid, nil)
+
+		if len(deletes) != 1 || deletes[0].IdPresence != 13 {
+			t.Errorf("expected presence 13 to be deleted, got %v", deletes)
+		}
+	})
+}
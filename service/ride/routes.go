@@ -2,11 +2,16 @@ package ride
 
 import (
 	// "fmt"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/gfmanica/splitz-backend/service/auth"
+	"github.com/gfmanica/splitz-backend/service/calendar"
+	"github.com/gfmanica/splitz-backend/service/group"
+	"github.com/gfmanica/splitz-backend/service/listing"
+	"github.com/gfmanica/splitz-backend/service/payment"
 	"github.com/gfmanica/splitz-backend/types"
 	"github.com/gfmanica/splitz-backend/utils"
 	"github.com/go-playground/validator/v10"
@@ -14,14 +19,16 @@ import (
 )
 
 type Handler struct {
-	store     types.RideStore
-	userStore types.UserStore
+	store      types.RideStore
+	userStore  types.UserStore
+	groupStore types.GroupStore
 }
 
-func NewHandler(store types.RideStore, userStore types.UserStore) *Handler {
+func NewHandler(store types.RideStore, userStore types.UserStore, groupStore types.GroupStore) *Handler {
 	return &Handler{
-		store:     store,
-		userStore: userStore,
+		store:      store,
+		userStore:  userStore,
+		groupStore: groupStore,
 	}
 }
 
@@ -29,13 +36,25 @@ func (h *Handler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/ride", auth.WithJWTAuth(h.handleGetRides, h.userStore)).Methods(http.MethodGet)
 	router.HandleFunc("/ride", auth.WithJWTAuth(h.handleCreateRide, h.userStore)).Methods(http.MethodPost)
 	router.HandleFunc("/ride", auth.WithJWTAuth(h.handleUpdateRide, h.userStore)).Methods(http.MethodPut)
+	router.HandleFunc("/ride/preview", auth.WithJWTAuth(h.handlePreviewRideDays, h.userStore)).Methods(http.MethodPost)
 	router.HandleFunc("/ride/{id}", auth.WithJWTAuth(h.handleGetRide, h.userStore)).Methods(http.MethodGet)
 	router.HandleFunc("/ride/{id}", auth.WithJWTAuth(h.handleDeleteRide, h.userStore)).Methods(http.MethodDelete)
+	router.HandleFunc("/ride/{id}/presence", auth.WithJWTAuth(h.handlePatchPresences, h.userStore)).Methods(http.MethodPatch)
 }
 
+// handleGetRides lista os rides paginados por cursor. Aceita search, status,
+// person, dtFrom e dtTo (período do ride), sort, order, limit, cursor e
+// include=payments,summary.
 func (h *Handler) handleGetRides(w http.ResponseWriter, r *http.Request) {
+	query, err := listing.ParseQuery(r.URL.Query(), Sorts)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
 	userId := auth.GetUserIDFromContext(r.Context())
-	rides, err := h.store.GetRides(userId)
+	rides, err := h.store.GetRides(userId, query)
 
 	if err != nil {
 		utils.WriterError(w, http.StatusInternalServerError, err)
@@ -48,8 +67,14 @@ func (h *Handler) handleGetRides(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) handleGetRide(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, _ := strconv.Atoi(vars["id"])
+	userId := auth.GetUserIDFromContext(r.Context())
 
-	Ride, err := h.store.GetRideById(id)
+	Ride, err := h.store.GetRideById(id, userId)
+
+	if errors.Is(err, ErrRideNotFound) {
+		utils.WriterError(w, http.StatusNotFound, err)
+		return
+	}
 
 	if err != nil {
 		utils.WriterError(w, http.StatusInternalServerError, err)
@@ -81,16 +106,47 @@ func (h *Handler) handleUpdateRide(w http.ResponseWriter, r *http.Request) {
 		DtInit:           payload.DtInit,
 		DtFinish:         payload.DtFinish,
 		FgCountWeekend:   payload.FgCountWeekend,
+		NrWeekdays:       payload.NrWeekdays,
+		IdCalendars:      payload.IdCalendars,
+		DtExcluded:       payload.DtExcluded,
+		TpPricing:        payload.TpPricing,
+		NrDistance:       payload.NrDistance,
+		NrConsumption:    payload.NrConsumption,
+		VlToll:           payload.VlToll,
+		VlParking:        payload.VlParking,
+		FuelPrices:       payload.FuelPrices,
 		GroupedPresences: convertToGroupedPresences(payload.GroupedPresences),
 		Payments:         convertToRidePayments(payload.Payments),
 	}
 
-	if err := h.store.UpdateRide(Ride); err != nil {
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	if err := h.resolveParticipants(Ride.Payments, userId); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.store.UpdateRide(Ride, userId); err != nil {
+		if errors.Is(err, ErrRideNotFound) {
+			utils.WriterError(w, http.StatusNotFound, err)
+			return
+		}
+
+		if errors.Is(err, payment.ErrPaymentHasHistory) {
+			utils.WriterError(w, http.StatusConflict, err)
+			return
+		}
+
+		if errors.Is(err, calendar.ErrCalendarNotFound) || errors.Is(err, ErrInvalidPresence) {
+			utils.WriterError(w, http.StatusBadRequest, err)
+			return
+		}
+
 		utils.WriterError(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	updatedRide, err := h.store.GetRideById(payload.IdRide)
+	updatedRide, err := h.store.GetRideById(payload.IdRide, userId)
 
 	if err != nil {
 		utils.WriterError(w, http.StatusInternalServerError, err)
@@ -115,6 +171,13 @@ func (h *Handler) handleCreateRide(w http.ResponseWriter, r *http.Request) {
 	}
 
 	userId := auth.GetUserIDFromContext(r.Context())
+	payments := convertToRidePayments(payload.Payments)
+
+	if err := h.resolveParticipants(payments, userId); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
 	ride, err := h.store.CreateRide(types.Ride{
 		DsRide:         payload.DsRide,
 		VlRide:         payload.VlRide,
@@ -122,9 +185,23 @@ func (h *Handler) handleCreateRide(w http.ResponseWriter, r *http.Request) {
 		DtFinish:       payload.DtFinish,
 		FgCountWeekend: payload.FgCountWeekend,
 		QtRide:         payload.QtRide,
-		Payments:       convertToRidePayments(payload.Payments),
+		NrWeekdays:     payload.NrWeekdays,
+		IdCalendars:    payload.IdCalendars,
+		DtExcluded:     payload.DtExcluded,
+		TpPricing:      payload.TpPricing,
+		NrDistance:     payload.NrDistance,
+		NrConsumption:  payload.NrConsumption,
+		VlToll:         payload.VlToll,
+		VlParking:      payload.VlParking,
+		FuelPrices:     payload.FuelPrices,
+		Payments:       payments,
 	}, userId)
 
+	if errors.Is(err, calendar.ErrCalendarNotFound) {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
 	if err != nil {
 		utils.WriterError(w, http.StatusInternalServerError, err)
 		return
@@ -133,11 +210,103 @@ func (h *Handler) handleCreateRide(w http.ResponseWriter, r *http.Request) {
 	utils.WriteJSON(w, http.StatusCreated, ride)
 }
 
+// handlePatchPresences altera só as presenças enviadas, sem reenviar o ride inteiro.
+func (h *Handler) handlePatchPresences(w http.ResponseWriter, r *http.Request) {
+	var payload types.PatchPresencesPayload
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		error := err.(validator.ValidationErrors)
+		utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %s", error))
+		return
+	}
+
+	vars := mux.Vars(r)
+	id, _ := strconv.Atoi(vars["id"])
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	err := h.store.PatchPresences(id, payload.Presences, userId)
+
+	if errors.Is(err, ErrRideNotFound) {
+		utils.WriterError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if errors.Is(err, ErrInvalidPresence) {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	ride, err := h.store.GetRideById(id, userId)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, ride)
+}
+
+// handlePreviewRideDays retorna a grade de dias que seria gerada para o
+// período, já descontando fins de semana, calendários e datas excluídas.
+func (h *Handler) handlePreviewRideDays(w http.ResponseWriter, r *http.Request) {
+	var payload types.PreviewRideDaysPayload
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		error := err.(validator.ValidationErrors)
+		utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %s", error))
+		return
+	}
+
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	preview, err := h.store.PreviewRideDays(types.Ride{
+		DtInit:         payload.DtInit,
+		DtFinish:       payload.DtFinish,
+		FgCountWeekend: payload.FgCountWeekend,
+		NrWeekdays:     payload.NrWeekdays,
+		IdCalendars:    payload.IdCalendars,
+		DtExcluded:     payload.DtExcluded,
+	}, userId)
+
+	if errors.Is(err, calendar.ErrCalendarNotFound) {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, preview)
+}
+
 func (h *Handler) handleDeleteRide(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, _ := strconv.Atoi(vars["id"])
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	err := h.store.DeleteRide(id, userId)
 
-	err := h.store.DeleteRide(id)
+	if errors.Is(err, ErrRideNotFound) {
+		utils.WriterError(w, http.StatusNotFound, err)
+		return
+	}
 
 	if err != nil {
 		utils.WriterError(w, http.StatusInternalServerError, err)
@@ -147,6 +316,28 @@ func (h *Handler) handleDeleteRide(w http.ResponseWriter, r *http.Request) {
 	utils.WriteJSON(w, http.StatusOK, nil)
 }
 
+// resolveParticipants valida os pagamentos vinculados a usuários e preenche
+// o nome da pessoa quando ele não foi informado.
+func (h *Handler) resolveParticipants(payments []types.RidePayment, userId int) error {
+	for i, payment := range payments {
+		if payment.IdUser == nil {
+			continue
+		}
+
+		participant, err := group.CheckParticipant(h.groupStore, h.userStore, userId, *payment.IdUser)
+
+		if err != nil {
+			return err
+		}
+
+		if payment.DsPerson == "" {
+			payments[i].DsPerson = participant.Name
+		}
+	}
+
+	return nil
+}
+
 func convertToRidePayments(createPayments []types.RidePayment) []types.RidePayment {
 	ridePayments := make([]types.RidePayment, len(createPayments))
 
@@ -155,7 +346,8 @@ func convertToRidePayments(createPayments []types.RidePayment) []types.RidePayme
 			IdRidePayment: createPayment.IdRidePayment,
 			DsPerson:      createPayment.DsPerson,
 			VlPayment:     createPayment.VlPayment,
-			FgPayed:       createPayment.FgPayed,
+			IdUser:        createPayment.IdUser,
+			NrWeekdays:    createPayment.NrWeekdays,
 		}
 	}
 	return ridePayments
@@ -182,6 +374,7 @@ func convertToPresences(createPresences []types.Presence) []types.Presence {
 			IdPresence:    createPresence.IdPresence,
 			IdRidePayment: createPresence.IdRidePayment,
 			QtPresence:    createPresence.QtPresence,
+			FgDriver:      createPresence.FgDriver,
 		}
 	}
 	return presences
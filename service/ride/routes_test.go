@@ -0,0 +1,362 @@
+package ride
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/service/auth"
+	"github.com/gfmanica/splitz-backend/service/payment"
+	"github.com/gfmanica/splitz-backend/types"
+	"github.com/gorilla/mux"
+)
+
+type mockRideStore struct {
+	rides  map[int]types.Ride
+	owners map[int]int
+	// Pagamentos com transações registradas
+	history map[int]bool
+}
+
+func newMockRideStore() *mockRideStore {
+	return &mockRideStore{
+		rides: map[int]types.Ride{1: {
+			IdRide:   1,
+			DsRide:   "Carona trabalho",
+			VlRide:   1000,
+			QtRide:   2,
+			DtInit:   time.Date(2025, 2, 3, 0, 0, 0, 0, time.UTC),
+			DtFinish: time.Date(2025, 2, 7, 0, 0, 0, 0, time.UTC),
+		}},
+		owners:  map[int]int{1: 1},
+		history: map[int]bool{},
+	}
+}
+
+// visible segue a consulta do banco: o dono e os participantes vinculados
+// a algum pagamento enxergam o ride.
+func (m *mockRideStore) visible(id int, userId int) bool {
+	if m.owners[id] == userId {
+		return true
+	}
+
+	for _, payment := range m.rides[id].Payments {
+		if payment.IdUser != nil && *payment.IdUser == userId {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *mockRideStore) GetRides(userId int, query types.ListQuery) (*types.Page[types.Ride], error) {
+	rides := make([]types.Ride, 0)
+
+	for id, ride := range m.rides {
+		if m.visible(id, userId) {
+			rides = append(rides, ride)
+		}
+	}
+
+	return &types.Page[types.Ride]{Items: rides, Limit: query.Limit}, nil
+}
+
+func (m *mockRideStore) GetRideById(id int, userId int) (*types.Ride, error) {
+	ride, ok := m.rides[id]
+
+	if !ok || !m.visible(id, userId) {
+		return nil, ErrRideNotFound
+	}
+
+	return &ride, nil
+}
+
+func (m *mockRideStore) CreateRide(r types.Ride, userId int) (*types.Ride, error) {
+	r.IdRide = len(m.rides) + 1
+	m.rides[r.IdRide] = r
+	m.owners[r.IdRide] = userId
+
+	return &r, nil
+}
+
+func (m *mockRideStore) UpdateRide(r types.Ride, userId int) error {
+	if _, ok := m.rides[r.IdRide]; !ok || m.owners[r.IdRide] != userId {
+		return ErrRideNotFound
+	}
+
+	kept := make(map[int]bool)
+	for _, p := range r.Payments {
+		kept[p.IdRidePayment] = true
+	}
+
+	for _, p := range m.rides[r.IdRide].Payments {
+		if m.history[p.IdRidePayment] && !kept[p.IdRidePayment] {
+			return payment.ErrPaymentHasHistory
+		}
+	}
+
+	m.rides[r.IdRide] = r
+
+	return nil
+}
+
+func (m *mockRideStore) DeleteRide(id int, userId int) error {
+	if _, ok := m.rides[id]; !ok || m.owners[id] != userId {
+		return ErrRideNotFound
+	}
+
+	delete(m.rides, id)
+
+	return nil
+}
+
+func (m *mockRideStore) PreviewRideDays(r types.Ride, userId int) (*types.RideDaysPreview, error) {
+	days, skipped := RideDays(r.DtInit, r.DtFinish, rideWeekdays(r), nil)
+
+	return &types.RideDaysPreview{Days: days, SkippedDays: skipped}, nil
+}
+
+func (m *mockRideStore) PatchPresences(id int, presences []types.Presence, userId int) error {
+	if _, ok := m.rides[id]; !ok || m.owners[id] != userId {
+		return ErrRideNotFound
+	}
+
+	return nil
+}
+
+// mockGroupStore só responde quem é colega de grupo do usuário 1.
+type mockGroupStore struct {
+	types.GroupStore
+	mates map[int]bool
+}
+
+func (m *mockGroupStore) AreGroupMates(userId int, otherUserId int) (bool, error) {
+	return userId == otherUserId || (userId == 1 && m.mates[otherUserId]), nil
+}
+
+type mockUserStore struct {
+	types.UserStore
+}
+
+func (m *mockUserStore) GetUserByID(id int) (*types.User, error) {
+	return &types.User{ID: id, Name: fmt.Sprintf("Usuário %d", id)}, nil
+}
+
+func serveAs(userId int, method, path string, body []byte, route string, handler http.HandlerFunc) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBuffer(body))
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, userId))
+
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+
+	router.HandleFunc(route, handler)
+	router.ServeHTTP(rr, req)
+
+	return rr
+}
+
+func TestRideServiceHandlers(t *testing.T) {
+	t.Run("should return the ride to its owner", func(t *testing.T) {
+		handler := NewHandler(newMockRideStore(), nil, nil)
+
+		rr := serveAs(1, http.MethodGet, "/ride/1", nil, "/ride/{id}", handler.handleGetRide)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, rr.Code)
+		}
+	})
+
+	t.Run("should not return another user's ride", func(t *testing.T) {
+		handler := NewHandler(newMockRideStore(), nil, nil)
+
+		rr := serveAs(2, http.MethodGet, "/ride/1", nil, "/ride/{id}", handler.handleGetRide)
+
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("should not update another user's ride", func(t *testing.T) {
+		store := newMockRideStore()
+		handler := NewHandler(store, nil, nil)
+
+		ride := store.rides[1]
+		ride.DsRide = "Alterado"
+		marshalled, _ := json.Marshal(ride)
+
+		rr := serveAs(2, http.MethodPut, "/ride", marshalled, "/ride", handler.handleUpdateRide)
+
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+		}
+
+		if store.rides[1].DsRide != "Carona trabalho" {
+			t.Errorf("expected ride to be unchanged, got %s", store.rides[1].DsRide)
+		}
+	})
+
+	t.Run("should reject negative presences on update", func(t *testing.T) {
+		store := newMockRideStore()
+		handler := NewHandler(store, nil, nil)
+
+		ride := store.rides[1]
+		ride.GroupedPresences = []types.GroupedPresence{{DtRide: ride.DtInit, Presences: []types.Presence{
+			{IdRidePayment: 1, QtPresence: -1, DtRide: ride.DtInit},
+		}}}
+		marshalled, _ := json.Marshal(ride)
+
+		rr := serveAs(1, http.MethodPut, "/ride", marshalled, "/ride", handler.handleUpdateRide)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+
+		if len(store.rides[1].GroupedPresences) != 0 {
+			t.Errorf("expected ride to be unchanged, got %+v", store.rides[1].GroupedPresences)
+		}
+	})
+
+	t.Run("should not drop a payment that has transactions", func(t *testing.T) {
+		store := newMockRideStore()
+		handler := NewHandler(store, nil, nil)
+
+		ride := store.rides[1]
+		ride.Payments = []types.RidePayment{{IdRidePayment: 1, DsPerson: "Ana"}, {IdRidePayment: 2, DsPerson: "Bruno"}}
+		store.rides[1] = ride
+		store.history[2] = true
+
+		ride.Payments = ride.Payments[:1]
+		marshalled, _ := json.Marshal(ride)
+
+		rr := serveAs(1, http.MethodPut, "/ride", marshalled, "/ride", handler.handleUpdateRide)
+
+		if rr.Code != http.StatusConflict {
+			t.Errorf("expected status %d, got %d: %s", http.StatusConflict, rr.Code, rr.Body)
+		}
+
+		if len(store.rides[1].Payments) != 2 {
+			t.Errorf("expected both payments to be kept, got %+v", store.rides[1].Payments)
+		}
+	})
+
+	t.Run("should not patch presences of another user's ride", func(t *testing.T) {
+		handler := NewHandler(newMockRideStore(), nil, nil)
+
+		marshalled, _ := json.Marshal(types.PatchPresencesPayload{Presences: []types.Presence{
+			{IdRidePayment: 1, QtPresence: 2, DtRide: time.Date(2025, 2, 3, 0, 0, 0, 0, time.UTC)},
+		}})
+
+		rr := serveAs(2, http.MethodPatch, "/ride/1/presence", marshalled, "/ride/{id}/presence", handler.handlePatchPresences)
+
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("should reject negative presences", func(t *testing.T) {
+		handler := NewHandler(newMockRideStore(), nil, nil)
+
+		marshalled, _ := json.Marshal(types.PatchPresencesPayload{Presences: []types.Presence{
+			{IdRidePayment: 1, QtPresence: -1, DtRide: time.Date(2025, 2, 3, 0, 0, 0, 0, time.UTC)},
+		}})
+
+		rr := serveAs(1, http.MethodPatch, "/ride/1/presence", marshalled, "/ride/{id}/presence", handler.handlePatchPresences)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("should require distance, consumption and fuel prices in fuel pricing", func(t *testing.T) {
+		handler := NewHandler(newMockRideStore(), nil, nil)
+
+		marshalled, _ := json.Marshal(types.CreateRidePayload{
+			DsRide:    "Carona combustível",
+			TpPricing: types.PricingFuel,
+			DtInit:    time.Date(2025, 2, 3, 0, 0, 0, 0, time.UTC),
+			DtFinish:  time.Date(2025, 2, 7, 0, 0, 0, 0, time.UTC),
+			Payments:  []types.RidePayment{{DsPerson: "Ana"}},
+		})
+
+		rr := serveAs(1, http.MethodPost, "/ride", marshalled, "/ride", handler.handleCreateRide)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("should not delete another user's ride", func(t *testing.T) {
+		store := newMockRideStore()
+		handler := NewHandler(store, nil, nil)
+
+		rr := serveAs(2, http.MethodDelete, "/ride/1", nil, "/ride/{id}", handler.handleDeleteRide)
+
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+		}
+
+		if _, ok := store.rides[1]; !ok {
+			t.Errorf("expected ride to still exist")
+		}
+	})
+}
+
+func TestRideParticipants(t *testing.T) {
+	mate := 2
+	stranger := 3
+
+	payload := func(idUser *int) []byte {
+		marshalled, _ := json.Marshal(types.CreateRidePayload{
+			DsRide:   "Carona faculdade",
+			VlRide:   1000,
+			DtInit:   time.Date(2025, 2, 3, 0, 0, 0, 0, time.UTC),
+			DtFinish: time.Date(2025, 2, 7, 0, 0, 0, 0, time.UTC),
+			Payments: []types.RidePayment{{DsPerson: "Ana"}, {IdUser: idUser}},
+		})
+
+		return marshalled
+	}
+
+	t.Run("should show the ride to a group mate linked to a payment", func(t *testing.T) {
+		handler := NewHandler(newMockRideStore(), &mockUserStore{}, &mockGroupStore{mates: map[int]bool{mate: true}})
+
+		rr := serveAs(1, http.MethodPost, "/ride", payload(&mate), "/ride", handler.handleCreateRide)
+
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+		}
+
+		var ride types.Ride
+		json.NewDecoder(rr.Body).Decode(&ride)
+
+		path := fmt.Sprintf("/ride/%d", ride.IdRide)
+
+		if rr := serveAs(mate, http.MethodGet, path, nil, "/ride/{id}", handler.handleGetRide); rr.Code != http.StatusOK {
+			t.Errorf("expected status %d for the participant, got %d", http.StatusOK, rr.Code)
+		}
+
+		if rr := serveAs(stranger, http.MethodGet, path, nil, "/ride/{id}", handler.handleGetRide); rr.Code != http.StatusNotFound {
+			t.Errorf("expected status %d for someone else, got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("should reject a participant outside the user's groups", func(t *testing.T) {
+		store := newMockRideStore()
+		handler := NewHandler(store, &mockUserStore{}, &mockGroupStore{mates: map[int]bool{mate: true}})
+
+		rr := serveAs(1, http.MethodPost, "/ride", payload(&stranger), "/ride", handler.handleCreateRide)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+
+		if len(store.rides) != 1 {
+			t.Errorf("expected no ride to be created, got %d rides", len(store.rides))
+		}
+	})
+}
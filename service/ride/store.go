@@ -4,11 +4,17 @@ import (
 	"database/sql"
 	"fmt"
 	"sort"
+	"strconv"
 	"time"
 
+	"github.com/gfmanica/splitz-backend/service/calendar"
+	"github.com/gfmanica/splitz-backend/service/listing"
+	"github.com/gfmanica/splitz-backend/service/payment"
 	"github.com/gfmanica/splitz-backend/types"
 )
 
+var ErrRideNotFound = fmt.Errorf("ride not found")
+
 type Store struct {
 	db *sql.DB
 }
@@ -17,8 +23,72 @@ func NewStore(db *sql.DB) *Store {
 	return &Store{db: db}
 }
 
-func (s *Store) GetRides(userId int) ([]types.Ride, error) {
-	rows, err := s.db.Query("SELECT id_ride, ds_ride, vl_ride,  dt_init, dt_finish, fg_count_weekend, qt_ride FROM ride WHERE id_user = $1 ORDER BY id_ride DESC", userId)
+// Sorts são as chaves de ordenação aceitas em GET /ride e na exportação.
+var Sorts = map[string]listing.Sort[types.Ride]{
+	"id": {Expr: "r.id_ride", Cast: "integer", Value: func(r types.Ride) string {
+		return strconv.Itoa(r.IdRide)
+	}},
+	"dsRide": {Expr: "r.ds_ride", Cast: "text", Value: func(r types.Ride) string {
+		return r.DsRide
+	}},
+	"vlRide": {Expr: "r.vl_ride", Cast: "numeric", Value: func(r types.Ride) string {
+		return r.VlRide.String()
+	}},
+	"dtInit": {Expr: "r.dt_init", Cast: "date", Value: func(r types.Ride) string {
+		return dateKey(r.DtInit)
+	}},
+	"dtFinish": {Expr: "r.dt_finish", Cast: "date", Value: func(r types.Ride) string {
+		return dateKey(r.DtFinish)
+	}},
+}
+
+// unsettledRide indica um ride com algum pagamento ainda em aberto.
+const unsettledRide = `EXISTS (
+	SELECT 1 FROM ride_payment rp
+	WHERE rp.id_ride = r.id_ride
+	AND rp.vl_payment > (SELECT COALESCE(SUM(pt.vl_payment), 0) FROM payment_transaction pt WHERE pt.id_ride_payment = rp.id_ride_payment))`
+
+func (s *Store) GetRides(userId int, query types.ListQuery) (*types.Page[types.Ride], error) {
+	sort := Sorts[query.Sort]
+	builder := &listing.Builder{}
+
+	// Além dos rides do usuário, retorna aqueles em que ele é participante
+	user := builder.Arg(userId)
+	builder.Where(fmt.Sprintf("(r.id_user = %s OR EXISTS (SELECT 1 FROM ride_payment rp WHERE rp.id_ride = r.id_ride AND rp.id_user = %s))", user, user))
+
+	if query.Search != "" {
+		builder.Where("r.ds_ride ILIKE " + builder.Arg(listing.Contains(query.Search)))
+	}
+
+	if query.Person != "" {
+		builder.Where("EXISTS (SELECT 1 FROM ride_payment rp WHERE rp.id_ride = r.id_ride AND rp.ds_person ILIKE " + builder.Arg(listing.Contains(query.Person)) + ")")
+	}
+
+	switch query.Status {
+	case types.StatusSettled:
+		builder.Where("NOT " + unsettledRide)
+	case types.StatusUnsettled:
+		builder.Where(unsettledRide)
+	}
+
+	// Rides cujo período cruza o intervalo informado
+	if query.DtFrom != nil {
+		builder.Where("r.dt_finish >= " + builder.Arg(*query.DtFrom))
+	}
+
+	if query.DtTo != nil {
+		builder.Where("r.dt_init <= " + builder.Arg(*query.DtTo))
+	}
+
+	listing.After(builder, sort, "r.id_ride", query)
+
+	rows, err := s.db.Query(`
+		SELECT r.id_ride, r.ds_ride, r.vl_ride, r.dt_init, r.dt_finish, r.fg_count_weekend, r.qt_ride, r.id_user, r.nr_weekdays,
+		r.tp_pricing, r.nr_distance, r.nr_consumption, r.vl_toll, r.vl_parking
+		FROM ride r
+		`+builder.WhereClause()+`
+		ORDER BY `+listing.OrderBy(sort, "r.id_ride", query.Order)+`
+		LIMIT `+builder.Arg(query.Limit+1), builder.Args()...)
 
 	if err != nil {
 		return nil, err
@@ -30,47 +100,135 @@ func (s *Store) GetRides(userId int) ([]types.Ride, error) {
 		ride, err := scanRowIntoRide(rows)
 
 		if err != nil {
+			rows.Close()
 			return nil, err
 		}
 
 		rides = append(rides, *ride)
 	}
+	rows.Close()
+
+	page := listing.NewPage(rides, query, sort, func(r types.Ride) int { return r.IdRide })
+
+	if query.FgPayments || query.FgSummary {
+		ids := make([]int64, len(page.Items))
+		for i, ride := range page.Items {
+			ids[i] = int64(ride.IdRide)
+		}
+
+		payments, err := s.getPayments(ids)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, ride := range page.Items {
+			if query.FgPayments {
+				page.Items[i].Payments = payments[ride.IdRide]
+			}
+
+			if query.FgSummary {
+				page.Items[i].Summary = summarize(payments[ride.IdRide])
+			}
+		}
+	}
+
+	return page, nil
+}
+
+func summarize(payments []types.RidePayment) *types.PaymentSummary {
+	vlPayments := make([]types.Money, len(payments))
+	vlPaids := make([]types.Money, len(payments))
+
+	for i, p := range payments {
+		vlPayments[i] = p.VlPayment
+		vlPaids[i] = p.VlPaid
+	}
 
-	return rides, nil
+	return payment.Summarize(vlPayments, vlPaids)
 }
 
-func (s *Store) GetRideById(id int) (*types.Ride, error) {
-	rows, err := s.db.Query("SELECT id_ride, ds_ride, vl_ride,  dt_init, dt_finish, fg_count_weekend, qt_ride  FROM ride WHERE id_ride = $1", id)
+// getPayments carrega os pagamentos dos rides informados, agrupados por ride.
+// O valor pago e a data do último pagamento vêm das transações.
+func (s *Store) getPayments(ids []int64) (map[int][]types.RidePayment, error) {
+	rows, err := s.db.Query(`
+		SELECT rp.id_ride, rp.id_ride_payment, rp.vl_payment, rp.ds_person, rp.id_user, rp.nr_weekdays, t.vl_paid, t.dt_payment
+		FROM ride_payment rp
+		CROSS JOIN LATERAL (
+			SELECT COALESCE(SUM(pt.vl_payment), 0) AS vl_paid,
+			MAX(pt.dt_payment) FILTER (WHERE pt.id_reversed_transaction IS NULL
+				AND NOT EXISTS (SELECT 1 FROM payment_transaction r WHERE r.id_reversed_transaction = pt.id_payment_transaction)) AS dt_payment
+			FROM payment_transaction pt
+			WHERE pt.id_ride_payment = rp.id_ride_payment
+		) t
+		WHERE rp.id_ride = ANY($1)
+		ORDER BY rp.id_ride_payment ASC`, ids)
 	if err != nil {
 		return nil, err
 	}
 
-	ride := &types.Ride{}
-	for rows.Next() {
-		ride, err = scanRowIntoRide(rows)
+	defer rows.Close()
 
+	payments := make(map[int][]types.RidePayment)
+	for rows.Next() {
+		p := types.RidePayment{}
+		var idRide int
+		var nrWeekdays *int
+		err := rows.Scan(&idRide, &p.IdRidePayment, &p.VlPayment, &p.DsPerson, &p.IdUser, &nrWeekdays, &p.VlPaid, &p.DtPayment)
 		if err != nil {
 			return nil, err
 		}
+		p.NrWeekdays = weekdaysFromColumn(nrWeekdays)
+		p.VlOutstanding, p.FgPayed = payment.Status(p.VlPayment, p.VlPaid)
+		payments[idRide] = append(payments[idRide], p)
 	}
 
-	paymentRows, err := s.db.Query("SELECT id_ride_payment, vl_payment,  ds_person, fg_payed FROM ride_payment WHERE id_ride = $1 ORDER BY id_ride_payment ASC", id)
+	return payments, nil
+}
+
+func (s *Store) GetRideById(id int, userId int) (*types.Ride, error) {
+	rows, err := s.db.Query(`
+		SELECT r.id_ride, r.ds_ride, r.vl_ride, r.dt_init, r.dt_finish, r.fg_count_weekend, r.qt_ride, r.id_user, r.nr_weekdays,
+		r.tp_pricing, r.nr_distance, r.nr_consumption, r.vl_toll, r.vl_parking
+		FROM ride r
+		WHERE r.id_ride = $1
+		AND (r.id_user = $2 OR EXISTS (SELECT 1 FROM ride_payment rp WHERE rp.id_ride = r.id_ride AND rp.id_user = $2))`, id, userId)
 	if err != nil {
 		return nil, err
 	}
 
-	ride.Payments = make([]types.RidePayment, 0)
-	for paymentRows.Next() {
-		payment := types.RidePayment{}
-		err := paymentRows.Scan(&payment.IdRidePayment, &payment.VlPayment, &payment.DsPerson, &payment.FgPayed)
+	ride := &types.Ride{}
+	for rows.Next() {
+		ride, err = scanRowIntoRide(rows)
+
 		if err != nil {
 			return nil, err
 		}
-		ride.Payments = append(ride.Payments, payment)
+	}
+
+	if ride.IdRide == 0 {
+		return nil, ErrRideNotFound
+	}
+
+	payments, err := s.getPayments([]int64{int64(id)})
+	if err != nil {
+		return nil, err
+	}
+
+	ride.Payments = payments[id]
+	if ride.Payments == nil {
+		ride.Payments = make([]types.RidePayment, 0)
+	}
+
+	if err := getDateRules(s.db, ride); err != nil {
+		return nil, err
+	}
+
+	if err := getFuelPrices(s.db, ride); err != nil {
+		return nil, err
 	}
 
 	presenceRows, err := s.db.Query(`
-		SELECT p.id_presence, p.id_ride_payment, p.qt_presence, p.dt_ride 
+		SELECT p.id_presence, p.id_ride_payment, p.qt_presence, p.dt_ride, p.fg_driver
 		FROM presence p
 		INNER JOIN ride_payment bp ON p.id_ride_payment = bp.id_ride_payment
 		WHERE bp.id_ride = $1`, id)
@@ -82,13 +240,17 @@ func (s *Store) GetRideById(id int) (*types.Ride, error) {
 
 	for presenceRows.Next() {
 		presence := types.Presence{}
-		err := presenceRows.Scan(&presence.IdPresence, &presence.IdRidePayment, &presence.QtPresence, &presence.DtRide)
+		err := presenceRows.Scan(&presence.IdPresence, &presence.IdRidePayment, &presence.QtPresence, &presence.DtRide, &presence.FgDriver)
 		if err != nil {
 			return nil, err
 		}
 		presences = append(presences, presence)
 	}
 
+	if err := setParticipation(s.db, ride, presences); err != nil {
+		return nil, err
+	}
+
 	// Agrupar presenças
 	groupMap := make(map[string][]types.Presence)
 	for _, p := range presences {
@@ -114,14 +276,14 @@ func (s *Store) GetRideById(id int) (*types.Ride, error) {
 		})
 	}
 
-	if ride.IdRide == 0 {
-		return nil, nil
-	}
-
 	return ride, nil
 }
 
 func (s *Store) CreateRide(ridePayload types.Ride, userId int) (*types.Ride, error) {
+	// Sem quantidade informada vale uma viagem por dia, como o padrão da coluna
+	ridePayload.QtRide = max(ridePayload.QtRide, 1)
+	ridePayload.TpPricing = pricingMode(ridePayload)
+
 	tx, err := s.db.Begin()
 	if err != nil {
 		return nil, err
@@ -129,14 +291,22 @@ func (s *Store) CreateRide(ridePayload types.Ride, userId int) (*types.Ride, err
 
 	var id int
 	err = tx.QueryRow(`
-		INSERT INTO ride (ds_ride, vl_ride,  dt_init, dt_finish, fg_count_weekend, id_user)
-		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id_ride
+		INSERT INTO ride (ds_ride, vl_ride, qt_ride, dt_init, dt_finish, fg_count_weekend, nr_weekdays,
+			tp_pricing, nr_distance, nr_consumption, vl_toll, vl_parking, id_user)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING id_ride
 	`,
 		ridePayload.DsRide,
 		ridePayload.VlRide,
+		ridePayload.QtRide,
 		ridePayload.DtInit,
 		ridePayload.DtFinish,
 		ridePayload.FgCountWeekend,
+		nullableWeekdays(ridePayload.NrWeekdays),
+		ridePayload.TpPricing,
+		ridePayload.NrDistance,
+		ridePayload.NrConsumption,
+		ridePayload.VlToll,
+		ridePayload.VlParking,
 		userId,
 	).Scan(&id)
 	if err != nil {
@@ -144,18 +314,24 @@ func (s *Store) CreateRide(ridePayload types.Ride, userId int) (*types.Ride, err
 		return nil, err
 	}
 
+	if err := saveFuelPrices(tx, id, ridePayload.FuelPrices); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
 	var paymentIDs []int
 	for i := 0; i < len(ridePayload.Payments); i++ {
 		var pid int
 		err := tx.QueryRow(`
-			INSERT INTO ride_payment (vl_payment, ds_person, fg_payed, id_ride)
-			VALUES ($1, $2, $3, $4)
+			INSERT INTO ride_payment (vl_payment, ds_person, id_ride, id_user, nr_weekdays)
+			VALUES ($1, $2, $3, $4, $5)
 			RETURNING id_ride_payment
 		`,
 			0,
 			ridePayload.Payments[i].DsPerson,
-			false,
 			id,
+			ridePayload.Payments[i].IdUser,
+			nullableWeekdays(ridePayload.Payments[i].NrWeekdays),
 		).Scan(&pid)
 		if err != nil {
 			tx.Rollback()
@@ -164,22 +340,30 @@ func (s *Store) CreateRide(ridePayload types.Ride, userId int) (*types.Ride, err
 		paymentIDs = append(paymentIDs, pid)
 	}
 
-	currentDate := ridePayload.DtInit
-	for !currentDate.After(ridePayload.DtFinish) {
-		if ridePayload.FgCountWeekend ||
-			(currentDate.Weekday() != 6 && currentDate.Weekday() != 0) {
-			for _, id := range paymentIDs {
-				_, err := tx.Exec(`
-					INSERT INTO presence (id_ride_payment, dt_ride, qt_presence)
-					VALUES ($1, $2, 0)
-				`, id, currentDate)
-				if err != nil {
-					tx.Rollback()
-					return nil, err
-				}
+	excluded, err := saveDateRules(tx, id, ridePayload, userId)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	days, _ := RideDays(ridePayload.DtInit, ridePayload.DtFinish, rideWeekdays(ridePayload), excluded)
+
+	for _, currentDate := range days {
+		for i, id := range paymentIDs {
+			// O participante só tem presença nos dias do seu padrão
+			if !paymentWeekdays(ridePayload, ridePayload.Payments[i]).Has(currentDate.Weekday()) {
+				continue
+			}
+
+			_, err := tx.Exec(`
+				INSERT INTO presence (id_ride_payment, dt_ride, qt_presence)
+				VALUES ($1, $2, 0)
+			`, id, currentDate)
+			if err != nil {
+				tx.Rollback()
+				return nil, err
 			}
 		}
-		currentDate = currentDate.AddDate(0, 0, 1)
 	}
 
 	err = tx.Commit()
@@ -187,25 +371,46 @@ func (s *Store) CreateRide(ridePayload types.Ride, userId int) (*types.Ride, err
 		return nil, err
 	}
 
-	return s.GetRideById(id)
+	return s.GetRideById(id, userId)
 }
 
-func (s *Store) UpdateRide(ridePayload types.Ride) error {
+func (s *Store) UpdateRide(ridePayload types.Ride, userId int) error {
+	ridePayload.QtRide = max(ridePayload.QtRide, 1)
+	ridePayload.TpPricing = pricingMode(ridePayload)
+
 	tx, err := s.db.Begin()
 	if err != nil {
 		return err
 	}
 
 	// Atualiza os dados da raiz do ride
-	_, err = tx.Exec(`
-		UPDATE ride SET ds_ride = $1, vl_ride = $2, dt_init = $3, dt_finish = $4, fg_count_weekend = $5
-		WHERE id_ride = $6
-	`, ridePayload.DsRide, ridePayload.VlRide, ridePayload.DtInit, ridePayload.DtFinish, ridePayload.FgCountWeekend, ridePayload.IdRide)
+	result, err := tx.Exec(`
+		UPDATE ride SET ds_ride = $1, vl_ride = $2, qt_ride = $3, dt_init = $4, dt_finish = $5, fg_count_weekend = $6, nr_weekdays = $7,
+		tp_pricing = $8, nr_distance = $9, nr_consumption = $10, vl_toll = $11, vl_parking = $12
+		WHERE id_ride = $13 AND id_user = $14
+	`, ridePayload.DsRide, ridePayload.VlRide, ridePayload.QtRide, ridePayload.DtInit, ridePayload.DtFinish, ridePayload.FgCountWeekend, nullableWeekdays(ridePayload.NrWeekdays),
+		ridePayload.TpPricing, ridePayload.NrDistance, ridePayload.NrConsumption, ridePayload.VlToll, ridePayload.VlParking, ridePayload.IdRide, userId)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
 
+	// Nenhuma linha atualizada significa que o ride não existe ou pertence a outro usuário
+	affected, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if affected == 0 {
+		tx.Rollback()
+		return ErrRideNotFound
+	}
+
+	if err := saveFuelPrices(tx, ridePayload.IdRide, ridePayload.FuelPrices); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	// Lida com os pagamentos
 	// Recupera os pagamentos existentes
 	rows, err := tx.Query(`SELECT id_ride_payment FROM ride_payment WHERE id_ride = $1`, ridePayload.IdRide)
@@ -228,9 +433,9 @@ func (s *Store) UpdateRide(ridePayload types.Ride) error {
 			// Atualiza pagamento existente
 			payloadPaymentsIDs[p.IdRidePayment] = true
 			_, err = tx.Exec(`
-				UPDATE ride_payment SET ds_person = $1, fg_payed = $2
-				WHERE id_ride_payment = $3
-			`, p.DsPerson, p.FgPayed, p.IdRidePayment)
+				UPDATE ride_payment SET ds_person = $1, id_user = $2, nr_weekdays = $3
+				WHERE id_ride_payment = $4 AND id_ride = $5
+			`, p.DsPerson, p.IdUser, nullableWeekdays(p.NrWeekdays), p.IdRidePayment, ridePayload.IdRide)
 			if err != nil {
 				tx.Rollback()
 				return err
@@ -239,9 +444,9 @@ func (s *Store) UpdateRide(ridePayload types.Ride) error {
 			// Insere pagamento novo
 			var newID int
 			err = tx.QueryRow(`
-				INSERT INTO ride_payment (vl_payment, ds_person, fg_payed, id_ride)
-				VALUES ($1, $2, $3, $4) RETURNING id_ride_payment
-			`, 0, p.DsPerson, false, ridePayload.IdRide).Scan(&newID)
+				INSERT INTO ride_payment (vl_payment, ds_person, id_ride, id_user, nr_weekdays)
+				VALUES ($1, $2, $3, $4, $5) RETURNING id_ride_payment
+			`, 0, p.DsPerson, ridePayload.IdRide, p.IdUser, nullableWeekdays(p.NrWeekdays)).Scan(&newID)
 			if err != nil {
 				tx.Rollback()
 				return err
@@ -249,9 +454,20 @@ func (s *Store) UpdateRide(ridePayload types.Ride) error {
 			payloadPaymentsIDs[newID] = true
 		}
 	}
-	// Deleta pagamentos que foram removidos
+	// Deleta pagamentos que foram removidos, desde que não tenham transações
 	for id := range existingPayments {
 		if !payloadPaymentsIDs[id] {
+			if err = payment.CheckRemovable(tx, types.PaymentRide, id); err != nil {
+				tx.Rollback()
+				return err
+			}
+
+			_, err = tx.Exec(`DELETE FROM presence WHERE id_ride_payment = $1`, id)
+			if err != nil {
+				tx.Rollback()
+				return err
+			}
+
 			_, err = tx.Exec(`DELETE FROM ride_payment WHERE id_ride_payment = $1`, id)
 			if err != nil {
 				tx.Rollback()
@@ -260,121 +476,305 @@ func (s *Store) UpdateRide(ridePayload types.Ride) error {
 		}
 	}
 
-	// Após atualizar/inserir/excluir os pagamentos, recupere os IDs válidos:
-	paymentIDs := []int{}
-	rows, err = tx.Query(`SELECT id_ride_payment FROM ride_payment WHERE id_ride = $1`, ridePayload.IdRide)
+	excluded, err := saveDateRules(tx, ridePayload.IdRide, ridePayload, userId)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
+
+	weekdaysByPayment, err := getPaymentWeekdays(tx, ridePayload)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	days, _ := RideDays(ridePayload.DtInit, ridePayload.DtFinish, rideWeekdays(ridePayload), excluded)
+
+	// Compara a grade nova com as presenças gravadas: a grade só muda quando o
+	// período, os dias da semana ou os participantes mudam, e nos demais casos
+	// só as quantidades alteradas são gravadas
+	existing, err := getPresences(tx, ridePayload.IdRide)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	inserts, updates, deletes := diffPresences(existing, presenceGrid(days, weekdaysByPayment), groupedPresenceChanges(ridePayload.GroupedPresences))
+
+	if err := savePresences(tx, inserts, updates, deletes); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := updatePaymentValues(tx, ridePayload, days); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// updatePaymentValues recalcula o vl_payment de todos os participantes a
+// partir das presenças gravadas do ride.
+func updatePaymentValues(tx *sql.Tx, ridePayload types.Ride, days []time.Time) error {
+	rows, err := tx.Query("SELECT id_ride_payment FROM ride_payment WHERE id_ride = $1", ridePayload.IdRide)
+	if err != nil {
+		return err
+	}
+
+	paymentIDs := make([]int, 0)
 	for rows.Next() {
-		var pid int
-		rows.Scan(&pid)
-		paymentIDs = append(paymentIDs, pid)
+		var idRidePayment int
+		if err := rows.Scan(&idRidePayment); err != nil {
+			rows.Close()
+			return err
+		}
+		paymentIDs = append(paymentIDs, idRidePayment)
 	}
 	rows.Close()
 
-	// Lida com as presenças: delete as antigas e insere com base na nova grade
-	_, err = tx.Exec(`
-		DELETE FROM presence 
-		WHERE id_ride_payment IN (SELECT id_ride_payment FROM ride_payment WHERE id_ride = $1)
-	`, ridePayload.IdRide)
+	existing, err := getPresences(tx, ridePayload.IdRide)
 	if err != nil {
-		tx.Rollback()
 		return err
 	}
 
-	// Insere as presenças conforme a nova grade iterando de DtInit a DtFinish
-	currentDate := ridePayload.DtInit
-	for !currentDate.After(ridePayload.DtFinish) {
-		if ridePayload.FgCountWeekend || (currentDate.Weekday() != time.Saturday && currentDate.Weekday() != time.Sunday) {
-			for _, id := range paymentIDs {
-				qtPresence := 0
-				found := false
-				// Se houver atualização para o dia, utiliza o qt_presence enviado
-				for _, gp := range ridePayload.GroupedPresences {
-					if gp.DtRide.Equal(currentDate) {
-						for _, ps := range gp.Presences {
-							if ps.IdRidePayment == id {
-								qtPresence = ps.QtPresence
-								found = true
-								break
-							}
-						}
-					}
-					if found {
-						break
-					}
-				}
-				_, err = tx.Exec(`
-					INSERT INTO presence (id_ride_payment, dt_ride, qt_presence)
-					VALUES ($1, $2, $3)
-				`, id, currentDate, qtPresence)
-				if err != nil {
-					tx.Rollback()
-					return err
-				}
-			}
+	presences := make([]types.Presence, 0, len(existing))
+	for _, presence := range existing {
+		presences = append(presences, presence)
+	}
+
+	if err := checkDrivers(presences); err != nil {
+		return err
+	}
+
+	dailyCost := func(day time.Time) types.Money {
+		return DailyCost(ridePayload, day)
+	}
+
+	totals := RideCosts(dailyCost, days, paymentIDs, presences)
+
+	for idRidePayment, total := range totals {
+		_, err := tx.Exec("UPDATE ride_payment SET vl_payment = $1 WHERE id_ride_payment = $2", total, idRidePayment)
+		if err != nil {
+			return err
 		}
-		currentDate = currentDate.AddDate(0, 0, 1)
-	}
-
-	// Recalcula e atualiza os vl_payment
-	paymentTotals := make(map[int]float64)
-	currentDate = ridePayload.DtInit
-	for !currentDate.After(ridePayload.DtFinish) {
-		if ridePayload.FgCountWeekend || (currentDate.Weekday() != time.Saturday && currentDate.Weekday() != time.Sunday) {
-			rows, err := tx.Query(`
-				SELECT id_ride_payment, qt_presence 
-				FROM presence 
-				WHERE dt_ride = $1
-			`, currentDate)
-			if err != nil {
-				tx.Rollback()
-				return err
-			}
-			dailyTotal := 0
-			dailyPresences := make(map[int]int)
-			for rows.Next() {
-				var pid, qt int
-				if err := rows.Scan(&pid, &qt); err != nil {
-					rows.Close()
-					tx.Rollback()
-					return err
-				}
-				dailyPresences[pid] = qt
-				dailyTotal += qt
+	}
+
+	return nil
+}
+
+// PreviewRideDays mostra a grade de dias que o ride teria, sem gravar nada.
+func (s *Store) PreviewRideDays(ridePayload types.Ride, userId int) (*types.RideDaysPreview, error) {
+	excluded, err := excludedDates(s.db, ridePayload, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	days, skipped := RideDays(ridePayload.DtInit, ridePayload.DtFinish, rideWeekdays(ridePayload), excluded)
+
+	return &types.RideDaysPreview{Days: days, SkippedDays: skipped}, nil
+}
+
+type querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// excludedDates junta as datas dos calendários do usuário vinculados ao ride
+// com as datas excluídas avulsas.
+func excludedDates(q querier, ridePayload types.Ride, userId int) ([]types.CalendarDate, error) {
+	excluded := make([]types.CalendarDate, 0)
+
+	for _, idCalendar := range ridePayload.IdCalendars {
+		var exists bool
+
+		err := q.QueryRow("SELECT EXISTS (SELECT 1 FROM calendar WHERE id_calendar = $1 AND id_user = $2)", idCalendar, userId).Scan(&exists)
+		if err != nil {
+			return nil, err
+		}
+
+		if !exists {
+			return nil, fmt.Errorf("%w: %d", calendar.ErrCalendarNotFound, idCalendar)
+		}
+
+		rows, err := q.Query("SELECT id_calendar, dt_date, ds_date FROM calendar_date WHERE id_calendar = $1", idCalendar)
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			date := types.CalendarDate{}
+			if err := rows.Scan(&date.IdCalendar, &date.DtDate, &date.DsDate); err != nil {
+				rows.Close()
+				return nil, err
 			}
+			excluded = append(excluded, date)
+		}
+		rows.Close()
+	}
+
+	for _, dtExcluded := range ridePayload.DtExcluded {
+		excluded = append(excluded, types.CalendarDate{DtDate: dtExcluded})
+	}
+
+	return excluded, nil
+}
+
+// saveDateRules regrava os calendários e as datas excluídas do ride e
+// retorna as datas que devem ficar fora da grade.
+func saveDateRules(tx *sql.Tx, idRide int, ridePayload types.Ride, userId int) ([]types.CalendarDate, error) {
+	excluded, err := excludedDates(tx, ridePayload, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec("DELETE FROM ride_calendar WHERE id_ride = $1", idRide)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, idCalendar := range ridePayload.IdCalendars {
+		_, err := tx.Exec("INSERT INTO ride_calendar (id_ride, id_calendar) VALUES ($1, $2) ON CONFLICT DO NOTHING", idRide, idCalendar)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.Exec("DELETE FROM ride_excluded_date WHERE id_ride = $1", idRide)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dtExcluded := range ridePayload.DtExcluded {
+		_, err := tx.Exec("INSERT INTO ride_excluded_date (id_ride, dt_excluded) VALUES ($1, $2) ON CONFLICT DO NOTHING", idRide, dtExcluded)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return excluded, nil
+}
+
+func getDateRules(q querier, ride *types.Ride) error {
+	ride.IdCalendars = make([]int, 0)
+	ride.DtExcluded = make([]time.Time, 0)
+
+	rows, err := q.Query("SELECT id_calendar FROM ride_calendar WHERE id_ride = $1 ORDER BY id_calendar ASC", ride.IdRide)
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		var idCalendar int
+		if err := rows.Scan(&idCalendar); err != nil {
 			rows.Close()
+			return err
+		}
+		ride.IdCalendars = append(ride.IdCalendars, idCalendar)
+	}
+	rows.Close()
 
-			if dailyTotal > 0 {
-				dailyCost := ridePayload.VlRide * float64(ridePayload.QtRide)
-				fmt.Println("dailyTotal", dailyCost)
+	rows, err = q.Query("SELECT dt_excluded FROM ride_excluded_date WHERE id_ride = $1 ORDER BY dt_excluded ASC", ride.IdRide)
+	if err != nil {
+		return err
+	}
 
-				for pid, qt := range dailyPresences {
-					share := (float64(qt) / float64(dailyTotal)) * dailyCost
-					paymentTotals[pid] += share
-				}
-			}
+	defer rows.Close()
+
+	for rows.Next() {
+		var dtExcluded time.Time
+		if err := rows.Scan(&dtExcluded); err != nil {
+			return err
 		}
-		currentDate = currentDate.AddDate(0, 0, 1)
+		ride.DtExcluded = append(ride.DtExcluded, dtExcluded)
 	}
 
-	for pid, total := range paymentTotals {
-		_, err = tx.Exec(`
-			UPDATE ride_payment SET vl_payment = $1 WHERE id_ride_payment = $2
-		`, total, pid)
+	return nil
+}
+
+// setParticipation preenche os dias dirigidos e como passageiro de cada
+// participante, usando a mesma grade de dias do cálculo do custo.
+func setParticipation(q querier, ride *types.Ride, presences []types.Presence) error {
+	// Os calendários do ride são sempre do dono, mesmo quando quem consulta é um participante
+	excluded, err := excludedDates(q, *ride, ride.IdUser)
+	if err != nil {
+		return err
+	}
+
+	days, _ := RideDays(ride.DtInit, ride.DtFinish, rideWeekdays(*ride), excluded)
+
+	paymentIDs := make([]int, len(ride.Payments))
+	for i, p := range ride.Payments {
+		paymentIDs[i] = p.IdRidePayment
+	}
+
+	dailyCost := func(day time.Time) types.Money {
+		return DailyCost(*ride, day)
+	}
+
+	participation := RideParticipation(dailyCost, days, paymentIDs, presences)
+
+	for i, p := range ride.Payments {
+		total := participation[p.IdRidePayment]
+		ride.Payments[i].QtDrivingDays = total.QtDrivingDays
+		ride.Payments[i].QtRidingDays = total.QtRidingDays
+		ride.Payments[i].VlDriving = total.VlDriving
+	}
+
+	return nil
+}
+
+func pricingMode(ride types.Ride) string {
+	if ride.TpPricing == "" {
+		return types.PricingFlat
+	}
+
+	return ride.TpPricing
+}
+
+// saveFuelPrices regrava a tabela de preços do combustível do ride.
+func saveFuelPrices(tx *sql.Tx, idRide int, prices []types.FuelPrice) error {
+	_, err := tx.Exec("DELETE FROM ride_fuel_price WHERE id_ride = $1", idRide)
+	if err != nil {
+		return err
+	}
+
+	for _, price := range prices {
+		_, err := tx.Exec(`
+			INSERT INTO ride_fuel_price (id_ride, vl_fuel_price, dt_effective) VALUES ($1, $2, $3)
+			ON CONFLICT (id_ride, dt_effective) DO UPDATE SET vl_fuel_price = EXCLUDED.vl_fuel_price`,
+			idRide, price.VlFuelPrice, price.DtEffective)
 		if err != nil {
-			tx.Rollback()
 			return err
 		}
 	}
 
-	err = tx.Commit()
+	return nil
+}
+
+func getFuelPrices(q querier, ride *types.Ride) error {
+	rows, err := q.Query("SELECT id_fuel_price, vl_fuel_price, dt_effective FROM ride_fuel_price WHERE id_ride = $1 ORDER BY dt_effective ASC", ride.IdRide)
 	if err != nil {
 		return err
 	}
 
+	defer rows.Close()
+
+	ride.FuelPrices = make([]types.FuelPrice, 0)
+
+	for rows.Next() {
+		price := types.FuelPrice{}
+		if err := rows.Scan(&price.IdFuelPrice, &price.VlFuelPrice, &price.DtEffective); err != nil {
+			return err
+		}
+		ride.FuelPrices = append(ride.FuelPrices, price)
+	}
+
 	return nil
 }
 
@@ -387,17 +787,27 @@ func mapKeys(m map[int]bool) []int {
 	return keys
 }
 
-func (s *Store) DeleteRide(id int) error {
-	_, err := s.db.Exec("DELETE FROM ride WHERE id_ride = $1", id)
+func (s *Store) DeleteRide(id int, userId int) error {
+	result, err := s.db.Exec("DELETE FROM ride WHERE id_ride = $1 AND id_user = $2", id, userId)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
 
+	if affected == 0 {
+		return ErrRideNotFound
+	}
+
 	return nil
 }
 
 func scanRowIntoRide(rows *sql.Rows) (*types.Ride, error) {
 	ride := &types.Ride{}
+	var nrWeekdays *int
 
 	err := rows.Scan(
 		&ride.IdRide,
@@ -407,11 +817,23 @@ func scanRowIntoRide(rows *sql.Rows) (*types.Ride, error) {
 		&ride.DtFinish,
 		&ride.FgCountWeekend,
 		&ride.QtRide,
+		&ride.IdUser,
+		&nrWeekdays,
+		&ride.TpPricing,
+		&ride.NrDistance,
+		&ride.NrConsumption,
+		&ride.VlToll,
+		&ride.VlParking,
 	)
 
 	if err != nil {
 		return nil, err
 	}
 
+	// NrWeekdays volta como foi gravado, para que um GET seguido de PUT não
+	// transforme o padrão derivado de FgCountWeekend em explícito
+	ride.NrWeekdays = weekdaysFromColumn(nrWeekdays)
+	ride.NrEffectiveWeekdays = rideWeekdays(*ride).Days()
+
 	return ride, nil
 }
@@ -0,0 +1,47 @@
+package security
+
+import (
+	"net/http"
+
+	"github.com/gfmanica/splitz-backend/service/auth"
+	"github.com/gfmanica/splitz-backend/service/listing"
+	"github.com/gfmanica/splitz-backend/types"
+	"github.com/gfmanica/splitz-backend/utils"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store     types.SecurityStore
+	userStore types.UserStore
+}
+
+func NewHandler(store types.SecurityStore, userStore types.UserStore) *Handler {
+	return &Handler{
+		store:     store,
+		userStore: userStore,
+	}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/user/security-event", auth.WithJWTAuth(h.handleGetSecurityEvents, h.userStore)).Methods(http.MethodGet)
+}
+
+// handleGetSecurityEvents mostra ao usuário os logins, as falhas e os
+// bloqueios da conta, do mais recente para o mais antigo.
+func (h *Handler) handleGetSecurityEvents(w http.ResponseWriter, r *http.Request) {
+	query, err := listing.ParseQuery(r.URL.Query(), Sorts)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	events, err := h.store.GetSecurityEvents(auth.GetUserIDFromContext(r.Context()), query)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, events)
+}
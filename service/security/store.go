@@ -0,0 +1,175 @@
+package security
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/service/listing"
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// GetLockout devolve até quando o email ou o IP estão bloqueados, ou nil.
+func (s *Store) GetLockout(email string, ip string) (*time.Time, error) {
+	var lockedUntil *time.Time
+
+	err := s.db.QueryRow(`
+		SELECT MAX(dt_locked_until) FROM login_attempt
+		WHERE ((tp_key = $1 AND ds_key = $2) OR (tp_key = $3 AND ds_key = $4)) AND dt_locked_until > $5`,
+		KeyAccount, NormalizeEmail(email), KeyIP, ip, time.Now().UTC()).Scan(&lockedUntil)
+
+	return lockedUntil, err
+}
+
+// RecordLoginFailure soma uma falha ao email e ao IP e devolve o bloqueio
+// resultante, se houver. O contador é incrementado no próprio upsert, então
+// requisições simultâneas em instâncias diferentes não perdem falhas.
+func (s *Store) RecordLoginFailure(email string, ip string) (*time.Time, error) {
+	now := time.Now().UTC()
+	var lockedUntil *time.Time
+
+	keys := []struct {
+		tpKey  string
+		dsKey  string
+		policy Policy
+	}{
+		{KeyAccount, NormalizeEmail(email), AccountPolicy()},
+		{KeyIP, ip, IPPolicy()},
+	}
+
+	for _, key := range keys {
+		var failures int
+
+		err := s.db.QueryRow(`
+			INSERT INTO login_attempt (tp_key, ds_key, qt_failures, dt_last_failure) VALUES ($1, $2, 1, $3)
+			ON CONFLICT (tp_key, ds_key) DO UPDATE SET
+				qt_failures = CASE WHEN login_attempt.dt_last_failure < $4 THEN 1 ELSE login_attempt.qt_failures + 1 END,
+				dt_last_failure = EXCLUDED.dt_last_failure
+			RETURNING qt_failures`, key.tpKey, key.dsKey, now, now.Add(-attemptWindow())).Scan(&failures)
+		if err != nil {
+			return nil, err
+		}
+
+		lock := key.policy.LockDuration(failures)
+		if lock == 0 {
+			continue
+		}
+
+		until := now.Add(lock)
+
+		_, err = s.db.Exec(`
+			UPDATE login_attempt SET dt_locked_until = $1
+			WHERE tp_key = $2 AND ds_key = $3 AND (dt_locked_until IS NULL OR dt_locked_until < $1)`,
+			until, key.tpKey, key.dsKey)
+		if err != nil {
+			return nil, err
+		}
+
+		if lockedUntil == nil || until.After(*lockedUntil) {
+			lockedUntil = &until
+		}
+	}
+
+	return lockedUntil, nil
+}
+
+// ResetLoginFailures zera as falhas da conta depois de um login certo. As do
+// IP continuam até saírem da janela.
+func (s *Store) ResetLoginFailures(email string) error {
+	_, err := s.db.Exec("DELETE FROM login_attempt WHERE tp_key = $1 AND ds_key = $2", KeyAccount, NormalizeEmail(email))
+
+	return err
+}
+
+func (s *Store) LogSecurityEvent(event types.SecurityEvent) error {
+	var lockedUntil *time.Time
+	if event.DtLockedUntil != nil {
+		utc := event.DtLockedUntil.UTC()
+		lockedUntil = &utc
+	}
+
+	_, err := s.db.Exec(`
+		INSERT INTO security_event (id_user, tp_event, ds_ip, ds_user_agent, dt_locked_until, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		event.IdUser, event.TpEvent, event.DsIp, truncate(event.DsUserAgent, 255), lockedUntil, time.Now().UTC())
+
+	return err
+}
+
+// Sorts são as chaves de ordenação aceitas em GET /user/security-event.
+var Sorts = map[string]listing.Sort[types.SecurityEvent]{
+	"id": {Expr: "e.id_security_event", Cast: "integer", Value: func(e types.SecurityEvent) string {
+		return strconv.Itoa(e.IdSecurityEvent)
+	}},
+	"createdAt": {Expr: "e.created_at", Cast: "timestamp", Value: func(e types.SecurityEvent) string {
+		return e.CreatedAt.Format("2006-01-02 15:04:05.999999")
+	}},
+}
+
+// GetSecurityEvents lista os eventos do usuário. O filtro status não se
+// aplica; search filtra pelo tipo do evento.
+func (s *Store) GetSecurityEvents(userId int, query types.ListQuery) (*types.Page[types.SecurityEvent], error) {
+	sort := Sorts[query.Sort]
+	builder := &listing.Builder{}
+
+	builder.Where("e.id_user = " + builder.Arg(userId))
+
+	if query.Search != "" {
+		builder.Where("e.tp_event ILIKE " + builder.Arg(listing.Contains(query.Search)))
+	}
+
+	if query.DtFrom != nil {
+		builder.Where("e.created_at >= " + builder.Arg(*query.DtFrom))
+	}
+
+	if query.DtTo != nil {
+		builder.Where("e.created_at < " + builder.Arg(query.DtTo.AddDate(0, 0, 1)))
+	}
+
+	listing.After(builder, sort, "e.id_security_event", query)
+
+	rows, err := s.db.Query(`
+		SELECT e.id_security_event, e.id_user, e.tp_event, e.ds_ip, e.ds_user_agent, e.dt_locked_until, e.created_at
+		FROM security_event e
+		`+builder.WhereClause()+`
+		ORDER BY `+listing.OrderBy(sort, "e.id_security_event", query.Order)+`
+		LIMIT `+builder.Arg(query.Limit+1), builder.Args()...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	events := make([]types.SecurityEvent, 0)
+
+	for rows.Next() {
+		event := types.SecurityEvent{}
+
+		err := rows.Scan(&event.IdSecurityEvent, &event.IdUser, &event.TpEvent, &event.DsIp, &event.DsUserAgent, &event.DtLockedUntil, &event.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, event)
+	}
+
+	return listing.NewPage(events, query, sort, func(e types.SecurityEvent) int { return e.IdSecurityEvent }), nil
+}
+
+func truncate(s string, size int) string {
+	runes := []rune(s)
+	if len(runes) <= size {
+		return s
+	}
+
+	return string(runes[:size])
+}
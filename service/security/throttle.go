@@ -0,0 +1,85 @@
+package security
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/config"
+)
+
+const (
+	KeyAccount = "account"
+	KeyIP      = "ip"
+)
+
+// Policy define a partir de quantas falhas seguidas a chave é bloqueada. Cada
+// falha depois disso dobra o bloqueio, até o máximo.
+type Policy struct {
+	MaxAttempts int
+	Base        time.Duration
+	Max         time.Duration
+}
+
+func (p Policy) LockDuration(failures int) time.Duration {
+	if failures < p.MaxAttempts {
+		return 0
+	}
+
+	lock := p.Base
+	for i := p.MaxAttempts; i < failures && lock < p.Max; i++ {
+		lock *= 2
+	}
+
+	if lock > p.Max {
+		return p.Max
+	}
+
+	return lock
+}
+
+// O IP tem um limite maior, já que vários usuários podem sair pelo mesmo NAT.
+func AccountPolicy() Policy {
+	return Policy{
+		MaxAttempts: int(config.Envs.LoginMaxAttempts),
+		Base:        time.Second * time.Duration(config.Envs.LoginLockoutBaseInSeconds),
+		Max:         time.Second * time.Duration(config.Envs.LoginLockoutMaxInSeconds),
+	}
+}
+
+func IPPolicy() Policy {
+	policy := AccountPolicy()
+	policy.MaxAttempts = int(config.Envs.LoginIPMaxAttempts)
+
+	return policy
+}
+
+func attemptWindow() time.Duration {
+	return time.Second * time.Duration(config.Envs.LoginAttemptWindowInSeconds)
+}
+
+// NormalizeEmail é a chave da conta: o mesmo email com outra caixa conta
+// como a mesma conta.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// ClientIP usa o X-Forwarded-For só com TRUST_PROXY, quando a API está atrás
+// de um proxy que sobrescreve o cabeçalho. Sem isso o cliente escolheria o IP.
+func ClientIP(r *http.Request) string {
+	if config.Envs.TrustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			if ip := net.ParseIP(strings.TrimSpace(strings.Split(forwarded, ",")[0])); ip != nil {
+				return ip.String()
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,65 @@
+package security
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/config"
+)
+
+func TestLockDuration(t *testing.T) {
+	policy := Policy{MaxAttempts: 5, Base: 30 * time.Second, Max: 10 * time.Minute}
+
+	t.Run("should not lock before the limit", func(t *testing.T) {
+		if lock := policy.LockDuration(4); lock != 0 {
+			t.Errorf("expected no lock, got %s", lock)
+		}
+	})
+
+	t.Run("should double the lock for each failure after the limit", func(t *testing.T) {
+		expected := map[int]time.Duration{
+			5: 30 * time.Second,
+			6: time.Minute,
+			7: 2 * time.Minute,
+			9: 8 * time.Minute,
+		}
+
+		for failures, lock := range expected {
+			if got := policy.LockDuration(failures); got != lock {
+				t.Errorf("expected %s after %d failures, got %s", lock, failures, got)
+			}
+		}
+	})
+
+	t.Run("should cap the lock", func(t *testing.T) {
+		if lock := policy.LockDuration(1000); lock != policy.Max {
+			t.Errorf("expected %s, got %s", policy.Max, lock)
+		}
+	})
+}
+
+func TestClientIP(t *testing.T) {
+	trustProxy := config.Envs.TrustProxy
+	defer func() { config.Envs.TrustProxy = trustProxy }()
+
+	req := httptest.NewRequest("POST", "/login", nil)
+	req.RemoteAddr = "10.0.0.1:5000"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+
+	t.Run("should ignore X-Forwarded-For without a trusted proxy", func(t *testing.T) {
+		config.Envs.TrustProxy = false
+
+		if ip := ClientIP(req); ip != "10.0.0.1" {
+			t.Errorf("expected 10.0.0.1, got %s", ip)
+		}
+	})
+
+	t.Run("should use X-Forwarded-For behind a trusted proxy", func(t *testing.T) {
+		config.Envs.TrustProxy = true
+
+		if ip := ClientIP(req); ip != "203.0.113.7" {
+			t.Errorf("expected 203.0.113.7, got %s", ip)
+		}
+	})
+}
@@ -0,0 +1,141 @@
+package statement
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Tamanho A4 em pontos
+const (
+	pageWidth  = 595.0
+	pageHeight = 842.0
+)
+
+// Fontes padrão do PDF, que não precisam ser embutidas no arquivo
+const (
+	fontRegular = "F1"
+	fontBold    = "F2"
+	fontMono    = "F3"
+)
+
+var fonts = []struct {
+	name     string
+	baseFont string
+}{
+	{fontRegular, "Helvetica"},
+	{fontBold, "Helvetica-Bold"},
+	{fontMono, "Courier"},
+}
+
+// pdfDocument é um gerador mínimo de PDF: só texto e linhas, com as fontes
+// padrão e codificação WinAnsi, suficiente para o extrato.
+type pdfDocument struct {
+	pages []*bytes.Buffer
+}
+
+func (d *pdfDocument) newPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+}
+
+func (d *pdfDocument) current() *bytes.Buffer {
+	if len(d.pages) == 0 {
+		d.newPage()
+	}
+
+	return d.pages[len(d.pages)-1]
+}
+
+func (d *pdfDocument) text(x float64, y float64, font string, size float64, s string) {
+	fmt.Fprintf(d.current(), "BT /%s %s Tf %s %s Td (%s) Tj ET\n", font, number(size), number(x), number(y), escapeText(s))
+}
+
+func (d *pdfDocument) line(x1 float64, y1 float64, x2 float64, y2 float64) {
+	fmt.Fprintf(d.current(), "%s %s m %s %s l S\n", number(x1), number(y1), number(x2), number(y2))
+}
+
+// WriteTo grava o documento com a tabela xref apontando o início de cada objeto.
+func (d *pdfDocument) WriteTo(w io.Writer) (int64, error) {
+	if len(d.pages) == 0 {
+		d.newPage()
+	}
+
+	var out bytes.Buffer
+	offsets := make([]int, 0)
+
+	object := func(content string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), content)
+	}
+
+	// Objetos: 1 catálogo, 2 árvore de páginas, as fontes e, para cada página,
+	// o objeto da página seguido do seu conteúdo
+	firstPage := 3 + len(fonts)
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+i*2)
+	}
+
+	fontRefs := make([]string, len(fonts))
+	for i, font := range fonts {
+		fontRefs[i] = fmt.Sprintf("/%s %d 0 R", font.name, 3+i)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+
+	for _, font := range fonts {
+		object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", font.baseFont))
+	}
+
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << %s >> >> /Contents %d 0 R >>",
+			number(pageWidth), number(pageHeight), strings.Join(fontRefs, " "), firstPage+i*2+1))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	n, err := w.Write(out.Bytes())
+
+	return int64(n), err
+}
+
+func number(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// escapeText converte o texto para WinAnsi (Latin-1 cobre os acentos do
+// português) e escapa os caracteres especiais das strings do PDF.
+func escapeText(s string) string {
+	var b strings.Builder
+
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n' || r == '\r' || r == '\t':
+			b.WriteByte(' ')
+		case r < 32:
+			continue
+		case r < 128:
+			b.WriteRune(r)
+		case r <= 255:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+
+	return b.String()
+}
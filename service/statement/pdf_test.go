@@ -0,0 +1,90 @@
+package statement
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+// checkXref confere se cada entrada da xref aponta para o início do objeto.
+func checkXref(t *testing.T, pdf string) int {
+	t.Helper()
+
+	match := regexp.MustCompile(`startxref\n(\d+)\n`).FindStringSubmatch(pdf)
+	if match == nil {
+		t.Fatalf("expected startxref in %q", pdf)
+	}
+
+	start, _ := strconv.Atoi(match[1])
+	if !strings.HasPrefix(pdf[start:], "xref\n") {
+		t.Fatalf("expected xref at %d", start)
+	}
+
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllStringSubmatch(pdf[start:], -1)
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(entry[1])
+		if !strings.HasPrefix(pdf[offset:], fmt.Sprintf("%d 0 obj", i+1)) {
+			t.Errorf("expected object %d at offset %d", i+1, offset)
+		}
+	}
+
+	return len(entries)
+}
+
+func TestRenderPDF(t *testing.T) {
+	generatedAt := time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC)
+
+	t.Run("should render a valid single page statement", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		err := RenderPDF(&buf, types.Statement{
+			DsPerson:      "João (motorista)",
+			Lines:         []types.StatementLine{{TpOrigin: types.PaymentBill, DsOrigin: "Mercado", VlPayment: 3000, VlOutstanding: 3000}},
+			VlTotal:       3000,
+			VlOutstanding: 3000,
+		}, generatedAt)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		pdf := buf.String()
+
+		if !strings.HasPrefix(pdf, "%PDF-1.4") || !strings.HasSuffix(pdf, "%%EOF\n") {
+			t.Errorf("expected a PDF header and trailer")
+		}
+
+		if !strings.Contains(pdf, `Jo\343o \(motorista\)`) || !strings.Contains(pdf, "Remaining balance: 30.00") {
+			t.Errorf("expected the escaped participant name and the balance")
+		}
+
+		// Catálogo, páginas, três fontes, página e conteúdo
+		if objects := checkXref(t, pdf); objects != 7 {
+			t.Errorf("expected 7 objects, got %d", objects)
+		}
+	})
+
+	t.Run("should break long statements into pages", func(t *testing.T) {
+		lines := make([]types.StatementLine, 120)
+		for i := range lines {
+			lines[i] = types.StatementLine{TpOrigin: types.PaymentRide, DsOrigin: "Carona", VlPayment: 100}
+		}
+
+		var buf bytes.Buffer
+		if err := RenderPDF(&buf, types.Statement{DsPerson: "Ana", Lines: lines}, generatedAt); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		pdf := buf.String()
+		checkXref(t, pdf)
+
+		if !strings.Contains(pdf, "/Count 3") || !strings.Contains(pdf, "(Page 3)") {
+			t.Errorf("expected the statement to take 3 pages")
+		}
+	})
+}
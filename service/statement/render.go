@@ -0,0 +1,110 @@
+package statement
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+const (
+	margin     = 40.0
+	rowHeight  = 13.0
+	tableSize  = 9.0
+	tableWidth = pageWidth - 2*margin
+)
+
+// A tabela usa Courier para alinhar as colunas sem precisar das métricas da fonte
+const rowFormat = "%-10s %-4s %-34s %11s %11s %11s %-7s"
+
+// RenderPDF desenha o extrato em A4, repetindo o cabeçalho da tabela a cada página.
+func RenderPDF(w io.Writer, statement types.Statement, generatedAt time.Time) error {
+	doc := &pdfDocument{}
+	page := 0
+	y := 0.0
+
+	newPage := func() {
+		doc.newPage()
+		page++
+		y = pageHeight - margin
+
+		if page == 1 {
+			doc.text(margin, y-14, fontBold, 18, "Statement")
+			doc.text(margin, y-34, fontRegular, 11, statement.DsPerson)
+			doc.text(margin, y-50, fontRegular, 9, "Period: "+period(statement.DtFrom, statement.DtTo))
+			doc.text(margin, y-63, fontRegular, 9, "Amounts in "+statement.CdCurrency+", generated at "+generatedAt.Format("2006-01-02 15:04"))
+			y -= 90
+		}
+
+		doc.text(pageWidth-margin-40, margin-20, fontRegular, 8, fmt.Sprintf("Page %d", page))
+
+		doc.text(margin, y, fontMono, tableSize, fmt.Sprintf(rowFormat, "Date", "Type", "Description", "Amount", "Paid", "Outstanding", "Status"))
+		doc.line(margin, y-4, margin+tableWidth, y-4)
+		y -= rowHeight + 2
+	}
+
+	newPage()
+
+	for _, line := range statement.Lines {
+		if y < margin+rowHeight {
+			newPage()
+		}
+
+		status := "open"
+		if line.FgPayed {
+			status = "paid"
+		}
+
+		doc.text(margin, y, fontMono, tableSize, fmt.Sprintf(rowFormat,
+			line.DtOrigin.Format("2006-01-02"),
+			line.TpOrigin,
+			shorten(line.DsOrigin, 34),
+			line.VlPayment.String(),
+			line.VlPaid.String(),
+			line.VlOutstanding.String(),
+			status,
+		))
+		y -= rowHeight
+	}
+
+	if len(statement.Lines) == 0 {
+		doc.text(margin, y, fontRegular, tableSize, "No bills or rides for this participant in the period.")
+		y -= rowHeight
+	}
+
+	if y < margin+3*rowHeight {
+		newPage()
+	}
+
+	doc.line(margin, y+rowHeight-4, margin+tableWidth, y+rowHeight-4)
+	doc.text(margin, y, fontMono, tableSize, fmt.Sprintf(rowFormat, "", "", "Total", statement.VlTotal.String(), statement.VlPaid.String(), statement.VlOutstanding.String(), ""))
+	y -= 2 * rowHeight
+	doc.text(margin, y, fontBold, 11, "Remaining balance: "+statement.VlOutstanding.String()+" "+statement.CdCurrency)
+
+	_, err := doc.WriteTo(w)
+
+	return err
+}
+
+func period(dtFrom *time.Time, dtTo *time.Time) string {
+	switch {
+	case dtFrom != nil && dtTo != nil:
+		return dtFrom.Format("2006-01-02") + " to " + dtTo.Format("2006-01-02")
+	case dtFrom != nil:
+		return "from " + dtFrom.Format("2006-01-02")
+	case dtTo != nil:
+		return "until " + dtTo.Format("2006-01-02")
+	default:
+		return "all dates"
+	}
+}
+
+func shorten(s string, size int) string {
+	runes := []rune(s)
+	if len(runes) <= size {
+		return s
+	}
+
+	return string(runes[:size-3]) + "..."
+}
@@ -0,0 +1,160 @@
+package statement
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/service/auth"
+	"github.com/gfmanica/splitz-backend/service/bill"
+	"github.com/gfmanica/splitz-backend/service/currency"
+	"github.com/gfmanica/splitz-backend/service/listing"
+	"github.com/gfmanica/splitz-backend/types"
+	"github.com/gfmanica/splitz-backend/utils"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	billStore types.BillStore
+	rideStore types.RideStore
+	rateStore types.ExchangeRateStore
+	userStore types.UserStore
+}
+
+func NewHandler(billStore types.BillStore, rideStore types.RideStore, rateStore types.ExchangeRateStore, userStore types.UserStore) *Handler {
+	return &Handler{
+		billStore: billStore,
+		rideStore: rideStore,
+		rateStore: rateStore,
+		userStore: userStore,
+	}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/statement", auth.WithJWTAuth(h.handleGetStatement, h.userStore)).Methods(http.MethodGet)
+}
+
+// handleGetStatement gera o extrato em PDF de um participante, informado por
+// person (nome) ou idUser, nos bills e rides visíveis ao usuário. O período
+// vem de dtFrom e dtTo, como nas listagens.
+func (h *Handler) handleGetStatement(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+
+	query, err := listing.ParseQuery(values, bill.Sorts)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	participant, err := h.parseParticipant(values)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// Só o período e o participante filtram o extrato
+	query = types.ListQuery{
+		Sort:       "id",
+		Order:      types.OrderDesc,
+		DtFrom:     query.DtFrom,
+		DtTo:       query.DtTo,
+		FgPayments: true,
+	}
+
+	if participant.IdUser == nil {
+		query.Person = participant.DsPerson
+	}
+
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	bills, err := listing.All(query, func(query types.ListQuery) (*types.Page[types.Bill], error) {
+		return h.billStore.GetBills(userId, query)
+	})
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	rides, err := listing.All(query, func(query types.ListQuery) (*types.Page[types.Ride], error) {
+		return h.rideStore.GetRides(userId, query)
+	})
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// O extrato soma bills de moedas diferentes, então tudo vai para a moeda do usuário
+	converter, err := currency.Load(h.rateStore, h.userStore, userId)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	bills, err = converter.Bills(bills)
+
+	if err == nil {
+		rides, err = converter.Rides(rides)
+	}
+
+	if errors.Is(err, currency.ErrMissingRate) {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	statement := BuildStatement(participant, query.DtFrom, query.DtTo, bills, rides)
+	statement.CdCurrency = converter.Home()
+
+	var buf bytes.Buffer
+
+	if err := RenderPDF(&buf, statement, time.Now()); err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `attachment; filename="statement.pdf"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+func (h *Handler) parseParticipant(values url.Values) (Participant, error) {
+	person := strings.TrimSpace(values.Get("person"))
+	rawIdUser := values.Get("idUser")
+
+	if (person == "") == (rawIdUser == "") {
+		return Participant{}, fmt.Errorf("inform either person or idUser")
+	}
+
+	if person != "" {
+		return Participant{DsPerson: person}, nil
+	}
+
+	idUser, err := strconv.Atoi(rawIdUser)
+
+	if err != nil {
+		return Participant{}, fmt.Errorf("invalid idUser %q", rawIdUser)
+	}
+
+	u, err := h.userStore.GetUserByID(idUser)
+
+	if err != nil {
+		return Participant{}, fmt.Errorf("user %d not found", idUser)
+	}
+
+	return Participant{DsPerson: u.Name, IdUser: &idUser}, nil
+}
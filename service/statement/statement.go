@@ -0,0 +1,86 @@
+package statement
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+// Participant identifica de quem é o extrato: pelo usuário vinculado aos
+// pagamentos ou pelo nome da pessoa, sem diferenciar maiúsculas.
+type Participant struct {
+	DsPerson string
+	IdUser   *int
+}
+
+func (p Participant) matches(dsPerson string, idUser *int) bool {
+	if p.IdUser != nil {
+		return idUser != nil && *idUser == *p.IdUser
+	}
+
+	return strings.EqualFold(strings.TrimSpace(dsPerson), strings.TrimSpace(p.DsPerson))
+}
+
+// BuildStatement junta as partes do participante nos bills e rides, já
+// filtrados pelo período, em ordem de data.
+func BuildStatement(participant Participant, dtFrom *time.Time, dtTo *time.Time, bills []types.Bill, rides []types.Ride) types.Statement {
+	statement := types.Statement{
+		DsPerson: participant.DsPerson,
+		IdUser:   participant.IdUser,
+		DtFrom:   dtFrom,
+		DtTo:     dtTo,
+		Lines:    make([]types.StatementLine, 0),
+	}
+
+	for _, bill := range bills {
+		for _, p := range bill.Payments {
+			if !participant.matches(p.DsPerson, p.IdUser) {
+				continue
+			}
+
+			statement.Lines = append(statement.Lines, types.StatementLine{
+				TpOrigin:      types.PaymentBill,
+				IdOrigin:      bill.IdBill,
+				DsOrigin:      bill.DsBill,
+				DtOrigin:      bill.CreatedAt,
+				VlPayment:     p.VlPayment,
+				VlPaid:        p.VlPaid,
+				VlOutstanding: p.VlOutstanding,
+				FgPayed:       p.FgPayed,
+			})
+		}
+	}
+
+	for _, ride := range rides {
+		for _, p := range ride.Payments {
+			if !participant.matches(p.DsPerson, p.IdUser) {
+				continue
+			}
+
+			statement.Lines = append(statement.Lines, types.StatementLine{
+				TpOrigin:      types.PaymentRide,
+				IdOrigin:      ride.IdRide,
+				DsOrigin:      ride.DsRide,
+				DtOrigin:      ride.DtInit,
+				VlPayment:     p.VlPayment,
+				VlPaid:        p.VlPaid,
+				VlOutstanding: p.VlOutstanding,
+				FgPayed:       p.FgPayed,
+			})
+		}
+	}
+
+	sort.SliceStable(statement.Lines, func(i, j int) bool {
+		return statement.Lines[i].DtOrigin.Before(statement.Lines[j].DtOrigin)
+	})
+
+	for _, line := range statement.Lines {
+		statement.VlTotal += line.VlPayment
+		statement.VlPaid += line.VlPaid
+		statement.VlOutstanding += line.VlOutstanding
+	}
+
+	return statement
+}
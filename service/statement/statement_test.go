@@ -0,0 +1,53 @@
+package statement
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+func TestBuildStatement(t *testing.T) {
+	ana := 10
+	march := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	bills := []types.Bill{{
+		IdBill:    1,
+		DsBill:    "Mercado",
+		CreatedAt: march.AddDate(0, 0, 5),
+		Payments: []types.BillPayment{
+			{DsPerson: "Ana", IdUser: &ana, VlPayment: 3000, VlPaid: 1000, VlOutstanding: 2000},
+			{DsPerson: "Bruno", VlPayment: 3000, VlPaid: 3000, FgPayed: true},
+		},
+	}}
+
+	rides := []types.Ride{{
+		IdRide: 2,
+		DsRide: "Carona trabalho",
+		DtInit: march,
+		Payments: []types.RidePayment{
+			{DsPerson: " ana ", VlPayment: 4500},
+			{DsPerson: "Bruno", VlPayment: 1500, VlPaid: 1500, FgPayed: true},
+		},
+	}}
+
+	t.Run("should list the participant shares by name in date order", func(t *testing.T) {
+		statement := BuildStatement(Participant{DsPerson: "ANA"}, nil, nil, bills, rides)
+
+		if len(statement.Lines) != 2 || statement.Lines[0].TpOrigin != types.PaymentRide || statement.Lines[1].TpOrigin != types.PaymentBill {
+			t.Fatalf("expected the ride and then the bill, got %+v", statement.Lines)
+		}
+
+		if statement.VlTotal != 7500 || statement.VlPaid != 1000 || statement.VlOutstanding != 2000 {
+			t.Errorf("unexpected totals %s, %s and %s", statement.VlTotal, statement.VlPaid, statement.VlOutstanding)
+		}
+	})
+
+	t.Run("should match only linked payments when filtering by user", func(t *testing.T) {
+		statement := BuildStatement(Participant{DsPerson: "Ana", IdUser: &ana}, nil, nil, bills, rides)
+
+		if len(statement.Lines) != 1 || statement.Lines[0].IdOrigin != 1 {
+			t.Errorf("expected only the linked bill share, got %+v", statement.Lines)
+		}
+	})
+}
@@ -0,0 +1,42 @@
+package totp
+
+import (
+	"crypto/rand"
+	"strings"
+)
+
+const RecoveryCodes = 10
+
+// Sem 0/o e 1/l, que se confundem quando o código é digitado de um papel.
+const recoveryAlphabet = "23456789abcdefghijkmnpqrstuvwxyz"
+
+// GenerateRecoveryCodes gera códigos no formato xxxxx-xxxxx (50 bits cada).
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+
+	for i := range codes {
+		b := make([]byte, 10)
+
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+
+		for j := range b {
+			b[j] = recoveryAlphabet[int(b[j])%len(recoveryAlphabet)]
+		}
+
+		codes[i] = string(b[:5]) + "-" + string(b[5:])
+	}
+
+	return codes, nil
+}
+
+// NormalizeRecoveryCode ignora maiúsculas, espaços e o hífen, para o hash
+// bater com o código digitado de qualquer jeito.
+func NormalizeRecoveryCode(code string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(strings.TrimSpace(code)))
+}
+
+func IsRecoveryCode(code string) bool {
+	return len(NormalizeRecoveryCode(code)) == 10
+}
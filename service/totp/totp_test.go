@@ -0,0 +1,102 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Segredo dos vetores de teste do RFC 6238 para SHA1
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	t.Run("should match the RFC 6238 test vectors", func(t *testing.T) {
+		key, _ := decodeSecret(rfcSecret)
+
+		vectors := map[int64]string{
+			59:          "94287082",
+			1111111109:  "07081804",
+			1111111111:  "14050471",
+			1234567890:  "89005924",
+			2000000000:  "69279037",
+			20000000000: "65353130",
+		}
+
+		for unix, expected := range vectors {
+			if code := hotp(key, Counter(time.Unix(unix, 0)), 8); code != expected {
+				t.Errorf("expected %s at %d, got %s", expected, unix, code)
+			}
+		}
+	})
+
+	t.Run("should use six digits", func(t *testing.T) {
+		code, err := Code(rfcSecret, time.Unix(59, 0))
+
+		if err != nil || code != "287082" {
+			t.Errorf("expected 287082, got %s (%v)", code, err)
+		}
+	})
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	t.Run("should accept codes from the adjacent periods", func(t *testing.T) {
+		for _, offset := range []time.Duration{-Period * time.Second, 0, Period * time.Second} {
+			code, _ := Code(rfcSecret, now.Add(offset))
+
+			counter, ok := Validate(rfcSecret, code, now)
+			if !ok {
+				t.Errorf("expected code from offset %s to be accepted", offset)
+			}
+
+			if counter != Counter(now.Add(offset)) {
+				t.Errorf("expected counter %d, got %d", Counter(now.Add(offset)), counter)
+			}
+		}
+	})
+
+	t.Run("should reject codes outside the window", func(t *testing.T) {
+		code, _ := Code(rfcSecret, now.Add(-2*Period*time.Second))
+
+		if _, ok := Validate(rfcSecret, code, now); ok {
+			t.Errorf("expected old code to be rejected")
+		}
+	})
+}
+
+func TestURI(t *testing.T) {
+	t.Run("should build the otpauth URI", func(t *testing.T) {
+		uri := URI("JBSWY3DPEHPK3PXP", "Splitz", "ana@example.com")
+
+		if !strings.HasPrefix(uri, "otpauth://totp/Splitz:ana@example.com?") {
+			t.Errorf("unexpected label in %s", uri)
+		}
+
+		if !strings.Contains(uri, "secret=JBSWY3DPEHPK3PXP") || !strings.Contains(uri, "issuer=Splitz") {
+			t.Errorf("expected secret and issuer in %s", uri)
+		}
+	})
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	t.Run("should generate distinct normalized codes", func(t *testing.T) {
+		codes, err := GenerateRecoveryCodes(RecoveryCodes)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		seen := make(map[string]bool)
+		for _, code := range codes {
+			if !IsRecoveryCode(code) || seen[code] {
+				t.Errorf("unexpected code %s", code)
+			}
+			seen[code] = true
+		}
+
+		if NormalizeRecoveryCode(" "+strings.ToUpper(codes[0])+" ") != strings.ReplaceAll(codes[0], "-", "") {
+			t.Errorf("expected the code to be normalized")
+		}
+	})
+}
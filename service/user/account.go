@@ -0,0 +1,295 @@
+package user
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/config"
+	"github.com/gfmanica/splitz-backend/service/auth"
+	"github.com/gfmanica/splitz-backend/types"
+	"github.com/gfmanica/splitz-backend/utils"
+	"github.com/go-playground/validator/v10"
+)
+
+var (
+	ErrInvalidUserToken = fmt.Errorf("invalid or expired token")
+	ErrEmailNotVerified = fmt.Errorf("email not verified")
+)
+
+// CreateUserToken grava um token novo e invalida os anteriores do mesmo tipo,
+// então só o link do último email funciona.
+func (s *Store) CreateUserToken(userId int, tpToken string, dsTokenHash string, dtExpires time.Time) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE user_token SET dt_used = $1 WHERE id_user = $2 AND tp_token = $3 AND dt_used IS NULL",
+		time.Now().UTC(), userId, tpToken)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec("INSERT INTO user_token (id_user, tp_token, ds_token_hash, dt_expires) VALUES ($1, $2, $3, $4)",
+		userId, tpToken, dsTokenHash, dtExpires.UTC())
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// useUserToken marca o token como usado e devolve o usuário dele. O UPDATE só
+// pega tokens ainda válidos, então duas requisições com o mesmo token não
+// passam as duas.
+func useUserToken(tx *sql.Tx, tpToken string, dsTokenHash string) (int, error) {
+	var userId int
+
+	err := tx.QueryRow(`
+		UPDATE user_token SET dt_used = $1
+		WHERE ds_token_hash = $2 AND tp_token = $3 AND dt_used IS NULL AND dt_expires > $1
+		RETURNING id_user`, time.Now().UTC(), dsTokenHash, tpToken).Scan(&userId)
+
+	if err == sql.ErrNoRows {
+		return 0, ErrInvalidUserToken
+	}
+
+	return userId, err
+}
+
+func (s *Store) VerifyEmail(dsTokenHash string) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	userId, err := useUserToken(tx, types.TokenVerifyEmail, dsTokenHash)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if _, err := tx.Exec("UPDATE users SET fg_email_verified = TRUE WHERE id = $1", userId); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	return userId, tx.Commit()
+}
+
+// ResetPassword troca a senha e encerra todas as sessões e chaves de API do
+// usuário. Receber o email também comprova o endereço, então a conta fica
+// verificada.
+func (s *Store) ResetPassword(dsTokenHash string, password string) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	userId, err := useUserToken(tx, types.TokenResetPassword, dsTokenHash)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if _, err := tx.Exec("UPDATE users SET password = $1, fg_email_verified = TRUE WHERE id = $2", password, userId); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if err := revokeAllSessions(tx, userId); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	return userId, tx.Commit()
+}
+
+// sendToken gera o token, grava o hash e envia o link por email.
+func (h *Handler) sendToken(u *types.User, tpToken string) error {
+	token, hash, err := auth.NewToken()
+	if err != nil {
+		return err
+	}
+
+	var email types.Email
+	var expiration int64
+
+	switch tpToken {
+	case types.TokenVerifyEmail:
+		expiration = config.Envs.VerifyEmailExpirationInSeconds
+		email = types.Email{
+			To:      u.Email,
+			Subject: "Confirm your email",
+			Body: fmt.Sprintf("Hi %s,\n\nConfirm your email address by opening the link below:\n\n%s\n\nThe link expires in %s.\n",
+				u.Name, link("/verify-email", token), duration(expiration)),
+		}
+	case types.TokenResetPassword:
+		expiration = config.Envs.ResetPasswordExpirationInSeconds
+		email = types.Email{
+			To:      u.Email,
+			Subject: "Reset your password",
+			Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset your password. If it was you, open the link below:\n\n%s\n\nThe link expires in %s. If you didn't ask for it, ignore this email.\n",
+				u.Name, link("/reset-password", token), duration(expiration)),
+		}
+	default:
+		return fmt.Errorf("unknown token type %q", tpToken)
+	}
+
+	dtExpires := time.Now().Add(time.Second * time.Duration(expiration))
+
+	if err := h.store.CreateUserToken(u.ID, tpToken, hash, dtExpires); err != nil {
+		return err
+	}
+
+	return h.mailer.Send(email)
+}
+
+// sendAccountExists avisa o dono da conta de que alguém tentou se cadastrar
+// com o email dele.
+func (h *Handler) sendAccountExists(u *types.User) error {
+	return h.mailer.Send(types.Email{
+		To:      u.Email,
+		Subject: "You already have an account",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone tried to sign up with this email address, which already has an account. If it was you, sign in or reset your password at:\n\n%s\n\nIf it wasn't you, ignore this email.\n",
+			u.Name, config.Envs.AppURL+"/forgot-password"),
+	})
+}
+
+func link(path string, token string) string {
+	return config.Envs.AppURL + path + "?token=" + url.QueryEscape(token)
+}
+
+func duration(seconds int64) string {
+	return (time.Second * time.Duration(seconds)).String()
+}
+
+func (h *Handler) handleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var payload types.VerifyEmailPayload
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		error := err.(validator.ValidationErrors)
+
+		utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %s", error))
+
+		return
+	}
+
+	_, err := h.store.VerifyEmail(auth.HashToken(payload.Token))
+
+	if errors.Is(err, ErrInvalidUserToken) {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+// handleResendVerification e handleForgotPassword respondem 202 mesmo quando
+// o email não tem conta, para não revelar quais emails estão cadastrados.
+func (h *Handler) handleResendVerification(w http.ResponseWriter, r *http.Request) {
+	var payload types.EmailPayload
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		error := err.(validator.ValidationErrors)
+
+		utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %s", error))
+
+		return
+	}
+
+	if u, err := h.store.GetUserByEmail(payload.Email); err == nil && !u.FgEmailVerified {
+		if err := h.sendToken(u, types.TokenVerifyEmail); err != nil {
+			log.Printf("Error sending verification email to user %d: %v", u.ID, err)
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusAccepted, nil)
+}
+
+func (h *Handler) handleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var payload types.EmailPayload
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		error := err.(validator.ValidationErrors)
+
+		utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %s", error))
+
+		return
+	}
+
+	if u, err := h.store.GetUserByEmail(payload.Email); err == nil {
+		if err := h.sendToken(u, types.TokenResetPassword); err != nil {
+			log.Printf("Error sending password reset email to user %d: %v", u.ID, err)
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusAccepted, nil)
+}
+
+func (h *Handler) handleResetPassword(w http.ResponseWriter, r *http.Request) {
+	var payload types.ResetPasswordPayload
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		error := err.(validator.ValidationErrors)
+
+		utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %s", error))
+
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(payload.Password)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	userId, err := h.store.ResetPassword(auth.HashToken(payload.Token), hashedPassword)
+
+	if errors.Is(err, ErrInvalidUserToken) {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.logEvent(r, userId, types.EventPasswordReset, nil)
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
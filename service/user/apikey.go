@@ -0,0 +1,193 @@
+package user
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/service/auth"
+	"github.com/gfmanica/splitz-backend/types"
+	"github.com/gfmanica/splitz-backend/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+var (
+	ErrApiKeyNotFound = fmt.Errorf("api key not found")
+	ErrInvalidApiKey  = fmt.Errorf("invalid, expired or revoked api key")
+)
+
+const apiKeyColumns = "id_api_key, id_user, ds_name, ds_prefix, tp_scope, dt_expires, dt_last_used, created_at"
+
+func (s *Store) CreateApiKey(key types.ApiKey, dsKeyHash string) (*types.ApiKey, error) {
+	var dtExpires *time.Time
+	if key.DtExpires != nil {
+		utc := key.DtExpires.UTC()
+		dtExpires = &utc
+	}
+
+	row := s.db.QueryRow(`
+		INSERT INTO api_key (id_user, ds_name, ds_prefix, ds_key_hash, tp_scope, dt_expires, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+apiKeyColumns,
+		key.IdUser, key.DsName, key.DsPrefix, dsKeyHash, key.TpScope, dtExpires, time.Now().UTC())
+
+	return scanApiKey(row)
+}
+
+// GetApiKeys lista as chaves não revogadas, inclusive as que já expiraram.
+func (s *Store) GetApiKeys(userId int) ([]types.ApiKey, error) {
+	rows, err := s.db.Query("SELECT "+apiKeyColumns+" FROM api_key WHERE id_user = $1 AND dt_revoked IS NULL ORDER BY id_api_key DESC", userId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	keys := make([]types.ApiKey, 0)
+
+	for rows.Next() {
+		key, err := scanApiKey(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, *key)
+	}
+
+	return keys, nil
+}
+
+func (s *Store) RevokeApiKey(id int, userId int) error {
+	result, err := s.db.Exec("UPDATE api_key SET dt_revoked = $1 WHERE id_api_key = $2 AND id_user = $3 AND dt_revoked IS NULL",
+		time.Now().UTC(), id, userId)
+	if err != nil {
+		return err
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrApiKeyNotFound
+	}
+
+	return nil
+}
+
+// AuthenticateApiKey confere a chave e grava o último uso na mesma consulta.
+func (s *Store) AuthenticateApiKey(dsKeyHash string) (*types.ApiKey, error) {
+	now := time.Now().UTC()
+
+	row := s.db.QueryRow(`
+		UPDATE api_key SET dt_last_used = $1
+		WHERE ds_key_hash = $2 AND dt_revoked IS NULL AND (dt_expires IS NULL OR dt_expires > $1)
+		RETURNING `+apiKeyColumns, now, dsKeyHash)
+
+	key, err := scanApiKey(row)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidApiKey
+	}
+
+	return key, err
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanApiKey(row scanner) (*types.ApiKey, error) {
+	key := &types.ApiKey{}
+
+	err := row.Scan(&key.IdApiKey, &key.IdUser, &key.DsName, &key.DsPrefix, &key.TpScope, &key.DtExpires, &key.DtLastUsed, &key.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (h *Handler) handleGetApiKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.store.GetApiKeys(auth.GetUserIDFromContext(r.Context()))
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, keys)
+}
+
+// handleCreateApiKey devolve a chave inteira uma única vez; depois disso só
+// o hash e o começo dela ficam gravados.
+func (h *Handler) handleCreateApiKey(w http.ResponseWriter, r *http.Request) {
+	var payload types.CreateApiKeyPayload
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		error := err.(validator.ValidationErrors)
+
+		utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %s", error))
+
+		return
+	}
+
+	if payload.DtExpires != nil && !payload.DtExpires.After(time.Now()) {
+		utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("dtExpires must be in the future"))
+		return
+	}
+
+	dsKey, dsPrefix, hash, err := auth.NewApiKey()
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	key, err := h.store.CreateApiKey(types.ApiKey{
+		IdUser:    userId,
+		DsName:    strings.TrimSpace(payload.DsName),
+		DsPrefix:  dsPrefix,
+		TpScope:   payload.TpScope,
+		DtExpires: payload.DtExpires,
+	}, hash)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.logEvent(r, userId, types.EventApiKeyCreated, nil)
+
+	w.Header().Set("Cache-Control", "no-store")
+	utils.WriteJSON(w, http.StatusCreated, types.CreatedApiKey{ApiKey: *key, DsKey: dsKey})
+}
+
+func (h *Handler) handleRevokeApiKey(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	err := h.store.RevokeApiKey(id, userId)
+
+	if errors.Is(err, ErrApiKeyNotFound) {
+		utils.WriterError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.logEvent(r, userId, types.EventApiKeyRevoked, nil)
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
@@ -0,0 +1,154 @@
+package user
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/service/auth"
+	"github.com/gfmanica/splitz-backend/types"
+	"github.com/gorilla/mux"
+)
+
+func serveAs(userId int, method, path string, body []byte, route string, handler http.HandlerFunc) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBuffer(body))
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, userId))
+
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+
+	router.HandleFunc(route, handler)
+	router.ServeHTTP(rr, req)
+
+	return rr
+}
+
+func createApiKey(t *testing.T, handler *Handler, userId int, payload types.CreateApiKeyPayload) types.CreatedApiKey {
+	rr := postAs(userId, handler.handleCreateApiKey, payload)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, rr.Code)
+	}
+
+	var created types.CreatedApiKey
+	json.NewDecoder(rr.Body).Decode(&created)
+
+	return created
+}
+
+// callWithKey chama uma rota protegida pelo middleware e devolve o status e o
+// usuário que chegou no contexto.
+func callWithKey(middleware func(http.HandlerFunc, types.UserStore) http.HandlerFunc, store types.UserStore, method string, key string) (int, int) {
+	userId := 0
+
+	handler := middleware(func(w http.ResponseWriter, r *http.Request) {
+		userId = auth.GetUserIDFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}, store)
+
+	req := httptest.NewRequest(method, "/bill", nil)
+	req.Header.Set("Authorization", "Bearer "+key)
+	rr := httptest.NewRecorder()
+
+	handler(rr, req)
+
+	return rr.Code, userId
+}
+
+func TestApiKeyHandlers(t *testing.T) {
+	t.Run("should authenticate with the key shown on creation", func(t *testing.T) {
+		store := newMockUserStore()
+		handler := NewHandler(store, &mockMailer{}, newMockSecurityStore())
+
+		created := createApiKey(t, handler, 1, types.CreateApiKeyPayload{DsName: "Script", TpScope: types.ScopeWrite})
+
+		if !auth.IsApiKey(created.DsKey) || created.DsKey[:len(created.DsPrefix)] != created.DsPrefix {
+			t.Fatalf("unexpected key %q with prefix %q", created.DsKey, created.DsPrefix)
+		}
+
+		if _, ok := store.apiKeys[auth.HashToken(created.DsKey)]; !ok {
+			t.Errorf("expected only the hash of the key to be stored")
+		}
+
+		code, userId := callWithKey(auth.WithJWTAuth, store, http.MethodPost, created.DsKey)
+
+		if code != http.StatusOK || userId != 1 {
+			t.Errorf("expected user 1 with status %d, got user %d with status %d", http.StatusOK, userId, code)
+		}
+	})
+
+	t.Run("should only allow reads with a read-only key", func(t *testing.T) {
+		store := newMockUserStore()
+		handler := NewHandler(store, &mockMailer{}, newMockSecurityStore())
+
+		created := createApiKey(t, handler, 1, types.CreateApiKeyPayload{DsName: "Dashboard", TpScope: types.ScopeRead})
+
+		if code, _ := callWithKey(auth.WithJWTAuth, store, http.MethodGet, created.DsKey); code != http.StatusOK {
+			t.Errorf("expected status %d on GET, got %d", http.StatusOK, code)
+		}
+
+		if code, _ := callWithKey(auth.WithJWTAuth, store, http.MethodDelete, created.DsKey); code != http.StatusForbidden {
+			t.Errorf("expected status %d on DELETE, got %d", http.StatusForbidden, code)
+		}
+	})
+
+	t.Run("should reject revoked keys", func(t *testing.T) {
+		store := newMockUserStore()
+		handler := NewHandler(store, &mockMailer{}, newMockSecurityStore())
+
+		created := createApiKey(t, handler, 1, types.CreateApiKeyPayload{DsName: "Script", TpScope: types.ScopeWrite})
+
+		if rr := serveAs(2, http.MethodDelete, "/user/api-key/1", nil, "/user/api-key/{id}", handler.handleRevokeApiKey); rr.Code != http.StatusNotFound {
+			t.Errorf("expected status %d revoking another user's key, got %d", http.StatusNotFound, rr.Code)
+		}
+
+		if rr := serveAs(1, http.MethodDelete, "/user/api-key/1", nil, "/user/api-key/{id}", handler.handleRevokeApiKey); rr.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		if code, _ := callWithKey(auth.WithJWTAuth, store, http.MethodGet, created.DsKey); code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, code)
+		}
+	})
+
+	t.Run("should reject keys after logging out of every session", func(t *testing.T) {
+		store := newMockUserStore()
+		handler := NewHandler(store, &mockMailer{}, newMockSecurityStore())
+
+		created := createApiKey(t, handler, 1, types.CreateApiKeyPayload{DsName: "Script", TpScope: types.ScopeWrite})
+
+		if rr := serveAs(1, http.MethodPost, "/logout/all", nil, "/logout/all", handler.handleLogoutAll); rr.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		if code, _ := callWithKey(auth.WithJWTAuth, store, http.MethodGet, created.DsKey); code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, code)
+		}
+	})
+
+	t.Run("should reject expiry dates in the past", func(t *testing.T) {
+		handler := NewHandler(newMockUserStore(), &mockMailer{}, newMockSecurityStore())
+
+		past := time.Now().Add(-time.Hour)
+		rr := postAs(1, handler.handleCreateApiKey, types.CreateApiKeyPayload{DsName: "Script", TpScope: types.ScopeRead, DtExpires: &past})
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("should not accept api keys on session-only routes", func(t *testing.T) {
+		store := newMockUserStore()
+		handler := NewHandler(store, &mockMailer{}, newMockSecurityStore())
+
+		created := createApiKey(t, handler, 1, types.CreateApiKeyPayload{DsName: "Script", TpScope: types.ScopeWrite})
+
+		if code, _ := callWithKey(auth.WithSessionAuth, store, http.MethodPost, created.DsKey); code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, code)
+		}
+	})
+}
@@ -2,10 +2,12 @@ package user
 
 import (
 	"fmt"
+	"log"
 	"net/http"
+	"strings"
 
-	"github.com/gfmanica/splitz-backend/config"
 	"github.com/gfmanica/splitz-backend/service/auth"
+	"github.com/gfmanica/splitz-backend/service/pix"
 	"github.com/gfmanica/splitz-backend/types"
 	"github.com/gfmanica/splitz-backend/utils"
 	"github.com/go-playground/validator/v10"
@@ -13,16 +15,37 @@ import (
 )
 
 type Handler struct {
-	store types.UserStore
+	store    types.UserStore
+	mailer   types.Mailer
+	security types.SecurityStore
 }
 
-func NewHandler(store types.UserStore) *Handler {
-	return &Handler{store: store}
+func NewHandler(store types.UserStore, mailer types.Mailer, security types.SecurityStore) *Handler {
+	return &Handler{store: store, mailer: mailer, security: security}
 }
 
 func (h *Handler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/login", h.handleLogin).Methods(http.MethodPost)
+	router.HandleFunc("/login/2fa", h.handleLoginTwoFactor).Methods(http.MethodPost)
 	router.HandleFunc("/register", h.handleRegister).Methods(http.MethodPost)
+	router.HandleFunc("/refresh", h.handleRefresh).Methods(http.MethodPost)
+	router.HandleFunc("/verify-email", h.handleVerifyEmail).Methods(http.MethodPost)
+	router.HandleFunc("/verify-email/resend", h.handleResendVerification).Methods(http.MethodPost)
+	router.HandleFunc("/password/forgot", h.handleForgotPassword).Methods(http.MethodPost)
+	router.HandleFunc("/password/reset", h.handleResetPassword).Methods(http.MethodPost)
+	router.HandleFunc("/logout", auth.WithSessionAuth(h.handleLogout, h.store)).Methods(http.MethodPost)
+	router.HandleFunc("/logout/all", auth.WithSessionAuth(h.handleLogoutAll, h.store)).Methods(http.MethodPost)
+	router.HandleFunc("/user/pix", auth.WithSessionAuth(h.handleUpdatePixKey, h.store)).Methods(http.MethodPut)
+	router.HandleFunc("/user/pix", auth.WithSessionAuth(h.handleDeletePixKey, h.store)).Methods(http.MethodDelete)
+	router.HandleFunc("/user/currency", auth.WithSessionAuth(h.handleUpdateCurrency, h.store)).Methods(http.MethodPut)
+	router.HandleFunc("/user/2fa/setup", auth.WithSessionAuth(h.handleSetupTwoFactor, h.store)).Methods(http.MethodPost)
+	router.HandleFunc("/user/2fa/qrcode", auth.WithSessionAuth(h.handleGetTwoFactorQRCode, h.store)).Methods(http.MethodGet)
+	router.HandleFunc("/user/2fa/confirm", auth.WithSessionAuth(h.handleConfirmTwoFactor, h.store)).Methods(http.MethodPost)
+	router.HandleFunc("/user/2fa/disable", auth.WithSessionAuth(h.handleDisableTwoFactor, h.store)).Methods(http.MethodPost)
+	router.HandleFunc("/user/2fa/recovery-codes", auth.WithSessionAuth(h.handleRegenerateRecoveryCodes, h.store)).Methods(http.MethodPost)
+	router.HandleFunc("/user/api-key", auth.WithSessionAuth(h.handleGetApiKeys, h.store)).Methods(http.MethodGet)
+	router.HandleFunc("/user/api-key", auth.WithSessionAuth(h.handleCreateApiKey, h.store)).Methods(http.MethodPost)
+	router.HandleFunc("/user/api-key/{id}", auth.WithSessionAuth(h.handleRevokeApiKey, h.store)).Methods(http.MethodDelete)
 }
 
 func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
@@ -31,6 +54,8 @@ func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
 
 	if err := utils.ParseJSON(r, &payload); err != nil {
 		utils.WriterError(w, http.StatusBadRequest, err)
+
+		return
 	}
 
 	// validate the payload
@@ -42,23 +67,41 @@ func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if h.checkLockout(w, r, payload.Email) {
+		return
+	}
+
 	u, err := h.store.GetUserByEmail(payload.Email)
 
 	if err != nil {
-		utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("user with email %s or password  not found", payload.Email))
+		u = nil
+	}
+
+	if !comparePassword(u, payload.Password) {
+		h.loginFailed(w, r, u, payload.Email, types.EventLoginFailed, ErrInvalidCredentials)
+
+		return
+	}
+
+	if err := h.security.ResetLoginFailures(payload.Email); err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
 
 		return
 	}
 
-	fmt.Print(u.Email)
-	if !auth.ComparePassword(u.Password, []byte(payload.Password)) {
-		utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("user with email %s or password  not found", payload.Email))
+	if !u.FgEmailVerified {
+		utils.WriterError(w, http.StatusForbidden, ErrEmailNotVerified)
 
 		return
 	}
 
-	secret := []byte(config.Envs.JWTSecret)
-	token, err := auth.CreateJWT(secret, u)
+	if u.FgTotpEnabled {
+		h.handleLoginChallenge(w, u)
+
+		return
+	}
+
+	tokens, err := h.createSession(u)
 
 	if err != nil {
 		utils.WriterError(w, http.StatusInternalServerError, err)
@@ -66,7 +109,9 @@ func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusOK, map[string]string{"token": token})
+	h.logEvent(r, u.ID, types.EventLoginSucceeded, nil)
+
+	utils.WriteJSON(w, http.StatusOK, tokens)
 }
 
 func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
@@ -86,19 +131,25 @@ func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// check if the user already exists
-	_, err := h.store.GetUserByEmail(payload.Email)
-
-	if err == nil {
-		utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("user with email %s exists", payload.Email))
+	// O hash é calculado antes da busca para que os dois caminhos levem o
+	// mesmo tempo
+	hashedPassword, err := auth.HashPassword(payload.Password)
 
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	hashedPassword, err := auth.HashPassword(payload.Password)
+	// Um email já cadastrado recebe a mesma resposta, e o aviso vai só para
+	// o dono da conta, sem revelar quais emails existem
+	if existing, err := h.store.GetUserByEmail(payload.Email); err == nil {
+		if err := h.sendAccountExists(existing); err != nil {
+			log.Printf("Error sending account exists email to user %d: %v", existing.ID, err)
+		}
 
-	if err != nil {
-		utils.WriterError(w, http.StatusInternalServerError, err)
+		utils.WriteJSON(w, http.StatusCreated, nil)
+
+		return
 	}
 
 	// create the user
@@ -114,6 +165,98 @@ func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Se o email falhar, o usuário pode pedir o reenvio
+	if u, err := h.store.GetUserByEmail(payload.Email); err == nil {
+		if err := h.sendToken(u, types.TokenVerifyEmail); err != nil {
+			log.Printf("Error sending verification email to user %d: %v", u.ID, err)
+		}
+	}
+
 	utils.WriteJSON(w, http.StatusCreated, nil)
 
 }
+
+func (h *Handler) handleUpdatePixKey(w http.ResponseWriter, r *http.Request) {
+	var payload types.UpdatePixKeyPayload
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		error := err.(validator.ValidationErrors)
+
+		utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %s", error))
+
+		return
+	}
+
+	key, err := pix.NormalizeKey(payload.DsPixKey)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	city := strings.TrimSpace(payload.DsPixCity)
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	if err := h.store.UpdatePixKey(userId, &key, &city); err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	u, err := h.store.GetUserByID(userId)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, u)
+}
+
+func (h *Handler) handleDeletePixKey(w http.ResponseWriter, r *http.Request) {
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	if err := h.store.UpdatePixKey(userId, nil, nil); err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+func (h *Handler) handleUpdateCurrency(w http.ResponseWriter, r *http.Request) {
+	var payload types.UpdateCurrencyPayload
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		error := err.(validator.ValidationErrors)
+
+		utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %s", error))
+
+		return
+	}
+
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	if err := h.store.UpdateCurrency(userId, payload.CdCurrency); err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	u, err := h.store.GetUserByID(userId)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, u)
+}
@@ -6,29 +6,403 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/gfmanica/splitz-backend/service/auth"
+	"github.com/gfmanica/splitz-backend/service/security"
 	"github.com/gfmanica/splitz-backend/types"
 	"github.com/gorilla/mux"
 )
 
-type mockUserStore struct{}
+type mockUserStore struct {
+	users         map[string]*types.User
+	tokens        map[string]*types.RefreshToken
+	userTokens    map[string]*mockUserToken
+	totpCounters  map[int]int64
+	recoveryCodes map[int]map[string]bool
+	apiKeys       map[string]*mockApiKey
+}
+
+type mockApiKey struct {
+	key     types.ApiKey
+	revoked bool
+}
+
+type mockUserToken struct {
+	idUser    int
+	tpToken   string
+	dtExpires time.Time
+	used      bool
+}
+
+func newMockUserStore() *mockUserStore {
+	return &mockUserStore{
+		users:         make(map[string]*types.User),
+		tokens:        make(map[string]*types.RefreshToken),
+		userTokens:    make(map[string]*mockUserToken),
+		totpCounters:  make(map[int]int64),
+		recoveryCodes: make(map[int]map[string]bool),
+		apiKeys:       make(map[string]*mockApiKey),
+	}
+}
+
+type mockMailer struct {
+	sent []types.Email
+}
+
+func (m *mockMailer) Send(email types.Email) error {
+	m.sent = append(m.sent, email)
+
+	return nil
+}
 
 func (m *mockUserStore) GetUserByEmail(email string) (*types.User, error) {
-	return nil, fmt.Errorf("user not found")
+	u, ok := m.users[email]
+
+	if !ok {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	return u, nil
 }
 
 func (m *mockUserStore) GetUserByID(id int) (*types.User, error) {
-	return nil, nil
+	for _, u := range m.users {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+
+	return &types.User{ID: id, Email: "gabriel@gmail.com"}, nil
 }
 
 func (m *mockUserStore) CreateUser(u types.User) error {
+	u.ID = len(m.users) + 1
+	m.users[u.Email] = &u
+
+	return nil
+}
+
+func (m *mockUserStore) UpdatePixKey(id int, dsPixKey *string, dsPixCity *string) error {
+	return nil
+}
+
+func (m *mockUserStore) UpdateCurrency(id int, cdCurrency string) error {
+	return nil
+}
+
+func (m *mockUserStore) CreateSession(userId int, dsFamily string, dsTokenHash string, dtExpires time.Time) error {
+	m.tokens[dsTokenHash] = &types.RefreshToken{IdUser: userId, DsFamily: dsFamily, DtExpires: dtExpires}
+
+	return nil
+}
+
+func (m *mockUserStore) RotateRefreshToken(dsTokenHash string, dsNewTokenHash string, dtExpires time.Time) (*types.RefreshToken, error) {
+	token, ok := m.tokens[dsTokenHash]
+
+	if !ok || token.DtRevoked != nil || token.DtExpires.Before(time.Now()) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if token.DtUsed != nil {
+		m.RevokeSession(token.DsFamily, token.IdUser)
+
+		return nil, ErrRefreshTokenReused
+	}
+
+	now := time.Now()
+	token.DtUsed = &now
+
+	return token, m.CreateSession(token.IdUser, token.DsFamily, dsNewTokenHash, dtExpires)
+}
+
+func (m *mockUserStore) IsSessionActive(dsFamily string, userId int) (bool, error) {
+	for _, token := range m.tokens {
+		if token.DsFamily == dsFamily && token.IdUser == userId && token.DtRevoked == nil && token.DtUsed == nil {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (m *mockUserStore) RevokeSession(dsFamily string, userId int) error {
+	now := time.Now()
+
+	for _, token := range m.tokens {
+		if token.DsFamily == dsFamily && token.IdUser == userId {
+			token.DtRevoked = &now
+		}
+	}
+
+	return nil
+}
+
+func (m *mockUserStore) RevokeAllSessions(userId int) error {
+	now := time.Now()
+
+	for _, token := range m.tokens {
+		if token.IdUser == userId {
+			token.DtRevoked = &now
+		}
+	}
+
+	for _, apiKey := range m.apiKeys {
+		if apiKey.key.IdUser == userId {
+			apiKey.revoked = true
+		}
+	}
+
+	return nil
+}
+
+func (m *mockUserStore) CreateUserToken(userId int, tpToken string, dsTokenHash string, dtExpires time.Time) error {
+	m.userTokens[dsTokenHash] = &mockUserToken{idUser: userId, tpToken: tpToken, dtExpires: dtExpires}
+
+	return nil
+}
+
+func (m *mockUserStore) useUserToken(tpToken string, dsTokenHash string) (int, error) {
+	token, ok := m.userTokens[dsTokenHash]
+
+	if !ok || token.used || token.tpToken != tpToken || token.dtExpires.Before(time.Now()) {
+		return 0, ErrInvalidUserToken
+	}
+
+	token.used = true
+
+	return token.idUser, nil
+}
+
+func (m *mockUserStore) VerifyEmail(dsTokenHash string) (int, error) {
+	userId, err := m.useUserToken(types.TokenVerifyEmail, dsTokenHash)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, u := range m.users {
+		if u.ID == userId {
+			u.FgEmailVerified = true
+		}
+	}
+
+	return userId, nil
+}
+
+func (m *mockUserStore) ResetPassword(dsTokenHash string, password string) (int, error) {
+	userId, err := m.useUserToken(types.TokenResetPassword, dsTokenHash)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, u := range m.users {
+		if u.ID == userId {
+			u.Password = password
+		}
+	}
+
+	return userId, m.RevokeAllSessions(userId)
+}
+
+func (m *mockUserStore) SetTotpSecret(userId int, dsTotpSecret string) error {
+	u, _ := m.GetUserByID(userId)
+	if u.FgTotpEnabled {
+		return ErrTwoFactorEnabled
+	}
+
+	u.DsTotpSecret = &dsTotpSecret
+	delete(m.totpCounters, userId)
+
+	return nil
+}
+
+func (m *mockUserStore) EnableTotp(userId int, codeHashes []string) error {
+	u, _ := m.GetUserByID(userId)
+	u.FgTotpEnabled = true
+
+	return m.ReplaceRecoveryCodes(userId, codeHashes)
+}
+
+func (m *mockUserStore) DisableTotp(userId int) error {
+	u, _ := m.GetUserByID(userId)
+	u.DsTotpSecret = nil
+	u.FgTotpEnabled = false
+	delete(m.totpCounters, userId)
+	delete(m.recoveryCodes, userId)
+
 	return nil
 }
 
+func (m *mockUserStore) UseTotpCounter(userId int, nrCounter int64) (bool, error) {
+	if last, ok := m.totpCounters[userId]; ok && last >= nrCounter {
+		return false, nil
+	}
+
+	m.totpCounters[userId] = nrCounter
+
+	return true, nil
+}
+
+func (m *mockUserStore) UseRecoveryCode(userId int, dsCodeHash string) (bool, error) {
+	if !m.recoveryCodes[userId][dsCodeHash] {
+		return false, nil
+	}
+
+	m.recoveryCodes[userId][dsCodeHash] = false
+
+	return true, nil
+}
+
+func (m *mockUserStore) ReplaceRecoveryCodes(userId int, codeHashes []string) error {
+	m.recoveryCodes[userId] = make(map[string]bool)
+
+	for _, hash := range codeHashes {
+		m.recoveryCodes[userId][hash] = true
+	}
+
+	return nil
+}
+
+func (m *mockUserStore) CreateApiKey(key types.ApiKey, dsKeyHash string) (*types.ApiKey, error) {
+	key.IdApiKey = len(m.apiKeys) + 1
+	key.CreatedAt = time.Now()
+	m.apiKeys[dsKeyHash] = &mockApiKey{key: key}
+
+	return &key, nil
+}
+
+func (m *mockUserStore) GetApiKeys(userId int) ([]types.ApiKey, error) {
+	keys := make([]types.ApiKey, 0)
+
+	for _, apiKey := range m.apiKeys {
+		if apiKey.key.IdUser == userId && !apiKey.revoked {
+			keys = append(keys, apiKey.key)
+		}
+	}
+
+	return keys, nil
+}
+
+func (m *mockUserStore) RevokeApiKey(id int, userId int) error {
+	for _, apiKey := range m.apiKeys {
+		if apiKey.key.IdApiKey == id && apiKey.key.IdUser == userId && !apiKey.revoked {
+			apiKey.revoked = true
+
+			return nil
+		}
+	}
+
+	return ErrApiKeyNotFound
+}
+
+func (m *mockUserStore) AuthenticateApiKey(dsKeyHash string) (*types.ApiKey, error) {
+	apiKey, ok := m.apiKeys[dsKeyHash]
+
+	if !ok || apiKey.revoked || (apiKey.key.DtExpires != nil && apiKey.key.DtExpires.Before(time.Now())) {
+		return nil, ErrInvalidApiKey
+	}
+
+	now := time.Now()
+	apiKey.key.DtLastUsed = &now
+
+	return &apiKey.key, nil
+}
+
+// mockSecurityStore segue a mesma política do banco, mas em memória.
+type mockSecurityStore struct {
+	failures    map[string]int
+	lockedUntil map[string]time.Time
+	events      []types.SecurityEvent
+}
+
+func newMockSecurityStore() *mockSecurityStore {
+	return &mockSecurityStore{
+		failures:    make(map[string]int),
+		lockedUntil: make(map[string]time.Time),
+	}
+}
+
+func (m *mockSecurityStore) GetLockout(email string, ip string) (*time.Time, error) {
+	for _, key := range []string{security.KeyAccount + ":" + security.NormalizeEmail(email), security.KeyIP + ":" + ip} {
+		if until, ok := m.lockedUntil[key]; ok && until.After(time.Now()) {
+			return &until, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (m *mockSecurityStore) RecordLoginFailure(email string, ip string) (*time.Time, error) {
+	var lockedUntil *time.Time
+
+	for key, policy := range map[string]security.Policy{
+		security.KeyAccount + ":" + security.NormalizeEmail(email): security.AccountPolicy(),
+		security.KeyIP + ":" + ip:                                  security.IPPolicy(),
+	} {
+		m.failures[key]++
+
+		if lock := policy.LockDuration(m.failures[key]); lock > 0 {
+			until := time.Now().Add(lock)
+			m.lockedUntil[key] = until
+			lockedUntil = &until
+		}
+	}
+
+	return lockedUntil, nil
+}
+
+func (m *mockSecurityStore) ResetLoginFailures(email string) error {
+	delete(m.failures, security.KeyAccount+":"+security.NormalizeEmail(email))
+
+	return nil
+}
+
+func (m *mockSecurityStore) LogSecurityEvent(event types.SecurityEvent) error {
+	m.events = append(m.events, event)
+
+	return nil
+}
+
+func (m *mockSecurityStore) GetSecurityEvents(userId int, query types.ListQuery) (*types.Page[types.SecurityEvent], error) {
+	return &types.Page[types.SecurityEvent]{Items: m.events, Limit: query.Limit}, nil
+}
+
+// tokenFromEmail tira o token do link enviado no email.
+func tokenFromEmail(t *testing.T, email types.Email) string {
+	match := regexp.MustCompile(`\?token=(\S+)`).FindStringSubmatch(email.Body)
+	if match == nil {
+		t.Fatalf("expected a link with a token in %q", email.Body)
+	}
+
+	token, _ := url.QueryUnescape(match[1])
+
+	return token
+}
+
+func post(handler http.HandlerFunc, path string, payload any) *httptest.ResponseRecorder {
+	marshalled, _ := json.Marshal(payload)
+
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewBuffer(marshalled))
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+
+	router.HandleFunc(path, handler)
+	router.ServeHTTP(rr, req)
+
+	return rr
+}
+
+func refresh(handler *Handler, refreshToken string) *httptest.ResponseRecorder {
+	return post(handler.handleRefresh, "/refresh", types.RefreshTokenPayload{RefreshToken: refreshToken})
+}
+
 func TestUserServiceHandlers(t *testing.T) {
-	userStore := &mockUserStore{}
-	handler := NewHandler(userStore)
+	userStore := newMockUserStore()
+	handler := NewHandler(userStore, &mockMailer{}, newMockSecurityStore())
 
 	t.Run("should fail if the user payload is invalid", func(t *testing.T) {
 		paylod := types.RegisterUserPayload{
@@ -81,4 +455,214 @@ func TestUserServiceHandlers(t *testing.T) {
 			t.Errorf("expected status %d, got %d", http.StatusBadRequest, rr.Code)
 		}
 	})
+
+	t.Run("should rotate the refresh token", func(t *testing.T) {
+		handler := NewHandler(newMockUserStore(), &mockMailer{}, newMockSecurityStore())
+
+		session, err := handler.createSession(&types.User{ID: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := refresh(handler, session.RefreshToken)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		var tokens types.TokenPair
+		json.NewDecoder(rr.Body).Decode(&tokens)
+
+		if tokens.Token == "" || tokens.RefreshToken == "" || tokens.RefreshToken == session.RefreshToken {
+			t.Errorf("expected a new token pair, got %+v", tokens)
+		}
+	})
+
+	t.Run("should revoke the session when a refresh token is reused", func(t *testing.T) {
+		store := newMockUserStore()
+		handler := NewHandler(store, &mockMailer{}, newMockSecurityStore())
+
+		session, _ := handler.createSession(&types.User{ID: 1})
+
+		rr := refresh(handler, session.RefreshToken)
+		var tokens types.TokenPair
+		json.NewDecoder(rr.Body).Decode(&tokens)
+
+		if rr := refresh(handler, session.RefreshToken); rr.Code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, rr.Code)
+		}
+
+		if rr := refresh(handler, tokens.RefreshToken); rr.Code != http.StatusUnauthorized {
+			t.Errorf("expected the rotated token to be revoked, got status %d", rr.Code)
+		}
+	})
+
+	t.Run("should reject an unknown refresh token", func(t *testing.T) {
+		handler := NewHandler(newMockUserStore(), &mockMailer{}, newMockSecurityStore())
+
+		if rr := refresh(handler, "unknown"); rr.Code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, rr.Code)
+		}
+	})
+
+	t.Run("should send a verification email on register", func(t *testing.T) {
+		store := newMockUserStore()
+		mailer := &mockMailer{}
+		handler := NewHandler(store, mailer, newMockSecurityStore())
+
+		rr := post(handler.handleRegister, "/register", types.RegisterUserPayload{Name: "Ana", Email: "ana@example.com", Password: "secret"})
+
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d", http.StatusCreated, rr.Code)
+		}
+
+		if len(mailer.sent) != 1 || mailer.sent[0].To != "ana@example.com" {
+			t.Fatalf("expected one email to ana@example.com, got %+v", mailer.sent)
+		}
+
+		token := tokenFromEmail(t, mailer.sent[0])
+
+		if rr := post(handler.handleVerifyEmail, "/verify-email", types.VerifyEmailPayload{Token: token}); rr.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		if !store.users["ana@example.com"].FgEmailVerified {
+			t.Errorf("expected the email to be verified")
+		}
+
+		if rr := post(handler.handleVerifyEmail, "/verify-email", types.VerifyEmailPayload{Token: token}); rr.Code != http.StatusBadRequest {
+			t.Errorf("expected a used token to be rejected, got status %d", rr.Code)
+		}
+	})
+
+	t.Run("should answer an existing email like a new one and warn its owner", func(t *testing.T) {
+		store := newMockUserStore()
+		mailer := &mockMailer{}
+		handler := NewHandler(store, mailer, newMockSecurityStore())
+
+		post(handler.handleRegister, "/register", types.RegisterUserPayload{Name: "Ana", Email: "ana@example.com", Password: "secret"})
+		password := store.users["ana@example.com"].Password
+
+		rr := post(handler.handleRegister, "/register", types.RegisterUserPayload{Name: "Outra", Email: "ana@example.com", Password: "another"})
+
+		if rr.Code != http.StatusCreated {
+			t.Errorf("expected status %d, got %d", http.StatusCreated, rr.Code)
+		}
+
+		if store.users["ana@example.com"].Password != password {
+			t.Errorf("expected the existing account to be unchanged")
+		}
+
+		if len(mailer.sent) != 2 || mailer.sent[1].To != "ana@example.com" || strings.Contains(mailer.sent[1].Body, "token=") {
+			t.Errorf("expected a warning without a token to the owner, got %+v", mailer.sent)
+		}
+	})
+
+	t.Run("should not log in before the email is verified", func(t *testing.T) {
+		store := newMockUserStore()
+		handler := NewHandler(store, &mockMailer{}, newMockSecurityStore())
+
+		post(handler.handleRegister, "/register", types.RegisterUserPayload{Name: "Ana", Email: "ana@example.com", Password: "secret"})
+
+		rr := post(handler.handleLogin, "/login", types.LoginUserPayload{Email: "ana@example.com", Password: "secret"})
+
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("expected status %d, got %d", http.StatusForbidden, rr.Code)
+		}
+	})
+
+	t.Run("should accept forgot password for unknown emails without sending", func(t *testing.T) {
+		mailer := &mockMailer{}
+		handler := NewHandler(newMockUserStore(), mailer, newMockSecurityStore())
+
+		rr := post(handler.handleForgotPassword, "/password/forgot", types.EmailPayload{Email: "nobody@example.com"})
+
+		if rr.Code != http.StatusAccepted {
+			t.Errorf("expected status %d, got %d", http.StatusAccepted, rr.Code)
+		}
+
+		if len(mailer.sent) != 0 {
+			t.Errorf("expected no email, got %+v", mailer.sent)
+		}
+	})
+
+	t.Run("should reset the password and revoke the sessions and api keys", func(t *testing.T) {
+		store := newMockUserStore()
+		mailer := &mockMailer{}
+		handler := NewHandler(store, mailer, newMockSecurityStore())
+
+		store.CreateUser(types.User{Name: "Ana", Email: "ana@example.com", Password: "old"})
+		u := store.users["ana@example.com"]
+		session, _ := handler.createSession(u)
+		created := createApiKey(t, handler, u.ID, types.CreateApiKeyPayload{DsName: "Script", TpScope: types.ScopeWrite})
+
+		post(handler.handleForgotPassword, "/password/forgot", types.EmailPayload{Email: "ana@example.com"})
+
+		if len(mailer.sent) != 1 {
+			t.Fatalf("expected one email, got %+v", mailer.sent)
+		}
+
+		token := tokenFromEmail(t, mailer.sent[0])
+
+		if rr := post(handler.handleResetPassword, "/password/reset", types.ResetPasswordPayload{Token: token, Password: "new-secret"}); rr.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		if !auth.ComparePassword(u.Password, []byte("new-secret")) {
+			t.Errorf("expected the password to be changed")
+		}
+
+		if rr := refresh(handler, session.RefreshToken); rr.Code != http.StatusUnauthorized {
+			t.Errorf("expected the session to be revoked, got status %d", rr.Code)
+		}
+
+		if code, _ := callWithKey(auth.WithJWTAuth, store, http.MethodGet, created.DsKey); code != http.StatusUnauthorized {
+			t.Errorf("expected the api key to be revoked, got status %d", code)
+		}
+
+		if rr := post(handler.handleResetPassword, "/password/reset", types.ResetPasswordPayload{Token: token, Password: "other"}); rr.Code != http.StatusBadRequest {
+			t.Errorf("expected a used token to be rejected, got status %d", rr.Code)
+		}
+	})
+
+	t.Run("should not change account settings with an api key", func(t *testing.T) {
+		store := newMockUserStore()
+		handler := NewHandler(store, &mockMailer{}, newMockSecurityStore())
+
+		router := mux.NewRouter()
+		handler.RegisterRoutes(router)
+
+		created := createApiKey(t, handler, 1, types.CreateApiKeyPayload{DsName: "Script", TpScope: types.ScopeWrite})
+		session, _ := handler.createSession(&types.User{ID: 1})
+
+		for _, tc := range []struct {
+			method  string
+			path    string
+			payload any
+		}{
+			{http.MethodPut, "/user/pix", types.UpdatePixKeyPayload{DsPixKey: "ana@example.com", DsPixCity: "Curitiba"}},
+			{http.MethodDelete, "/user/pix", nil},
+			{http.MethodPut, "/user/currency", types.UpdateCurrencyPayload{CdCurrency: "USD"}},
+		} {
+			marshalled, _ := json.Marshal(tc.payload)
+
+			req := httptest.NewRequest(tc.method, tc.path, bytes.NewBuffer(marshalled))
+			req.Header.Set("Authorization", "Bearer "+created.DsKey)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusUnauthorized {
+				t.Errorf("expected status %d for %s %s with an api key, got %d", http.StatusUnauthorized, tc.method, tc.path, rr.Code)
+			}
+
+			req = httptest.NewRequest(tc.method, tc.path, bytes.NewBuffer(marshalled))
+			req.Header.Set("Authorization", "Bearer "+session.Token)
+			rr = httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code == http.StatusUnauthorized {
+				t.Errorf("expected %s %s to accept a session token", tc.method, tc.path)
+			}
+		}
+	})
 }
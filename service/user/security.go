@@ -0,0 +1,98 @@
+package user
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/service/auth"
+	"github.com/gfmanica/splitz-backend/service/security"
+	"github.com/gfmanica/splitz-backend/types"
+	"github.com/gfmanica/splitz-backend/utils"
+)
+
+// As duas respostas são as mesmas para emails com e sem conta.
+var (
+	ErrInvalidCredentials = fmt.Errorf("invalid email or password")
+	ErrTooManyAttempts    = fmt.Errorf("too many failed attempts, try again later")
+)
+
+var (
+	dummyHash     string
+	dummyHashOnce sync.Once
+)
+
+// comparePassword também roda o bcrypt quando o email não tem conta, para o
+// tempo de resposta não denunciar quais emails estão cadastrados.
+func comparePassword(u *types.User, password string) bool {
+	if u == nil {
+		dummyHashOnce.Do(func() {
+			dummyHash, _ = auth.HashPassword("dummy password")
+		})
+
+		auth.ComparePassword(dummyHash, []byte(password))
+
+		return false
+	}
+
+	return auth.ComparePassword(u.Password, []byte(password))
+}
+
+func (h *Handler) logEvent(r *http.Request, userId int, tpEvent string, lockedUntil *time.Time) {
+	err := h.security.LogSecurityEvent(types.SecurityEvent{
+		IdUser:        userId,
+		TpEvent:       tpEvent,
+		DsIp:          security.ClientIP(r),
+		DsUserAgent:   r.UserAgent(),
+		DtLockedUntil: lockedUntil,
+	})
+
+	if err != nil {
+		log.Printf("Error logging security event %s for user %d: %v", tpEvent, userId, err)
+	}
+}
+
+// checkLockout responde 429 com Retry-After se o email ou o IP estão bloqueados.
+func (h *Handler) checkLockout(w http.ResponseWriter, r *http.Request, email string) bool {
+	lockedUntil, err := h.security.GetLockout(email, security.ClientIP(r))
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return true
+	}
+
+	if lockedUntil == nil {
+		return false
+	}
+
+	retryAfter := math.Ceil(time.Until(*lockedUntil).Seconds())
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Max(retryAfter, 1))))
+	utils.WriterError(w, http.StatusTooManyRequests, ErrTooManyAttempts)
+
+	return true
+}
+
+// loginFailed conta a falha e registra no log de segurança da conta, quando
+// ela existe. A resposta é sempre 401 com o erro informado.
+func (h *Handler) loginFailed(w http.ResponseWriter, r *http.Request, u *types.User, email string, tpEvent string, failure error) {
+	lockedUntil, err := h.security.RecordLoginFailure(email, security.ClientIP(r))
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if u != nil {
+		h.logEvent(r, u.ID, tpEvent, nil)
+
+		if lockedUntil != nil {
+			h.logEvent(r, u.ID, types.EventAccountLocked, lockedUntil)
+		}
+	}
+
+	utils.WriterError(w, http.StatusUnauthorized, failure)
+}
@@ -0,0 +1,121 @@
+package user
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gfmanica/splitz-backend/service/auth"
+	"github.com/gfmanica/splitz-backend/service/security"
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+func verifiedUser(store *mockUserStore) *types.User {
+	password, _ := auth.HashPassword("secret")
+	store.CreateUser(types.User{Name: "Ana", Email: "ana@example.com", Password: password})
+
+	u := store.users["ana@example.com"]
+	u.FgEmailVerified = true
+
+	return u
+}
+
+func TestLoginThrottling(t *testing.T) {
+	t.Run("should answer the same for unknown emails and wrong passwords", func(t *testing.T) {
+		store := newMockUserStore()
+		handler := NewHandler(store, &mockMailer{}, newMockSecurityStore())
+		verifiedUser(store)
+
+		unknown := post(handler.handleLogin, "/login", types.LoginUserPayload{Email: "nobody@example.com", Password: "secret"})
+		wrong := post(handler.handleLogin, "/login", types.LoginUserPayload{Email: "ana@example.com", Password: "wrong"})
+
+		if unknown.Code != http.StatusUnauthorized || wrong.Code != http.StatusUnauthorized {
+			t.Errorf("expected status %d for both, got %d and %d", http.StatusUnauthorized, unknown.Code, wrong.Code)
+		}
+
+		if unknown.Body.String() != wrong.Body.String() {
+			t.Errorf("expected the same body, got %q and %q", unknown.Body.String(), wrong.Body.String())
+		}
+	})
+
+	t.Run("should lock the account after too many failures", func(t *testing.T) {
+		store := newMockUserStore()
+		securityStore := newMockSecurityStore()
+		handler := NewHandler(store, &mockMailer{}, securityStore)
+		u := verifiedUser(store)
+
+		for i := 0; i < security.AccountPolicy().MaxAttempts; i++ {
+			post(handler.handleLogin, "/login", types.LoginUserPayload{Email: "ana@example.com", Password: "wrong"})
+		}
+
+		// Nem a senha certa entra enquanto a conta está bloqueada
+		rr := post(handler.handleLogin, "/login", types.LoginUserPayload{Email: "ANA@example.com", Password: "secret"})
+
+		if rr.Code != http.StatusTooManyRequests {
+			t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, rr.Code)
+		}
+
+		if rr.Header().Get("Retry-After") == "" {
+			t.Errorf("expected a Retry-After header")
+		}
+
+		var failed, locked int
+		for _, event := range securityStore.events {
+			if event.IdUser != u.ID {
+				t.Errorf("expected events of user %d, got %d", u.ID, event.IdUser)
+			}
+
+			switch event.TpEvent {
+			case types.EventLoginFailed:
+				failed++
+			case types.EventAccountLocked:
+				locked++
+
+				if event.DtLockedUntil == nil {
+					t.Errorf("expected the lockout end in the event")
+				}
+			}
+		}
+
+		if failed != security.AccountPolicy().MaxAttempts || locked != 1 {
+			t.Errorf("expected %d failures and 1 lockout, got %d and %d", security.AccountPolicy().MaxAttempts, failed, locked)
+		}
+	})
+
+	t.Run("should lock unknown emails the same way", func(t *testing.T) {
+		handler := NewHandler(newMockUserStore(), &mockMailer{}, newMockSecurityStore())
+
+		for i := 0; i < security.AccountPolicy().MaxAttempts; i++ {
+			post(handler.handleLogin, "/login", types.LoginUserPayload{Email: "nobody@example.com", Password: "wrong"})
+		}
+
+		rr := post(handler.handleLogin, "/login", types.LoginUserPayload{Email: "nobody@example.com", Password: "wrong"})
+
+		if rr.Code != http.StatusTooManyRequests {
+			t.Errorf("expected status %d, got %d", http.StatusTooManyRequests, rr.Code)
+		}
+	})
+
+	t.Run("should reset the failures after a successful login", func(t *testing.T) {
+		store := newMockUserStore()
+		securityStore := newMockSecurityStore()
+		handler := NewHandler(store, &mockMailer{}, securityStore)
+		u := verifiedUser(store)
+
+		for i := 0; i < security.AccountPolicy().MaxAttempts-1; i++ {
+			post(handler.handleLogin, "/login", types.LoginUserPayload{Email: "ana@example.com", Password: "wrong"})
+		}
+
+		if rr := post(handler.handleLogin, "/login", types.LoginUserPayload{Email: "ana@example.com", Password: "secret"}); rr.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		if rr := post(handler.handleLogin, "/login", types.LoginUserPayload{Email: "ana@example.com", Password: "wrong"}); rr.Code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, rr.Code)
+		}
+
+		last := securityStore.events[len(securityStore.events)-2]
+		if last.TpEvent != types.EventLoginSucceeded || last.IdUser != u.ID {
+			t.Errorf("expected a login_succeeded event, got %+v", last)
+		}
+	})
+}
@@ -0,0 +1,136 @@
+package user
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+var (
+	ErrInvalidRefreshToken = fmt.Errorf("invalid refresh token")
+	ErrRefreshTokenReused  = fmt.Errorf("refresh token reused, session revoked")
+)
+
+// CreateSession abre uma família com o primeiro refresh token. As datas de
+// expiração são gravadas e comparadas em UTC.
+func (s *Store) CreateSession(userId int, dsFamily string, dsTokenHash string, dtExpires time.Time) error {
+	_, err := s.db.Exec("INSERT INTO refresh_token (id_user, ds_family, ds_token_hash, dt_expires) VALUES ($1, $2, $3, $4)",
+		userId, dsFamily, dsTokenHash, dtExpires.UTC())
+
+	return err
+}
+
+// RotateRefreshToken troca o refresh token por um novo da mesma família. Um
+// token que já foi trocado só volta a aparecer se vazou, então a família
+// inteira é revogada.
+func (s *Store) RotateRefreshToken(dsTokenHash string, dsNewTokenHash string, dtExpires time.Time) (*types.RefreshToken, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	token := &types.RefreshToken{}
+
+	err = tx.QueryRow(`
+		SELECT id_refresh_token, id_user, ds_family, dt_expires, dt_used, dt_revoked
+		FROM refresh_token
+		WHERE ds_token_hash = $1
+		FOR UPDATE`, dsTokenHash).Scan(&token.IdRefreshToken, &token.IdUser, &token.DsFamily, &token.DtExpires, &token.DtUsed, &token.DtRevoked)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if token.DtRevoked != nil {
+		tx.Rollback()
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if token.DtUsed != nil {
+		if _, err := tx.Exec("UPDATE refresh_token SET dt_revoked = CURRENT_TIMESTAMP WHERE ds_family = $1 AND dt_revoked IS NULL", token.DsFamily); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+
+		return nil, ErrRefreshTokenReused
+	}
+
+	if time.Now().UTC().After(token.DtExpires) {
+		tx.Rollback()
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if _, err := tx.Exec("UPDATE refresh_token SET dt_used = CURRENT_TIMESTAMP WHERE id_refresh_token = $1", token.IdRefreshToken); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	_, err = tx.Exec("INSERT INTO refresh_token (id_user, ds_family, ds_token_hash, dt_expires) VALUES ($1, $2, $3, $4)",
+		token.IdUser, token.DsFamily, dsNewTokenHash, dtExpires.UTC())
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// IsSessionActive indica se a família ainda tem um refresh token válido, ou
+// seja, se a sessão não foi encerrada nem expirou.
+func (s *Store) IsSessionActive(dsFamily string, userId int) (bool, error) {
+	var active bool
+
+	err := s.db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM refresh_token
+			WHERE ds_family = $1 AND id_user = $2 AND dt_revoked IS NULL AND dt_used IS NULL AND dt_expires > $3
+		)`, dsFamily, userId, time.Now().UTC()).Scan(&active)
+
+	return active, err
+}
+
+func (s *Store) RevokeSession(dsFamily string, userId int) error {
+	_, err := s.db.Exec("UPDATE refresh_token SET dt_revoked = CURRENT_TIMESTAMP WHERE ds_family = $1 AND id_user = $2 AND dt_revoked IS NULL", dsFamily, userId)
+
+	return err
+}
+
+// RevokeAllSessions encerra as sessões e revoga as chaves de API do usuário,
+// que de outra forma continuariam valendo depois de um logout geral.
+func (s *Store) RevokeAllSessions(userId int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := revokeAllSessions(tx, userId); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func revokeAllSessions(tx *sql.Tx, userId int) error {
+	if _, err := tx.Exec("UPDATE refresh_token SET dt_revoked = CURRENT_TIMESTAMP WHERE id_user = $1 AND dt_revoked IS NULL", userId); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec("UPDATE api_key SET dt_revoked = CURRENT_TIMESTAMP WHERE id_user = $1 AND dt_revoked IS NULL", userId)
+
+	return err
+}
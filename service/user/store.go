@@ -7,6 +7,8 @@ import (
 	"github.com/gfmanica/splitz-backend/types"
 )
 
+const userColumns = "id, email, password, name, ds_pix_key, ds_pix_city, cd_currency, fg_email_verified, ds_totp_secret, fg_totp_enabled, created_at"
+
 type Store struct {
 	db *sql.DB
 }
@@ -16,7 +18,7 @@ func NewStore(db *sql.DB) *Store {
 }
 
 func (s *Store) GetUserByEmail(email string) (*types.User, error) {
-	rows, err := s.db.Query("SELECT * FROM users WHERE email = $1", email)
+	rows, err := s.db.Query("SELECT "+userColumns+" FROM users WHERE email = $1", email)
 
 	if err != nil {
 		return nil, err
@@ -40,7 +42,7 @@ func (s *Store) GetUserByEmail(email string) (*types.User, error) {
 }
 
 func (s *Store) GetUserByID(id int) (*types.User, error) {
-	rows, err := s.db.Query("SELECT * FROM users WHERE id = $1", id)
+	rows, err := s.db.Query("SELECT "+userColumns+" FROM users WHERE id = $1", id)
 
 	if err != nil {
 		return nil, err
@@ -74,6 +76,20 @@ func (s *Store) CreateUser(u types.User) error {
 	return nil
 }
 
+// UpdatePixKey grava ou, com valores nulos, remove a chave Pix do usuário.
+func (s *Store) UpdatePixKey(id int, dsPixKey *string, dsPixCity *string) error {
+	_, err := s.db.Exec("UPDATE users SET ds_pix_key = $1, ds_pix_city = $2 WHERE id = $3", dsPixKey, dsPixCity, id)
+
+	return err
+}
+
+// UpdateCurrency define a moeda para a qual saldos e exportações são convertidos.
+func (s *Store) UpdateCurrency(id int, cdCurrency string) error {
+	_, err := s.db.Exec("UPDATE users SET cd_currency = $1 WHERE id = $2", cdCurrency, id)
+
+	return err
+}
+
 func scanRowIntoUser(rows *sql.Rows) (*types.User, error) {
 	u := &types.User{}
 
@@ -82,6 +98,12 @@ func scanRowIntoUser(rows *sql.Rows) (*types.User, error) {
 		&u.Email,
 		&u.Password,
 		&u.Name,
+		&u.DsPixKey,
+		&u.DsPixCity,
+		&u.CdCurrency,
+		&u.FgEmailVerified,
+		&u.DsTotpSecret,
+		&u.FgTotpEnabled,
 		&u.CreatedAt)
 
 	if err != nil {
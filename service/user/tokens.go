@@ -0,0 +1,130 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/config"
+	"github.com/gfmanica/splitz-backend/service/auth"
+	"github.com/gfmanica/splitz-backend/types"
+	"github.com/gfmanica/splitz-backend/utils"
+	"github.com/go-playground/validator/v10"
+)
+
+func refreshExpiration() time.Time {
+	return time.Now().Add(time.Second * time.Duration(config.Envs.RefreshTokenExpirationInSeconds))
+}
+
+// createSession abre uma sessão nova (família de refresh tokens) e emite o
+// primeiro par de tokens dela.
+func (h *Handler) createSession(u *types.User) (*types.TokenPair, error) {
+	sessionID, err := auth.RandomID()
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, hash, err := auth.NewToken()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.store.CreateSession(u.ID, sessionID, hash, refreshExpiration()); err != nil {
+		return nil, err
+	}
+
+	return tokenPair(u, sessionID, refreshToken)
+}
+
+func tokenPair(u *types.User, sessionID string, refreshToken string) (*types.TokenPair, error) {
+	token, err := auth.CreateJWT([]byte(config.Envs.JWTSecret), u, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.TokenPair{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    config.Envs.JWTExpirationInSeconds,
+	}, nil
+}
+
+// handleRefresh troca o refresh token por um par novo. O token enviado deixa
+// de valer, e reenviá-lo depois revoga a sessão.
+func (h *Handler) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	var payload types.RefreshTokenPayload
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		error := err.(validator.ValidationErrors)
+
+		utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %s", error))
+
+		return
+	}
+
+	refreshToken, hash, err := auth.NewToken()
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	previous, err := h.store.RotateRefreshToken(auth.HashToken(payload.RefreshToken), hash, refreshExpiration())
+
+	if errors.Is(err, ErrInvalidRefreshToken) || errors.Is(err, ErrRefreshTokenReused) {
+		utils.WriterError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	u, err := h.store.GetUserByID(previous.IdUser)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusUnauthorized, ErrInvalidRefreshToken)
+		return
+	}
+
+	tokens, err := tokenPair(u, previous.DsFamily, refreshToken)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, tokens)
+}
+
+// handleLogout encerra a sessão do access token usado na requisição.
+func (h *Handler) handleLogout(w http.ResponseWriter, r *http.Request) {
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	if err := h.store.RevokeSession(auth.GetSessionIDFromContext(r.Context()), userId); err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+// handleLogoutAll encerra todas as sessões do usuário, em todos os aparelhos,
+// e revoga as chaves de API.
+func (h *Handler) handleLogoutAll(w http.ResponseWriter, r *http.Request) {
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	if err := h.store.RevokeAllSessions(userId); err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
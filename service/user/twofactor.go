@@ -0,0 +1,507 @@
+package user
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/config"
+	"github.com/gfmanica/splitz-backend/service/auth"
+	"github.com/gfmanica/splitz-backend/service/totp"
+	"github.com/gfmanica/splitz-backend/types"
+	"github.com/gfmanica/splitz-backend/utils"
+	"github.com/go-playground/validator/v10"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// Nome que aparece no app autenticador
+const totpIssuer = "Splitz"
+
+var (
+	ErrTwoFactorEnabled  = fmt.Errorf("two-factor authentication is already enabled")
+	ErrTwoFactorNotSetUp = fmt.Errorf("two-factor authentication is not set up")
+	ErrInvalidCode       = fmt.Errorf("invalid two-factor code")
+)
+
+// SetTotpSecret grava o segredo do setup. Com o 2FA já ativo o segredo não é
+// trocado; para isso é preciso desativar antes.
+func (s *Store) SetTotpSecret(userId int, dsTotpSecret string) error {
+	result, err := s.db.Exec("UPDATE users SET ds_totp_secret = $1, nr_totp_counter = NULL WHERE id = $2 AND fg_totp_enabled = FALSE",
+		dsTotpSecret, userId)
+	if err != nil {
+		return err
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrTwoFactorEnabled
+	}
+
+	return nil
+}
+
+func (s *Store) EnableTotp(userId int, codeHashes []string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE users SET fg_totp_enabled = TRUE WHERE id = $1", userId); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := replaceRecoveryCodes(tx, userId, codeHashes); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Store) DisableTotp(userId int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE users SET ds_totp_secret = NULL, fg_totp_enabled = FALSE, nr_totp_counter = NULL WHERE id = $1", userId); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM recovery_code WHERE id_user = $1", userId); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UseTotpCounter grava o período do código aceito. Um código do mesmo período
+// ou de um anterior já não vale, o que impede repetir um código interceptado.
+func (s *Store) UseTotpCounter(userId int, nrCounter int64) (bool, error) {
+	result, err := s.db.Exec("UPDATE users SET nr_totp_counter = $1 WHERE id = $2 AND (nr_totp_counter IS NULL OR nr_totp_counter < $1)",
+		nrCounter, userId)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+
+	return affected == 1, err
+}
+
+func (s *Store) UseRecoveryCode(userId int, dsCodeHash string) (bool, error) {
+	result, err := s.db.Exec("UPDATE recovery_code SET dt_used = $1 WHERE id_user = $2 AND ds_code_hash = $3 AND dt_used IS NULL",
+		time.Now().UTC(), userId, dsCodeHash)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+
+	return affected == 1, err
+}
+
+func (s *Store) ReplaceRecoveryCodes(userId int, codeHashes []string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := replaceRecoveryCodes(tx, userId, codeHashes); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func replaceRecoveryCodes(tx *sql.Tx, userId int, codeHashes []string) error {
+	if _, err := tx.Exec("DELETE FROM recovery_code WHERE id_user = $1", userId); err != nil {
+		return err
+	}
+
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec("INSERT INTO recovery_code (id_user, ds_code_hash) VALUES ($1, $2)", userId, hash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := totp.GenerateRecoveryCodes(totp.RecoveryCodes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = auth.HashToken(totp.NormalizeRecoveryCode(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// verifyCode confere um código TOTP ou, com o 2FA ativo, um código de
+// recuperação. Os dois são de uso único.
+func (h *Handler) verifyCode(u *types.User, code string) error {
+	if u.DsTotpSecret == nil {
+		return ErrTwoFactorNotSetUp
+	}
+
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+
+	if len(code) == totp.Digits {
+		counter, ok := totp.Validate(*u.DsTotpSecret, code, time.Now())
+		if !ok {
+			return ErrInvalidCode
+		}
+
+		used, err := h.store.UseTotpCounter(u.ID, counter)
+		if err != nil {
+			return err
+		}
+
+		if !used {
+			return ErrInvalidCode
+		}
+
+		return nil
+	}
+
+	if !u.FgTotpEnabled || !totp.IsRecoveryCode(code) {
+		return ErrInvalidCode
+	}
+
+	used, err := h.store.UseRecoveryCode(u.ID, auth.HashToken(totp.NormalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+
+	if !used {
+		return ErrInvalidCode
+	}
+
+	return nil
+}
+
+func writeCodeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrInvalidCode) || errors.Is(err, ErrTwoFactorNotSetUp) {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	utils.WriterError(w, http.StatusInternalServerError, err)
+}
+
+// handleLoginChallenge é o primeiro passo do login com 2FA: a senha já foi
+// conferida e o cliente recebe o token para enviar com o código.
+func (h *Handler) handleLoginChallenge(w http.ResponseWriter, u *types.User) {
+	token, err := auth.CreateChallengeJWT([]byte(config.Envs.JWTSecret), u.ID)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, types.LoginChallenge{
+		TwoFactorRequired: true,
+		ChallengeToken:    token,
+		ExpiresIn:         config.Envs.ChallengeExpirationInSeconds,
+	})
+}
+
+func (h *Handler) handleLoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var payload types.TwoFactorLoginPayload
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		error := err.(validator.ValidationErrors)
+
+		utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %s", error))
+
+		return
+	}
+
+	userId, err := auth.ValidateChallenge(payload.ChallengeToken)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	u, err := h.store.GetUserByID(userId)
+
+	if err != nil || !u.FgTotpEnabled {
+		utils.WriterError(w, http.StatusUnauthorized, auth.ErrInvalidChallenge)
+		return
+	}
+
+	// Os códigos errados contam para o mesmo bloqueio das senhas erradas
+	if h.checkLockout(w, r, u.Email) {
+		return
+	}
+
+	if err := h.verifyCode(u, payload.Code); err != nil {
+		if errors.Is(err, ErrInvalidCode) {
+			h.loginFailed(w, r, u, u.Email, types.EventTwoFactorFailed, err)
+			return
+		}
+
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := h.security.ResetLoginFailures(u.Email); err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	tokens, err := h.createSession(u)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.logEvent(r, u.ID, types.EventLoginSucceeded, nil)
+
+	utils.WriteJSON(w, http.StatusOK, tokens)
+}
+
+// handleSetupTwoFactor gera um segredo novo. O 2FA só passa a valer depois
+// da confirmação com um código do app.
+func (h *Handler) handleSetupTwoFactor(w http.ResponseWriter, r *http.Request) {
+	u, err := h.store.GetUserByID(auth.GetUserIDFromContext(r.Context()))
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if u.FgTotpEnabled {
+		utils.WriterError(w, http.StatusConflict, ErrTwoFactorEnabled)
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	err = h.store.SetTotpSecret(u.ID, secret)
+
+	if errors.Is(err, ErrTwoFactorEnabled) {
+		utils.WriterError(w, http.StatusConflict, err)
+		return
+	}
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	utils.WriteJSON(w, http.StatusOK, types.TwoFactorSetup{
+		DsSecret: secret,
+		DsURI:    totp.URI(secret, totpIssuer, u.Email),
+	})
+}
+
+// handleGetTwoFactorQRCode devolve o QR code do setup pendente como PNG.
+func (h *Handler) handleGetTwoFactorQRCode(w http.ResponseWriter, r *http.Request) {
+	u, err := h.store.GetUserByID(auth.GetUserIDFromContext(r.Context()))
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if u.FgTotpEnabled {
+		utils.WriterError(w, http.StatusConflict, ErrTwoFactorEnabled)
+		return
+	}
+
+	if u.DsTotpSecret == nil {
+		utils.WriterError(w, http.StatusNotFound, ErrTwoFactorNotSetUp)
+		return
+	}
+
+	png, err := qrcode.Encode(totp.URI(*u.DsTotpSecret, totpIssuer, u.Email), qrcode.Medium, 256)
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(png)
+}
+
+// handleConfirmTwoFactor ativa o 2FA e devolve os códigos de recuperação, que
+// não aparecem de novo depois desta resposta.
+func (h *Handler) handleConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var payload types.TwoFactorCodePayload
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		error := err.(validator.ValidationErrors)
+
+		utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %s", error))
+
+		return
+	}
+
+	u, err := h.store.GetUserByID(auth.GetUserIDFromContext(r.Context()))
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if u.FgTotpEnabled {
+		utils.WriterError(w, http.StatusConflict, ErrTwoFactorEnabled)
+		return
+	}
+
+	if err := h.verifyCode(u, payload.Code); err != nil {
+		writeCodeError(w, err)
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := h.store.EnableTotp(u.ID, hashes); err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.logEvent(r, u.ID, types.EventTwoFactorEnabled, nil)
+
+	w.Header().Set("Cache-Control", "no-store")
+	utils.WriteJSON(w, http.StatusOK, types.RecoveryCodes{Codes: codes})
+}
+
+// handleDisableTwoFactor exige a senha e um código, para um access token
+// vazado não bastar para tirar o 2FA.
+func (h *Handler) handleDisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var payload types.DisableTwoFactorPayload
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		error := err.(validator.ValidationErrors)
+
+		utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %s", error))
+
+		return
+	}
+
+	u, err := h.store.GetUserByID(auth.GetUserIDFromContext(r.Context()))
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !u.FgTotpEnabled {
+		utils.WriterError(w, http.StatusBadRequest, ErrTwoFactorNotSetUp)
+		return
+	}
+
+	if !auth.ComparePassword(u.Password, []byte(payload.Password)) {
+		utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("invalid password"))
+		return
+	}
+
+	if err := h.verifyCode(u, payload.Code); err != nil {
+		writeCodeError(w, err)
+		return
+	}
+
+	if err := h.store.DisableTotp(u.ID); err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.logEvent(r, u.ID, types.EventTwoFactorDisabled, nil)
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+// handleRegenerateRecoveryCodes troca todos os códigos de recuperação,
+// inclusive os que ainda não foram usados.
+func (h *Handler) handleRegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	var payload types.TwoFactorCodePayload
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriterError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		error := err.(validator.ValidationErrors)
+
+		utils.WriterError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %s", error))
+
+		return
+	}
+
+	u, err := h.store.GetUserByID(auth.GetUserIDFromContext(r.Context()))
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !u.FgTotpEnabled {
+		utils.WriterError(w, http.StatusBadRequest, ErrTwoFactorNotSetUp)
+		return
+	}
+
+	if err := h.verifyCode(u, payload.Code); err != nil {
+		writeCodeError(w, err)
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+
+	if err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := h.store.ReplaceRecoveryCodes(u.ID, hashes); err != nil {
+		utils.WriterError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	utils.WriteJSON(w, http.StatusOK, types.RecoveryCodes{Codes: codes})
+}
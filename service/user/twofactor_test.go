@@ -0,0 +1,179 @@
+package user
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gfmanica/splitz-backend/service/auth"
+	"github.com/gfmanica/splitz-backend/service/totp"
+	"github.com/gfmanica/splitz-backend/types"
+)
+
+func postAs(userId int, handler http.HandlerFunc, payload any) *httptest.ResponseRecorder {
+	marshalled, _ := json.Marshal(payload)
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBuffer(marshalled))
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, userId))
+	rr := httptest.NewRecorder()
+
+	handler(rr, req)
+
+	return rr
+}
+
+// enrolledUser cria um usuário verificado com o 2FA ativo e devolve o segredo
+// e os códigos de recuperação.
+func enrolledUser(t *testing.T, store *mockUserStore, handler *Handler) (*types.User, string, []string) {
+	password, _ := auth.HashPassword("secret")
+	store.CreateUser(types.User{Name: "Ana", Email: "ana@example.com", Password: password})
+	u := store.users["ana@example.com"]
+	u.FgEmailVerified = true
+
+	rr := postAs(u.ID, handler.handleSetupTwoFactor, nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d on setup, got %d", http.StatusOK, rr.Code)
+	}
+
+	var setup types.TwoFactorSetup
+	json.NewDecoder(rr.Body).Decode(&setup)
+
+	code, _ := totp.Code(setup.DsSecret, time.Now())
+
+	rr = postAs(u.ID, handler.handleConfirmTwoFactor, types.TwoFactorCodePayload{Code: code})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d on confirm, got %d", http.StatusOK, rr.Code)
+	}
+
+	var recovery types.RecoveryCodes
+	json.NewDecoder(rr.Body).Decode(&recovery)
+
+	return u, setup.DsSecret, recovery.Codes
+}
+
+func login(handler *Handler) *httptest.ResponseRecorder {
+	return post(handler.handleLogin, "/login", types.LoginUserPayload{Email: "ana@example.com", Password: "secret"})
+}
+
+func challenge(t *testing.T, handler *Handler) string {
+	rr := login(handler)
+
+	var response types.LoginChallenge
+	json.NewDecoder(rr.Body).Decode(&response)
+
+	if rr.Code != http.StatusOK || !response.TwoFactorRequired || response.ChallengeToken == "" {
+		t.Fatalf("expected a login challenge, got status %d", rr.Code)
+	}
+
+	return response.ChallengeToken
+}
+
+func TestTwoFactorHandlers(t *testing.T) {
+	t.Run("should not enable two-factor with a wrong code", func(t *testing.T) {
+		store := newMockUserStore()
+		handler := NewHandler(store, &mockMailer{}, newMockSecurityStore())
+
+		store.CreateUser(types.User{Name: "Ana", Email: "ana@example.com"})
+		u := store.users["ana@example.com"]
+
+		postAs(u.ID, handler.handleSetupTwoFactor, nil)
+
+		rr := postAs(u.ID, handler.handleConfirmTwoFactor, types.TwoFactorCodePayload{Code: "000000"})
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+
+		if u.FgTotpEnabled {
+			t.Errorf("expected two-factor to stay disabled")
+		}
+	})
+
+	t.Run("should require a code after the password", func(t *testing.T) {
+		store := newMockUserStore()
+		handler := NewHandler(store, &mockMailer{}, newMockSecurityStore())
+
+		_, secret, codes := enrolledUser(t, store, handler)
+
+		if len(codes) != totp.RecoveryCodes {
+			t.Fatalf("expected %d recovery codes, got %d", totp.RecoveryCodes, len(codes))
+		}
+
+		token := challenge(t, handler)
+
+		// Códigos de períodos até o da confirmação já não valem
+		used, _ := totp.Code(secret, time.Now().Add(-totp.Period*time.Second))
+		if rr := post(handler.handleLoginTwoFactor, "/login/2fa", types.TwoFactorLoginPayload{ChallengeToken: token, Code: used}); rr.Code != http.StatusUnauthorized {
+			t.Errorf("expected a reused code to be rejected, got status %d", rr.Code)
+		}
+
+		next, _ := totp.Code(secret, time.Now().Add(totp.Period*time.Second))
+		rr := post(handler.handleLoginTwoFactor, "/login/2fa", types.TwoFactorLoginPayload{ChallengeToken: token, Code: next})
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		var tokens types.TokenPair
+		json.NewDecoder(rr.Body).Decode(&tokens)
+
+		if tokens.Token == "" || tokens.RefreshToken == "" {
+			t.Errorf("expected a token pair, got %+v", tokens)
+		}
+	})
+
+	t.Run("should accept a recovery code only once", func(t *testing.T) {
+		store := newMockUserStore()
+		handler := NewHandler(store, &mockMailer{}, newMockSecurityStore())
+
+		_, _, codes := enrolledUser(t, store, handler)
+
+		payload := types.TwoFactorLoginPayload{ChallengeToken: challenge(t, handler), Code: codes[0]}
+
+		if rr := post(handler.handleLoginTwoFactor, "/login/2fa", payload); rr.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		if rr := post(handler.handleLoginTwoFactor, "/login/2fa", payload); rr.Code != http.StatusUnauthorized {
+			t.Errorf("expected a used recovery code to be rejected, got status %d", rr.Code)
+		}
+	})
+
+	t.Run("should not accept an access token as challenge", func(t *testing.T) {
+		store := newMockUserStore()
+		handler := NewHandler(store, &mockMailer{}, newMockSecurityStore())
+
+		u, secret, _ := enrolledUser(t, store, handler)
+		session, _ := handler.createSession(u)
+		code, _ := totp.Code(secret, time.Now().Add(totp.Period*time.Second))
+
+		rr := post(handler.handleLoginTwoFactor, "/login/2fa", types.TwoFactorLoginPayload{ChallengeToken: session.Token, Code: code})
+
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, rr.Code)
+		}
+	})
+
+	t.Run("should disable two-factor with password and code", func(t *testing.T) {
+		store := newMockUserStore()
+		handler := NewHandler(store, &mockMailer{}, newMockSecurityStore())
+
+		u, _, codes := enrolledUser(t, store, handler)
+
+		if rr := postAs(u.ID, handler.handleDisableTwoFactor, types.DisableTwoFactorPayload{Password: "wrong", Code: codes[0]}); rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d with a wrong password, got %d", http.StatusBadRequest, rr.Code)
+		}
+
+		if rr := postAs(u.ID, handler.handleDisableTwoFactor, types.DisableTwoFactorPayload{Password: "secret", Code: codes[0]}); rr.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		if rr := login(handler); rr.Code != http.StatusOK || u.FgTotpEnabled {
+			t.Errorf("expected login without a challenge, got status %d", rr.Code)
+		}
+	})
+}
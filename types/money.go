@@ -0,0 +1,288 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Money representa um valor exato em centavos. É gravado nas colunas
+// DECIMAL(x, 2) como texto e serializado em JSON como número decimal,
+// então os clientes continuam enviando e recebendo valores como 33.33.
+type Money int64
+
+func NewMoneyFromCents(cents int64) Money {
+	return Money(cents)
+}
+
+func ParseMoney(s string) (Money, error) {
+	v, err := parseFixed2(s)
+
+	return Money(v), err
+}
+
+// parseFixed2 converte um decimal com até duas casas para um inteiro em centésimos.
+func parseFixed2(s string) (int64, error) {
+	return parseFixed(s, 2)
+}
+
+// parseFixed converte um decimal com até places casas para um inteiro
+// escalado por 10^places.
+func parseFixed(s string, places int) (int64, error) {
+	s = strings.TrimSpace(s)
+
+	if s == "" {
+		return 0, fmt.Errorf("invalid amount: empty value")
+	}
+
+	negative := false
+
+	if s[0] == '-' || s[0] == '+' {
+		negative = s[0] == '-'
+		s = s[1:]
+	}
+
+	intPart, fracPart, _ := strings.Cut(s, ".")
+
+	// Só dígitos depois do sinal: recusa "--5", "1.+5" e afins, que o
+	// ParseInt aceitaria em cada parte
+	if intPart == "" && fracPart == "" || !isDigits(intPart) || !isDigits(fracPart) {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+
+	if intPart == "" {
+		intPart = "0"
+	}
+
+	// Aceita zeros à direita vindos do banco, como 10.500
+	fracPart = strings.TrimRight(fracPart, "0")
+
+	if len(fracPart) > places {
+		return 0, fmt.Errorf("invalid amount %q: more than %d decimal places", s, places)
+	}
+
+	for len(fracPart) < places {
+		fracPart += "0"
+	}
+
+	units, err := strconv.ParseInt(intPart, 10, 64)
+
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+
+	cents, err := strconv.ParseInt(fracPart, 10, 64)
+
+	if err != nil || cents < 0 {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+
+	scale := int64(math.Pow10(places))
+
+	if units > (math.MaxInt64-cents)/scale {
+		return 0, fmt.Errorf("invalid amount %q: out of range", s)
+	}
+
+	total := units*scale + cents
+
+	if negative {
+		total = -total
+	}
+
+	return total, nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func formatFixed2(v int64) string {
+	sign := ""
+
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+
+	return fmt.Sprintf("%s%d.%02d", sign, v/100, v%100)
+}
+
+func unmarshalFixed2(data []byte) (int64, error) {
+	if string(data) == "null" {
+		return 0, nil
+	}
+
+	var raw json.Number
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		var s string
+
+		if err := json.Unmarshal(data, &s); err != nil {
+			return 0, fmt.Errorf("invalid amount %s", data)
+		}
+
+		raw = json.Number(s)
+	}
+
+	return parseFixed2(raw.String())
+}
+
+func scanFixed2(src any) (int64, error) {
+	switch v := src.(type) {
+	case nil:
+		return 0, nil
+	case string:
+		return parseFixed2(v)
+	case []byte:
+		return parseFixed2(string(v))
+	case int64:
+		return v * 100, nil
+	case float64:
+		return int64(math.Round(v * 100)), nil
+	default:
+		return 0, fmt.Errorf("cannot scan %T into a decimal", src)
+	}
+}
+
+func (m Money) Cents() int64 {
+	return int64(m)
+}
+
+func (m Money) Mul(n int) Money {
+	return m * Money(n)
+}
+
+func (m Money) String() string {
+	return formatFixed2(int64(m))
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	v, err := unmarshalFixed2(data)
+
+	if err != nil {
+		return err
+	}
+
+	*m = Money(v)
+
+	return nil
+}
+
+func (m *Money) Scan(src any) error {
+	v, err := scanFixed2(src)
+
+	if err != nil {
+		return err
+	}
+
+	*m = Money(v)
+
+	return nil
+}
+
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Percent aplica o percentual ao valor, arredondando para o centavo mais próximo.
+func (m Money) Percent(p Percent) Money {
+	v := int64(m) * int64(p)
+	half := int64(5000)
+
+	if v < 0 {
+		half = -half
+	}
+
+	return Money((v + half) / 10000)
+}
+
+// Allocate divide o valor em n partes que somam exatamente o total,
+// distribuindo os centavos restantes entre as primeiras partes.
+func (m Money) Allocate(n int) []Money {
+	weights := make([]int64, n)
+
+	for i := range weights {
+		weights[i] = 1
+	}
+
+	return m.AllocateByWeights(weights)
+}
+
+// AllocateByWeights divide o valor proporcionalmente aos pesos usando o
+// método dos maiores restos, de forma que a soma das partes seja sempre
+// igual ao total.
+func (m Money) AllocateByWeights(weights []int64) []Money {
+	parts := make([]Money, len(weights))
+
+	var totalWeight int64
+
+	for _, w := range weights {
+		if w > 0 {
+			totalWeight += w
+		}
+	}
+
+	if totalWeight == 0 {
+		return parts
+	}
+
+	total := int64(m)
+	negative := total < 0
+
+	if negative {
+		total = -total
+	}
+
+	remainders := make([]int64, len(weights))
+	var allocated int64
+
+	for i, w := range weights {
+		if w <= 0 {
+			continue
+		}
+
+		share := total * w
+		parts[i] = Money(share / totalWeight)
+		remainders[i] = share % totalWeight
+		allocated += int64(parts[i])
+	}
+
+	order := make([]int, 0, len(weights))
+
+	for i, w := range weights {
+		if w > 0 {
+			order = append(order, i)
+		}
+	}
+
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]] > remainders[order[b]]
+	})
+
+	for i := 0; allocated < total; i++ {
+		parts[order[i%len(order)]]++
+		allocated++
+	}
+
+	if negative {
+		for i := range parts {
+			parts[i] = -parts[i]
+		}
+	}
+
+	return parts
+}
@@ -0,0 +1,93 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMoney(t *testing.T) {
+	t.Run("should split a bill so the shares add up to the total", func(t *testing.T) {
+		total := Money(10000)
+		shares := total.Allocate(3)
+
+		expected := []Money{3334, 3333, 3333}
+
+		var sum Money
+		for i, share := range shares {
+			if share != expected[i] {
+				t.Errorf("expected share %d to be %s, got %s", i, expected[i], share)
+			}
+			sum += share
+		}
+
+		if sum != total {
+			t.Errorf("expected shares to add up to %s, got %s", total, sum)
+		}
+	})
+
+	t.Run("should split by weights using the largest remainders", func(t *testing.T) {
+		shares := Money(1000).AllocateByWeights([]int64{1, 1, 1, 0})
+
+		expected := []Money{334, 333, 333, 0}
+
+		for i, share := range shares {
+			if share != expected[i] {
+				t.Errorf("expected share %d to be %s, got %s", i, expected[i], share)
+			}
+		}
+	})
+
+	t.Run("should round trip through JSON as a decimal number", func(t *testing.T) {
+		var m Money
+
+		if err := json.Unmarshal([]byte("33.3"), &m); err != nil {
+			t.Fatal(err)
+		}
+
+		if m != 3330 {
+			t.Errorf("expected 3330 cents, got %d", m)
+		}
+
+		marshalled, _ := json.Marshal(m)
+
+		if string(marshalled) != "33.30" {
+			t.Errorf("expected 33.30, got %s", marshalled)
+		}
+	})
+
+	t.Run("should reject malformed amounts", func(t *testing.T) {
+		for _, input := range []string{"--5", "+-5", "1.+5", "1.-5", "1-5", "-", ".", "1.2.3", "1e2", "1,50", " - 5"} {
+			if m, err := ParseMoney(input); err == nil {
+				t.Errorf("expected an error for %q, got %d", input, m)
+			}
+		}
+	})
+
+	t.Run("should accept signed and partial decimals", func(t *testing.T) {
+		cases := map[string]Money{"-5": -500, "+5": 500, ".5": 50, "5.": 500, " -0.05 ": -5, "10.500": 1050}
+
+		for input, expected := range cases {
+			if m, err := ParseMoney(input); err != nil || m != expected {
+				t.Errorf("expected %d for %q, got %d (%v)", expected, input, m, err)
+			}
+		}
+	})
+
+	t.Run("should reject amounts with more than two decimal places", func(t *testing.T) {
+		if _, err := ParseMoney("10.005"); err == nil {
+			t.Errorf("expected an error for 10.005")
+		}
+	})
+
+	t.Run("should scan DECIMAL values", func(t *testing.T) {
+		var m Money
+
+		if err := m.Scan("-12.50"); err != nil {
+			t.Fatal(err)
+		}
+
+		if m != -1250 {
+			t.Errorf("expected -1250 cents, got %d", m)
+		}
+	})
+}
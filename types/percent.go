@@ -0,0 +1,51 @@
+package types
+
+import "database/sql/driver"
+
+// Percent representa um percentual com duas casas decimais, guardado em
+// centésimos de ponto percentual (12.5% é 1250).
+type Percent int64
+
+const OneHundredPercent Percent = 10000
+
+func ParsePercent(s string) (Percent, error) {
+	v, err := parseFixed2(s)
+
+	return Percent(v), err
+}
+
+func (p Percent) String() string {
+	return formatFixed2(int64(p))
+}
+
+func (p Percent) MarshalJSON() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+func (p *Percent) UnmarshalJSON(data []byte) error {
+	v, err := unmarshalFixed2(data)
+
+	if err != nil {
+		return err
+	}
+
+	*p = Percent(v)
+
+	return nil
+}
+
+func (p *Percent) Scan(src any) error {
+	v, err := scanFixed2(src)
+
+	if err != nil {
+		return err
+	}
+
+	*p = Percent(v)
+
+	return nil
+}
+
+func (p Percent) Value() (driver.Value, error) {
+	return p.String(), nil
+}
@@ -0,0 +1,137 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Rate é uma taxa de câmbio com seis casas decimais, guardada em milionésimos
+// (5.4321 é 5432100).
+type Rate int64
+
+const (
+	rateScale      = 1000000
+	OneRate   Rate = rateScale
+)
+
+func ParseRate(s string) (Rate, error) {
+	v, err := parseFixed(s, 6)
+
+	return Rate(v), err
+}
+
+func (r Rate) String() string {
+	sign := ""
+	v := int64(r)
+
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+
+	frac := strings.TrimRight(fmt.Sprintf("%06d", v%rateScale), "0")
+	for len(frac) < 2 {
+		frac += "0"
+	}
+
+	return fmt.Sprintf("%s%d.%s", sign, v/rateScale, frac)
+}
+
+// Inverse retorna a taxa da conversão no sentido contrário.
+func (r Rate) Inverse() Rate {
+	if r == 0 {
+		return 0
+	}
+
+	return Rate(roundDiv(big.NewInt(rateScale*rateScale), big.NewInt(int64(r))))
+}
+
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*r = 0
+		return nil
+	}
+
+	var raw json.Number
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		var s string
+
+		if err := json.Unmarshal(data, &s); err != nil {
+			return fmt.Errorf("invalid rate %s", data)
+		}
+
+		raw = json.Number(s)
+	}
+
+	v, err := ParseRate(raw.String())
+	if err != nil {
+		return err
+	}
+
+	*r = v
+
+	return nil
+}
+
+func (r *Rate) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*r = 0
+	case string:
+		return r.parse(v)
+	case []byte:
+		return r.parse(string(v))
+	default:
+		return fmt.Errorf("cannot scan %T into a rate", src)
+	}
+
+	return nil
+}
+
+func (r *Rate) parse(s string) error {
+	v, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+
+	*r = v
+
+	return nil
+}
+
+func (r Rate) Value() (driver.Value, error) {
+	return r.String(), nil
+}
+
+// Convert aplica a taxa ao valor, arredondando para o centavo mais próximo.
+func (m Money) Convert(r Rate) Money {
+	v := new(big.Int).Mul(big.NewInt(int64(m)), big.NewInt(int64(r)))
+
+	return Money(roundDiv(v, big.NewInt(rateScale)))
+}
+
+// roundDiv divide arredondando a metade para longe do zero.
+func roundDiv(a *big.Int, b *big.Int) int64 {
+	quotient, remainder := new(big.Int).QuoRem(a, b, new(big.Int))
+
+	twice := new(big.Int).Abs(remainder)
+	twice.Lsh(twice, 1)
+
+	if twice.Cmp(new(big.Int).Abs(b)) >= 0 {
+		if (a.Sign() < 0) != (b.Sign() < 0) {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+
+	return quotient.Int64()
+}
@@ -0,0 +1,55 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRate(t *testing.T) {
+	t.Run("should parse and format up to six decimal places", func(t *testing.T) {
+		rate, err := ParseRate("5.4321")
+		if err != nil || rate != 5432100 || rate.String() != "5.4321" {
+			t.Errorf("expected 5.4321, got %s (%v)", rate, err)
+		}
+
+		if _, err := ParseRate("1.1234567"); err == nil {
+			t.Errorf("expected an error for more than six decimal places")
+		}
+
+		if OneRate.String() != "1.00" {
+			t.Errorf("expected 1.00, got %s", OneRate)
+		}
+	})
+
+	t.Run("should convert money rounding to the nearest cent", func(t *testing.T) {
+		rate, _ := ParseRate("5.4321")
+
+		if converted := Money(1999).Convert(rate); converted != 10859 {
+			t.Errorf("expected 108.59, got %s", converted)
+		}
+
+		if converted := Money(-1999).Convert(rate); converted != -10859 {
+			t.Errorf("expected -108.59, got %s", converted)
+		}
+	})
+
+	t.Run("should invert the rate", func(t *testing.T) {
+		rate, _ := ParseRate("5")
+
+		if inverse := rate.Inverse(); inverse != 200000 {
+			t.Errorf("expected 0.2, got %s", inverse)
+		}
+	})
+
+	t.Run("should round trip through JSON", func(t *testing.T) {
+		var rate Rate
+		if err := json.Unmarshal([]byte(`"0.187654"`), &rate); err != nil || rate != 187654 {
+			t.Fatalf("expected 0.187654, got %s (%v)", rate, err)
+		}
+
+		marshalled, _ := json.Marshal(rate)
+		if string(marshalled) != "0.187654" {
+			t.Errorf("expected 0.187654, got %s", marshalled)
+		}
+	})
+}
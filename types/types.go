@@ -10,102 +10,804 @@ type RegisterUserPayload struct {
 	Password string `json:"password" validate:"required,min=3,max=130"`
 }
 
+// UpdatePixKeyPayload grava a chave Pix usada nas cobranças dos bills e
+// rides do usuário. A cidade é exigida pelo BR Code.
+type UpdatePixKeyPayload struct {
+	DsPixKey  string `json:"dsPixKey" validate:"required"`
+	DsPixCity string `json:"dsPixCity" validate:"required,max=60"`
+}
+
+type UpdateCurrencyPayload struct {
+	CdCurrency string `json:"cdCurrency" validate:"required,iso4217"`
+}
+
+type RefreshTokenPayload struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
+// TokenPair é a resposta do login e do refresh. ExpiresIn é a duração do
+// access token em segundos.
+type TokenPair struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int64  `json:"expiresIn"`
+}
+
+type RefreshToken struct {
+	IdRefreshToken int
+	IdUser         int
+	DsFamily       string
+	DtExpires      time.Time
+	DtUsed         *time.Time
+	DtRevoked      *time.Time
+}
+
+type VerifyEmailPayload struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// EmailPayload pede o reenvio da verificação ou o link de troca de senha.
+type EmailPayload struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordPayload struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=3,max=130"`
+}
+
+const (
+	TokenVerifyEmail   = "verify_email"
+	TokenResetPassword = "reset_password"
+)
+
+// LoginChallenge é a resposta do login quando o usuário tem 2FA: o token
+// do segundo passo, que vai para POST /login/2fa junto com o código.
+type LoginChallenge struct {
+	TwoFactorRequired bool   `json:"twoFactorRequired"`
+	ChallengeToken    string `json:"challengeToken"`
+	ExpiresIn         int64  `json:"expiresIn"`
+}
+
+// Code aceita tanto o código TOTP quanto um código de recuperação.
+type TwoFactorLoginPayload struct {
+	ChallengeToken string `json:"challengeToken" validate:"required"`
+	Code           string `json:"code" validate:"required"`
+}
+
+type TwoFactorCodePayload struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type DisableTwoFactorPayload struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+type TwoFactorSetup struct {
+	DsSecret string `json:"dsSecret"`
+	DsURI    string `json:"dsUri"`
+}
+
+type RecoveryCodes struct {
+	Codes []string `json:"codes"`
+}
+
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+type CreateApiKeyPayload struct {
+	DsName    string     `json:"dsName" validate:"required,max=100"`
+	TpScope   string     `json:"tpScope" validate:"required,oneof=read write"`
+	DtExpires *time.Time `json:"dtExpires"`
+}
+
+type ApiKey struct {
+	IdApiKey   int        `json:"idApiKey"`
+	IdUser     int        `json:"idUser"`
+	DsName     string     `json:"dsName"`
+	DsPrefix   string     `json:"dsPrefix"`
+	TpScope    string     `json:"tpScope"`
+	DtExpires  *time.Time `json:"dtExpires"`
+	DtLastUsed *time.Time `json:"dtLastUsed"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// CreatedApiKey é a única resposta que traz a chave inteira.
+type CreatedApiKey struct {
+	ApiKey
+	DsKey string `json:"dsKey"`
+}
+
 type LoginUserPayload struct {
 	Email    string `json:"email" validate:"required"`
 	Password string `json:"password" validate:"required"`
 }
 
 type CreateBillPayload struct {
-	DsBill   string        `json:"dsBill" validate:"required"`
-	VlBill   float64       `json:"vlBill" validate:"required"`
-	QtPerson float64       `json:"qtPerson" validate:"required"`
-	Payments []BillPayment `json:"payments,omitempty"`
+	DsBill          string        `json:"dsBill" validate:"required"`
+	VlBill          Money         `json:"vlBill" validate:"required_without=Items"`
+	QtPerson        float64       `json:"qtPerson" validate:"required,gt=0"`
+	TpSplit         string        `json:"tpSplit" validate:"omitempty,oneof=equal shares percentage exact"`
+	PcTax           Percent       `json:"pcTax"`
+	PcServiceCharge Percent       `json:"pcServiceCharge"`
+	PcTip           Percent       `json:"pcTip"`
+	CdCurrency      string        `json:"cdCurrency" validate:"omitempty,iso4217"`
+	Items           []BillItem    `json:"items,omitempty" validate:"dive"`
+	Payments        []BillPayment `json:"payments,omitempty"`
 }
 
 type CreateRidePayload struct {
 	DsRide         string        `json:"dsRide" validate:"required"`
-	VlRide         float64       `json:"vlRide" validate:"required"`
+	TpPricing      string        `json:"tpPricing" validate:"omitempty,oneof=flat fuel"`
+	VlRide         Money         `json:"vlRide" validate:"required_unless=TpPricing fuel"`
 	DtInit         time.Time     `json:"dtInit" validate:"required"`
 	QtRide         int           `json:"qtRide"`
 	DtFinish       time.Time     `json:"dtFinish" validate:"required"`
 	FgCountWeekend bool          `json:"fgCountWeekend"`
-	Payments       []RidePayment `json:"payments" validate:"required"`
+	NrWeekdays     []int         `json:"nrWeekdays" validate:"dive,min=0,max=6"`
+	IdCalendars    []int         `json:"idCalendars"`
+	DtExcluded     []time.Time   `json:"dtExcluded"`
+	NrDistance     float64       `json:"nrDistance" validate:"required_if=TpPricing fuel,min=0"`
+	NrConsumption  float64       `json:"nrConsumption" validate:"required_if=TpPricing fuel,min=0"`
+	VlToll         Money         `json:"vlToll" validate:"min=0"`
+	VlParking      Money         `json:"vlParking" validate:"min=0"`
+	FuelPrices     []FuelPrice   `json:"fuelPrices" validate:"required_if=TpPricing fuel,dive"`
+	Payments       []RidePayment `json:"payments" validate:"required,dive"`
+}
+
+type PreviewRideDaysPayload struct {
+	DtInit         time.Time   `json:"dtInit" validate:"required"`
+	DtFinish       time.Time   `json:"dtFinish" validate:"required"`
+	FgCountWeekend bool        `json:"fgCountWeekend"`
+	NrWeekdays     []int       `json:"nrWeekdays" validate:"dive,min=0,max=6"`
+	IdCalendars    []int       `json:"idCalendars"`
+	DtExcluded     []time.Time `json:"dtExcluded"`
+}
+
+type CreateRecurringBillPayload struct {
+	DsBill       string                 `json:"dsBill" validate:"required"`
+	VlBill       Money                  `json:"vlBill" validate:"required"`
+	QtPerson     float64                `json:"qtPerson" validate:"required,gt=0"`
+	TpSplit      string                 `json:"tpSplit" validate:"omitempty,oneof=equal shares percentage exact"`
+	TpRecurrence string                 `json:"tpRecurrence" validate:"required,oneof=monthly weekly yearly"`
+	NrDay        int                    `json:"nrDay"`
+	NrMonth      int                    `json:"nrMonth"`
+	DtStart      time.Time              `json:"dtStart" validate:"required"`
+	DtFinish     *time.Time             `json:"dtFinish"`
+	Payments     []RecurringBillPayment `json:"payments,omitempty"`
+}
+
+type CreatePaymentTransactionPayload struct {
+	VlPayment Money      `json:"vlPayment" validate:"required,gt=0"`
+	DtPayment *time.Time `json:"dtPayment"`
+	TpMethod  string     `json:"tpMethod" validate:"required,oneof=cash pix transfer card other"`
+	DsNote    string     `json:"dsNote" validate:"max=255"`
+}
+
+type PatchPresencesPayload struct {
+	Presences []Presence `json:"presences" validate:"required,dive"`
+}
+
+type CreateCalendarPayload struct {
+	DsCalendar string         `json:"dsCalendar" validate:"required"`
+	Dates      []CalendarDate `json:"dates" validate:"dive"`
+}
+
+type AddCalendarDatesPayload struct {
+	Dates []CalendarDate `json:"dates" validate:"required,dive"`
+}
+
+type CreateGroupPayload struct {
+	DsGroup string `json:"dsGroup" validate:"required"`
+}
+
+type InviteGroupMemberPayload struct {
+	Email string `json:"email" validate:"required,email"`
 }
 
 type UserStore interface {
 	GetUserByEmail(email string) (*User, error)
 	GetUserByID(id int) (*User, error)
 	CreateUser(u User) error
+	UpdatePixKey(id int, dsPixKey *string, dsPixCity *string) error
+	UpdateCurrency(id int, cdCurrency string) error
+	SessionStore
+	UserTokenStore
+	TwoFactorStore
+	ApiKeyStore
+}
+
+// ApiKeyStore guarda as chaves de API pelo hash. AuthenticateApiKey só
+// devolve chaves ativas e registra o último uso.
+type ApiKeyStore interface {
+	CreateApiKey(key ApiKey, dsKeyHash string) (*ApiKey, error)
+	GetApiKeys(userId int) ([]ApiKey, error)
+	RevokeApiKey(id int, userId int) error
+	AuthenticateApiKey(dsKeyHash string) (*ApiKey, error)
+}
+
+type TwoFactorStore interface {
+	SetTotpSecret(userId int, dsTotpSecret string) error
+	EnableTotp(userId int, codeHashes []string) error
+	DisableTotp(userId int) error
+	UseTotpCounter(userId int, nrCounter int64) (bool, error)
+	UseRecoveryCode(userId int, dsCodeHash string) (bool, error)
+	ReplaceRecoveryCodes(userId int, codeHashes []string) error
+}
+
+// UserTokenStore guarda os tokens de uso único enviados por email. Consumir o
+// token e aplicar a alteração acontecem na mesma transação.
+type UserTokenStore interface {
+	CreateUserToken(userId int, tpToken string, dsTokenHash string, dtExpires time.Time) error
+	VerifyEmail(dsTokenHash string) (int, error)
+	ResetPassword(dsTokenHash string, password string) (int, error)
+}
+
+type Email struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer envia os emails da aplicação. Em desenvolvimento os emails vão para
+// o log ou para arquivos em vez de um servidor SMTP.
+type Mailer interface {
+	Send(email Email) error
+}
+
+// SessionStore guarda as famílias de refresh tokens. Cada família é uma
+// sessão aberta no login, e o access token carrega o id dela.
+type SessionStore interface {
+	CreateSession(userId int, dsFamily string, dsTokenHash string, dtExpires time.Time) error
+	RotateRefreshToken(dsTokenHash string, dsNewTokenHash string, dtExpires time.Time) (*RefreshToken, error)
+	IsSessionActive(dsFamily string, userId int) (bool, error)
+	RevokeSession(dsFamily string, userId int) error
+	RevokeAllSessions(userId int) error
+}
+
+// LoginThrottleStore conta as falhas de login por email e por IP no banco,
+// para o bloqueio valer em todas as instâncias da API.
+type LoginThrottleStore interface {
+	GetLockout(email string, ip string) (*time.Time, error)
+	RecordLoginFailure(email string, ip string) (*time.Time, error)
+	ResetLoginFailures(email string) error
+}
+
+type SecurityStore interface {
+	LoginThrottleStore
+	LogSecurityEvent(event SecurityEvent) error
+	GetSecurityEvents(userId int, query ListQuery) (*Page[SecurityEvent], error)
+}
+
+type ExchangeRateStore interface {
+	GetRates(userId int) ([]ExchangeRate, error)
+	SaveRates(rates []ExchangeRate, userId int) error
+	DeleteRate(id int, userId int) error
 }
 
 type BillStore interface {
-	GetBills(userId int) ([]Bill, error)
-	GetBillById(id int) (*Bill, error)
+	GetBills(userId int, query ListQuery) (*Page[Bill], error)
+	GetBillById(id int, userId int) (*Bill, error)
 	CreateBill(b Bill, userId int) (*Bill, error)
-	UpdateBill(b Bill) error
-	DeleteBill(id int) error
+	UpdateBill(b Bill, userId int) error
+	DeleteBill(id int, userId int) error
+	GetImportedIds(externalIds []string, userId int) (map[string]bool, error)
+	ImportBills(transactions []ImportedTransaction, template Bill, userId int) (*ImportResult, error)
 }
 
 type RideStore interface {
-	GetRides(userId int) ([]Ride, error)
-	GetRideById(id int) (*Ride, error)
+	GetRides(userId int, query ListQuery) (*Page[Ride], error)
+	GetRideById(id int, userId int) (*Ride, error)
 	CreateRide(r Ride, userId int) (*Ride, error)
-	UpdateRide(r Ride) error
-	DeleteRide(id int) error
+	UpdateRide(r Ride, userId int) error
+	DeleteRide(id int, userId int) error
+	PreviewRideDays(r Ride, userId int) (*RideDaysPreview, error)
+	PatchPresences(id int, presences []Presence, userId int) error
+}
+
+type CalendarStore interface {
+	GetCalendars(userId int) ([]Calendar, error)
+	GetCalendarById(id int, userId int) (*Calendar, error)
+	CreateCalendar(c Calendar, userId int) (*Calendar, error)
+	DeleteCalendar(id int, userId int) error
+	AddDates(id int, dates []CalendarDate, userId int) error
+	RemoveDate(id int, date time.Time, userId int) error
+}
+
+type GroupStore interface {
+	GetGroups(userId int) ([]Group, error)
+	GetGroupById(id int, userId int) (*Group, error)
+	CreateGroup(g Group, userId int) (*Group, error)
+	DeleteGroup(id int, userId int) error
+	InviteMember(id int, userId int, memberUserId int) error
+	AcceptInvitation(id int, userId int) error
+	RemoveMember(id int, userId int, memberUserId int) error
+	AreGroupMates(userId int, otherUserId int) (bool, error)
+}
+
+type RecurringBillStore interface {
+	GetRecurringBills(userId int) ([]RecurringBill, error)
+	GetRecurringBillById(id int, userId int) (*RecurringBill, error)
+	CreateRecurringBill(r RecurringBill, userId int) (*RecurringBill, error)
+	UpdateRecurringBill(r RecurringBill, userId int) error
+	DeleteRecurringBill(id int, userId int) error
+	GetActiveRecurringBills() ([]RecurringBill, error)
+	GeneratePeriod(id int, period time.Time, b Bill, userId int) (bool, error)
+}
+
+type PaymentTransactionStore interface {
+	GetTransactions(tpPayment string, idParent int, idPayment int, userId int) ([]PaymentTransaction, error)
+	CreateTransaction(tpPayment string, idParent int, idPayment int, t PaymentTransaction, userId int) (*PaymentTransaction, error)
+	ReverseTransaction(id int, userId int) (*PaymentTransaction, error)
+	GetPixCharge(tpPayment string, idParent int, idPayment int, userId int) (*PixCharge, error)
+}
+
+type BalanceStore interface {
+	GetDebts(userId int) ([]Debt, error)
 }
 
 type User struct {
-	ID        int       `json:"id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	Password  string    `json:"-"`
-	CreatedAt time.Time `json:"createdAt"`
+	ID              int       `json:"id"`
+	Name            string    `json:"name"`
+	Email           string    `json:"email"`
+	Password        string    `json:"-"`
+	DsPixKey        *string   `json:"dsPixKey"`
+	DsPixCity       *string   `json:"dsPixCity"`
+	CdCurrency      string    `json:"cdCurrency"`
+	FgEmailVerified bool      `json:"fgEmailVerified"`
+	DsTotpSecret    *string   `json:"-"`
+	FgTotpEnabled   bool      `json:"fgTotpEnabled"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+const (
+	EventLoginSucceeded    = "login_succeeded"
+	EventLoginFailed       = "login_failed"
+	EventTwoFactorFailed   = "two_factor_failed"
+	EventAccountLocked     = "account_locked"
+	EventPasswordReset     = "password_reset"
+	EventTwoFactorEnabled  = "two_factor_enabled"
+	EventTwoFactorDisabled = "two_factor_disabled"
+	EventApiKeyCreated     = "api_key_created"
+	EventApiKeyRevoked     = "api_key_revoked"
+)
+
+type SecurityEvent struct {
+	IdSecurityEvent int        `json:"idSecurityEvent"`
+	IdUser          int        `json:"idUser"`
+	TpEvent         string     `json:"tpEvent"`
+	DsIp            string     `json:"dsIp"`
+	DsUserAgent     string     `json:"dsUserAgent"`
+	DtLockedUntil   *time.Time `json:"dtLockedUntil,omitempty"`
+	CreatedAt       time.Time  `json:"createdAt"`
 }
 
+type Group struct {
+	IdGroup int           `json:"idGroup"`
+	DsGroup string        `json:"dsGroup"`
+	IdUser  int           `json:"idUser"`
+	Members []GroupMember `json:"members"`
+}
+
+type GroupMember struct {
+	IdGroupMember int    `json:"idGroupMember"`
+	IdGroup       int    `json:"idGroup"`
+	IdUser        int    `json:"idUser"`
+	Name          string `json:"name"`
+	Email         string `json:"email,omitempty"`
+	FgAccepted    bool   `json:"fgAccepted"`
+}
+
+const (
+	SplitEqual      = "equal"
+	SplitShares     = "shares"
+	SplitPercentage = "percentage"
+	SplitExact      = "exact"
+)
+
 type Bill struct {
-	IdBill   int           `json:"idBill"`
-	DsBill   string        `json:"dsBill"`
-	VlBill   float64       `json:"vlBill"`
-	QtPerson float64       `json:"qtPerson"`
-	Payments []BillPayment `json:"payments"`
+	IdBill          int             `json:"idBill"`
+	IdUser          int             `json:"idUser"`
+	DsBill          string          `json:"dsBill"`
+	VlBill          Money           `json:"vlBill"`
+	QtPerson        float64         `json:"qtPerson" validate:"gt=0"`
+	TpSplit         string          `json:"tpSplit" validate:"omitempty,oneof=equal shares percentage exact"`
+	PcTax           Percent         `json:"pcTax"`
+	PcServiceCharge Percent         `json:"pcServiceCharge"`
+	PcTip           Percent         `json:"pcTip"`
+	CdCurrency      string          `json:"cdCurrency" validate:"omitempty,iso4217"`
+	CreatedAt       time.Time       `json:"createdAt"`
+	Items           []BillItem      `json:"items"`
+	Payments        []BillPayment   `json:"payments"`
+	Summary         *PaymentSummary `json:"summary,omitempty"`
+}
+
+// PaymentSummary resume os pagamentos de um bill ou ride nas listagens.
+type PaymentSummary struct {
+	VlTotal       Money `json:"vlTotal"`
+	VlPaid        Money `json:"vlPaid"`
+	VlOutstanding Money `json:"vlOutstanding"`
+	QtPayments    int   `json:"qtPayments"`
+	QtPayed       int   `json:"qtPayed"`
+	FgSettled     bool  `json:"fgSettled"`
+}
+
+const (
+	StatusSettled   = "settled"
+	StatusUnsettled = "unsettled"
+)
+
+const (
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
+// ListQuery são os filtros, a ordenação e a paginação das listagens.
+type ListQuery struct {
+	Search     string
+	Status     string
+	Person     string
+	DtFrom     *time.Time
+	DtTo       *time.Time
+	Sort       string
+	Order      string
+	Limit      int
+	Cursor     *Cursor
+	FgPayments bool
+	FgSummary  bool
+}
+
+// Cursor aponta o último item da página anterior pela chave de ordenação e pelo id.
+type Cursor struct {
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+	Value string `json:"v"`
+	Id    int    `json:"i"`
+}
+
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	Limit      int    `json:"limit"`
+	FgHasMore  bool   `json:"fgHasMore"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+type BillItem struct {
+	IdBillItem   int      `json:"idBillItem"`
+	IdBill       int      `json:"idBill"`
+	DsItem       string   `json:"dsItem" validate:"required"`
+	VlItem       Money    `json:"vlItem" validate:"required"`
+	QtItem       int      `json:"qtItem"`
+	Participants []string `json:"participants"`
 }
 
 type BillPayment struct {
 	IdBillPayment   int     `json:"idBillPayment"`
 	IdBill          int     `json:"idBill"`
-	VlPayment       float64 `json:"vlPayment"`
-	FgPayed         bool    `json:"fgPayed"`
+	VlPayment       Money   `json:"vlPayment"`
 	FgCustomPayment bool    `json:"fgCustomPayment"`
 	DsPerson        string  `json:"dsPerson"`
+	IdUser          *int    `json:"idUser"`
+	QtShare         int     `json:"qtShare"`
+	PcPayment       Percent `json:"pcPayment"`
+	// Derivados das transações de pagamento
+	VlPaid        Money      `json:"vlPaid"`
+	VlOutstanding Money      `json:"vlOutstanding"`
+	FgPayed       bool       `json:"fgPayed"`
+	DtPayment     *time.Time `json:"dtPayment"`
 }
 
+const (
+	PricingFlat = "flat"
+	PricingFuel = "fuel"
+)
+
 type Ride struct {
-	IdRide           int               `json:"idRide"`
-	DsRide           string            `json:"dsRide"`
-	VlRide           float64           `json:"vlRide"`
-	DtInit           time.Time         `json:"dtInit"`
-	DtFinish         time.Time         `json:"dtFinish"`
-	QtRide           int               `json:"qtRide"`
-	FgCountWeekend   bool              `json:"fgCountWeekend"`
-	GroupedPresences []GroupedPresence `json:"groupedPresences"`
-	Payments         []RidePayment     `json:"payments"`
+	IdRide         int         `json:"idRide"`
+	IdUser         int         `json:"idUser"`
+	DsRide         string      `json:"dsRide"`
+	TpPricing      string      `json:"tpPricing" validate:"omitempty,oneof=flat fuel"`
+	VlRide         Money       `json:"vlRide"`
+	DtInit         time.Time   `json:"dtInit"`
+	DtFinish       time.Time   `json:"dtFinish"`
+	QtRide         int         `json:"qtRide"`
+	FgCountWeekend bool        `json:"fgCountWeekend"`
+	NrWeekdays     []int       `json:"nrWeekdays" validate:"dive,min=0,max=6"`
+	IdCalendars    []int       `json:"idCalendars"`
+	DtExcluded     []time.Time `json:"dtExcluded"`
+	// Dias da semana em uso: NrWeekdays ou, sem ele, os derivados de
+	// FgCountWeekend. Só leitura.
+	NrEffectiveWeekdays []int `json:"nrEffectiveWeekdays"`
+	// Precificação por combustível: km por viagem, km/l do veículo e o preço
+	// do litro vigente em cada dia, mais pedágio e estacionamento por dia
+	NrDistance       float64           `json:"nrDistance" validate:"required_if=TpPricing fuel,min=0"`
+	NrConsumption    float64           `json:"nrConsumption" validate:"required_if=TpPricing fuel,min=0"`
+	VlToll           Money             `json:"vlToll" validate:"min=0"`
+	VlParking        Money             `json:"vlParking" validate:"min=0"`
+	FuelPrices       []FuelPrice       `json:"fuelPrices" validate:"required_if=TpPricing fuel,dive"`
+	GroupedPresences []GroupedPresence `json:"groupedPresences" validate:"dive"`
+	Payments         []RidePayment     `json:"payments" validate:"dive"`
+	Summary          *PaymentSummary   `json:"summary,omitempty"`
+}
+
+// FuelPrice é o preço do litro a partir de DtEffective, até o próximo preço.
+type FuelPrice struct {
+	IdFuelPrice int       `json:"idFuelPrice"`
+	VlFuelPrice Money     `json:"vlFuelPrice" validate:"required,gt=0"`
+	DtEffective time.Time `json:"dtEffective" validate:"required"`
 }
 
 type RidePayment struct {
-	IdRidePayment int     `json:"idRidePayment"`
-	VlPayment     float64 `json:"vlPayment"`
-	FgPayed       bool    `json:"fgPayed"`
-	DsPerson      string  `json:"dsPerson"`
+	IdRidePayment int    `json:"idRidePayment"`
+	VlPayment     Money  `json:"vlPayment"`
+	DsPerson      string `json:"dsPerson"`
+	IdUser        *int   `json:"idUser"`
+	// Dias da semana do participante, restritos aos do ride. Vazio segue o ride.
+	NrWeekdays []int `json:"nrWeekdays" validate:"dive,min=0,max=6"`
+	// Dias em que dirigiu e em que foi passageiro. VlDriving é o custo dos
+	// dias dirigidos, dividido entre os passageiros; VlPayment já é o custo
+	// dos dias como passageiro.
+	QtDrivingDays int   `json:"qtDrivingDays"`
+	QtRidingDays  int   `json:"qtRidingDays"`
+	VlDriving     Money `json:"vlDriving"`
+	// Derivados das transações de pagamento
+	VlPaid        Money      `json:"vlPaid"`
+	VlOutstanding Money      `json:"vlOutstanding"`
+	FgPayed       bool       `json:"fgPayed"`
+	DtPayment     *time.Time `json:"dtPayment"`
 }
 
 type Presence struct {
 	IdPresence    int       `json:"idPresence"`
-	IdRidePayment int       `json:"idRidePayment"`
-	QtPresence    int       `json:"qtPresence"`
-	DtRide        time.Time `json:"dtRide"`
+	IdRidePayment int       `json:"idRidePayment" validate:"required"`
+	QtPresence    int       `json:"qtPresence" validate:"min=0"`
+	DtRide        time.Time `json:"dtRide" validate:"required"`
+	FgDriver      bool      `json:"fgDriver"`
 }
 
 type GroupedPresence struct {
-	DtRide    time.Time  `json:"dtRide"`
-	Presences []Presence `json:"presences"`
+	DtRide    time.Time  `json:"dtRide" validate:"required"`
+	Presences []Presence `json:"presences" validate:"dive"`
+}
+
+// RideDaysPreview lista os dias que terão presença e os dias do período
+// que foram pulados por estarem em um calendário ou na lista de exclusões.
+type RideDaysPreview struct {
+	Days        []time.Time    `json:"days"`
+	SkippedDays []CalendarDate `json:"skippedDays"`
+}
+
+type Calendar struct {
+	IdCalendar int            `json:"idCalendar"`
+	DsCalendar string         `json:"dsCalendar"`
+	IdUser     int            `json:"idUser"`
+	Dates      []CalendarDate `json:"dates"`
+}
+
+type CalendarDate struct {
+	IdCalendarDate int       `json:"idCalendarDate"`
+	IdCalendar     int       `json:"idCalendar"`
+	DtDate         time.Time `json:"dtDate" validate:"required"`
+	DsDate         string    `json:"dsDate"`
+}
+
+const (
+	RecurrenceMonthly = "monthly"
+	RecurrenceWeekly  = "weekly"
+	RecurrenceYearly  = "yearly"
+)
+
+type RecurringBill struct {
+	IdRecurringBill int                    `json:"idRecurringBill"`
+	IdUser          int                    `json:"idUser"`
+	DsBill          string                 `json:"dsBill"`
+	VlBill          Money                  `json:"vlBill"`
+	QtPerson        float64                `json:"qtPerson" validate:"gt=0"`
+	TpSplit         string                 `json:"tpSplit" validate:"omitempty,oneof=equal shares percentage exact"`
+	TpRecurrence    string                 `json:"tpRecurrence" validate:"required,oneof=monthly weekly yearly"`
+	NrDay           int                    `json:"nrDay"`
+	NrMonth         int                    `json:"nrMonth"`
+	DtStart         time.Time              `json:"dtStart"`
+	DtFinish        *time.Time             `json:"dtFinish"`
+	DtLastPeriod    *time.Time             `json:"dtLastPeriod"`
+	FgActive        bool                   `json:"fgActive"`
+	Payments        []RecurringBillPayment `json:"payments"`
+}
+
+type RecurringBillPayment struct {
+	IdRecurringBillPayment int     `json:"idRecurringBillPayment"`
+	DsPerson               string  `json:"dsPerson"`
+	IdUser                 *int    `json:"idUser"`
+	VlPayment              Money   `json:"vlPayment"`
+	QtShare                int     `json:"qtShare"`
+	PcPayment              Percent `json:"pcPayment"`
+}
+
+const (
+	PaymentBill = "bill"
+	PaymentRide = "ride"
+)
+
+const (
+	MethodCash     = "cash"
+	MethodPix      = "pix"
+	MethodTransfer = "transfer"
+	MethodCard     = "card"
+	MethodOther    = "other"
+)
+
+// PaymentTransaction registra um pagamento (total ou parcial) de um
+// BillPayment ou RidePayment. Estornos são transações com valor negativo
+// que apontam para a transação estornada.
+type PaymentTransaction struct {
+	IdPaymentTransaction  int       `json:"idPaymentTransaction"`
+	IdBillPayment         *int      `json:"idBillPayment"`
+	IdRidePayment         *int      `json:"idRidePayment"`
+	VlPayment             Money     `json:"vlPayment"`
+	DtPayment             time.Time `json:"dtPayment"`
+	TpMethod              string    `json:"tpMethod"`
+	DsNote                string    `json:"dsNote"`
+	IdReversedTransaction *int      `json:"idReversedTransaction"`
+	FgReversed            bool      `json:"fgReversed"`
+	IdUser                int       `json:"idUser"`
+	CreatedAt             time.Time `json:"createdAt"`
+}
+
+// PixCharge é a cobrança Pix do valor em aberto de um pagamento, recebida
+// na chave do dono do bill ou ride. Os valores do pagamento ficam na moeda
+// do bill e VlCharge é o valor cobrado em reais.
+type PixCharge struct {
+	TpPayment     string    `json:"tpPayment"`
+	IdParent      int       `json:"idParent"`
+	IdPayment     int       `json:"idPayment"`
+	DsParent      string    `json:"dsParent"`
+	DsPerson      string    `json:"dsPerson"`
+	CdCurrency    string    `json:"cdCurrency"`
+	DtParent      time.Time `json:"dtParent"`
+	VlPayment     Money     `json:"vlPayment"`
+	VlPaid        Money     `json:"vlPaid"`
+	VlOutstanding Money     `json:"vlOutstanding"`
+	VlCharge      Money     `json:"vlCharge"`
+	IdReceiver    int       `json:"idReceiver"`
+	DsReceiver    string    `json:"dsReceiver"`
+	DsPixKey      *string   `json:"dsPixKey"`
+	DsPixCity     *string   `json:"dsPixCity"`
+	DsReference   string    `json:"dsReference"`
+	DsBrCode      string    `json:"dsBrCode"`
+}
+
+// Debt é o valor em aberto de um pagamento, na moeda do bill (rides são
+// sempre em reais) e com a data usada na conversão.
+type Debt struct {
+	IdUserDebtor     *int
+	DsPersonDebtor   string
+	IdUserCreditor   *int
+	DsPersonCreditor string
+	VlDebt           Money
+	CdCurrency       string
+	DtDebt           time.Time
+	// Parte do próprio dono do registro
+	FgOwnerShare bool
+}
+
+type Balance struct {
+	IdUser     *int   `json:"idUser"`
+	DsPerson   string `json:"dsPerson"`
+	VlBalance  Money  `json:"vlBalance"`
+	CdCurrency string `json:"cdCurrency"`
+}
+
+type Settlement struct {
+	IdUserFrom   *int   `json:"idUserFrom"`
+	DsPersonFrom string `json:"dsPersonFrom"`
+	IdUserTo     *int   `json:"idUserTo"`
+	DsPersonTo   string `json:"dsPersonTo"`
+	VlSettlement Money  `json:"vlSettlement"`
+	CdCurrency   string `json:"cdCurrency"`
+}
+
+// DefaultCurrency é a moeda dos rides e dos bills sem moeda informada.
+const DefaultCurrency = "BRL"
+
+// ExchangeRate é a cotação de CdFrom em CdTo a partir de DtRate, cadastrada
+// pelo usuário.
+type ExchangeRate struct {
+	IdExchangeRate int       `json:"idExchangeRate"`
+	CdFrom         string    `json:"cdFrom" validate:"required,iso4217"`
+	CdTo           string    `json:"cdTo" validate:"required,iso4217,nefield=CdFrom"`
+	DtRate         time.Time `json:"dtRate" validate:"required"`
+	VlRate         Rate      `json:"vlRate" validate:"gt=0"`
+}
+
+type SaveRatesPayload struct {
+	Rates []ExchangeRate `json:"rates" validate:"required,min=1,dive"`
+}
+
+type ImportRatesPayload struct {
+	DsContent string `json:"dsContent" validate:"required"`
+}
+
+// Statement é o extrato de um participante: as partes que ele deve em bills e
+// rides no período, com o que já foi pago.
+type Statement struct {
+	DsPerson      string          `json:"dsPerson"`
+	IdUser        *int            `json:"idUser"`
+	DtFrom        *time.Time      `json:"dtFrom"`
+	DtTo          *time.Time      `json:"dtTo"`
+	Lines         []StatementLine `json:"lines"`
+	VlTotal       Money           `json:"vlTotal"`
+	VlPaid        Money           `json:"vlPaid"`
+	VlOutstanding Money           `json:"vlOutstanding"`
+	CdCurrency    string          `json:"cdCurrency"`
+}
+
+type StatementLine struct {
+	TpOrigin      string    `json:"tpOrigin"`
+	IdOrigin      int       `json:"idOrigin"`
+	DsOrigin      string    `json:"dsOrigin"`
+	DtOrigin      time.Time `json:"dtOrigin"`
+	VlPayment     Money     `json:"vlPayment"`
+	VlPaid        Money     `json:"vlPaid"`
+	VlOutstanding Money     `json:"vlOutstanding"`
+	FgPayed       bool      `json:"fgPayed"`
+}
+
+const (
+	ImportCSV = "csv"
+	ImportOFX = "ofx"
+)
+
+const (
+	EntryDebit  = "debit"
+	EntryCredit = "credit"
+)
+
+// ImportedTransaction é um lançamento lido de um extrato. DsExternalId vem
+// do FITID, da coluna de id do CSV ou de um hash do lançamento, e é usado
+// para não importar o mesmo lançamento duas vezes.
+type ImportedTransaction struct {
+	DsExternalId  string    `json:"dsExternalId" validate:"required,max=255"`
+	DtTransaction time.Time `json:"dtTransaction" validate:"required"`
+	DsDescription string    `json:"dsDescription" validate:"required"`
+	VlAmount      Money     `json:"vlAmount" validate:"gt=0"`
+	TpEntry       string    `json:"tpEntry"`
+	FgImported    bool      `json:"fgImported"`
+}
+
+// CSVMapping indica as colunas do CSV, pelo nome no cabeçalho ou pela
+// posição começando em 1, e como ler datas e valores.
+type CSVMapping struct {
+	DsDateColumn        string `json:"dsDateColumn" validate:"required"`
+	DsDescriptionColumn string `json:"dsDescriptionColumn" validate:"required"`
+	DsAmountColumn      string `json:"dsAmountColumn" validate:"required"`
+	DsIdColumn          string `json:"dsIdColumn"`
+	DsDateLayout        string `json:"dsDateLayout"`
+	DsDelimiter         string `json:"dsDelimiter" validate:"max=1"`
+	FgDecimalComma      bool   `json:"fgDecimalComma"`
+	// Em extratos de conta os débitos vêm negativos; em faturas de cartão, positivos
+	FgNegativeDebit bool `json:"fgNegativeDebit"`
+}
+
+type PreviewImportPayload struct {
+	TpFormat  string      `json:"tpFormat" validate:"required,oneof=csv ofx"`
+	DsContent string      `json:"dsContent" validate:"required"`
+	Mapping   *CSVMapping `json:"mapping" validate:"required_if=TpFormat csv"`
+}
+
+// ImportBillsPayload cria um bill para cada lançamento selecionado, todos com
+// a mesma lista de participantes.
+type ImportBillsPayload struct {
+	Transactions []ImportedTransaction `json:"transactions" validate:"required,min=1,dive"`
+	QtPerson     float64               `json:"qtPerson" validate:"required,gt=0"`
+	TpSplit      string                `json:"tpSplit" validate:"omitempty,oneof=equal shares percentage exact"`
+	CdCurrency   string                `json:"cdCurrency" validate:"omitempty,iso4217"`
+	Payments     []BillPayment         `json:"payments,omitempty"`
+}
+
+type ImportResult struct {
+	Bills   []Bill   `json:"bills"`
+	Skipped []string `json:"skipped"`
 }